@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
@@ -28,14 +30,23 @@ type app struct {
 	opts          *startOpts
 	w             io.Writer
 	printer       resultPrinter
+	// notifyInterrupt relays interrupts of interactive calls to the channel until stop is called
+	notifyInterrupt func(c chan<- os.Signal) (stop func())
 }
 
 type startOpts struct {
-	Service            string
-	Method             string
-	Discover           bool
-	Deadline           int
-	Verbose            bool
+	Service  string
+	Method   string
+	Discover bool
+	Deadline time.Duration
+	// ReflectTimeout is used for grpc reflection calls, Deadline is used if it's not set
+	ReflectTimeout time.Duration
+	// IdleTimeout cancels server streams if no message is received within this duration,
+	// the call deadline is not applied to such streams
+	IdleTimeout time.Duration
+	Verbose     bool
+	// VerboseFormat is either text or json
+	VerboseFormat      string
 	Target             string
 	IsInteractive      bool
 	Authority          string
@@ -51,18 +62,50 @@ type startOpts struct {
 	Cert     string
 	CertKey  string
 
+	TLSOptions *rpc.TLSOptions
+
 	Protos       []string
+	Protosets    []string
 	ProtoImports []string
 	Headers      map[string][]string
 
+	OAuth2   *rpc.OAuth2Config
+	AuthExec []string
+	JWT      *rpc.JWTConfig
+
+	Tracing *rpc.TracingConfig
+
 	Keepalive     bool
 	KeepaliveTime time.Duration
 
 	MaxRecvMsgSize int
+	Compressor     string
+	// Protocol is used for service calls, reflection always uses gRPC
+	Protocol string
+	// ConnectCodec is message encoding of connect protocol
+	ConnectCodec string
+	Retry        *rpc.RetryPolicy
 
 	w io.Writer
 }
 
+// withApp creates the app for the run func and closes it afterwards,
+// close error is returned if the run func succeeds
+func withApp(opts *startOpts, run func(a *app) error) (e error) {
+	a, err := newApp(opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := a.Close(); err != nil && e == nil {
+			e = err
+		}
+	}()
+
+	return run(a)
+}
+
 func newApp(opts *startOpts) (*app, error) {
 	connOpts := []rpc.ConnFactoryOption{
 		rpc.WithAuthority(opts.Authority),
@@ -71,19 +114,55 @@ func newApp(opts *startOpts) (*app, error) {
 
 	if opts.TLS {
 		connOpts = append(connOpts, rpc.WithConnCred(opts.Insecure, opts.CACert, opts.Cert, opts.CertKey))
+		if opts.TLSOptions != nil {
+			connOpts = append(connOpts, rpc.WithTLSOptions(opts.TLSOptions))
+		}
 	}
 
 	if opts.MaxRecvMsgSize > 0 {
 		connOpts = append(connOpts, rpc.WithMaxRecvMsgSize(opts.MaxRecvMsgSize))
 	}
 
+	if opts.Retry != nil {
+		connOpts = append(connOpts, rpc.WithRetry(opts.Retry))
+	}
+
+	if opts.Compressor != "" {
+		connOpts = append(connOpts, rpc.WithCompressor(opts.Compressor))
+	}
+
+	if opts.Verbose {
+		connOpts = append(connOpts, rpc.WithConnTiming())
+	}
+
+	if opts.Tracing != nil {
+		connOpts = append(connOpts, rpc.WithTracing(opts.Tracing))
+	}
+
+	if opts.Protocol != "" {
+		connOpts = append(connOpts, rpc.WithProtocol(opts.Protocol), rpc.WithConnectCodec(opts.ConnectCodec))
+	}
+
 	if len(opts.Headers) > 0 {
 		connOpts = append(connOpts, rpc.WithHeaders(opts.Headers))
 	}
 
+	if len(opts.AuthExec) > 0 {
+		connOpts = append(connOpts, rpc.WithAuthExec(opts.AuthExec))
+	}
+
+	if opts.OAuth2 != nil {
+		connOpts = append(connOpts, rpc.WithOAuth2(opts.OAuth2))
+	}
+
+	if opts.JWT != nil {
+		connOpts = append(connOpts, rpc.WithJWT(opts.JWT))
+	}
+
 	a := &app{
-		connFact: rpc.NewGrpcConnFactory(connOpts...),
-		opts:     opts,
+		connFact:        rpc.NewGrpcConnFactory(connOpts...),
+		opts:            opts,
+		notifyInterrupt: notifyInterrupt,
 	}
 
 	a.w = opts.w
@@ -93,24 +172,13 @@ func newApp(opts *startOpts) (*app, error) {
 
 	a.printer = newResultPrinter(a.w, opts.OutFormat)
 
-	var svc caller.ServiceMetaData
-	if len(opts.Protos) > 0 {
-		svc = caller.NewServiceMetadataProto(opts.Protos, opts.ProtoImports)
-	} else {
-		svc = caller.NewServiceMetaData(&caller.ServiceMetaDataConfig{
-			ConnFact:       a.connFact,
-			Target:         a.opts.Target,
-			Deadline:       a.opts.Deadline,
-			ProtoImports:   a.opts.ProtoImports,
-			ReflectVersion: a.opts.GrpcReflectVersion,
-		})
-	}
+	svc := a.serviceMetaData(opts)
 
 	ctx := rpc.WithStatsCtx(context.Background())
 	services, err := svc.GetServiceMetaDataList(ctx)
 	if err != nil {
 		if a.opts.Verbose {
-			printVerbose(a.w, rpc.ExtractRpcStats(ctx), err)
+			a.printVerbose(rpc.ExtractRpcStats(ctx), err)
 		}
 		return nil, err
 	}
@@ -140,6 +208,25 @@ func newApp(opts *startOpts) (*app, error) {
 	return a, nil
 }
 
+// serviceMetaData returns the source of services, proto files take precedence over protosets and reflection
+func (a *app) serviceMetaData(opts *startOpts) caller.ServiceMetaData {
+	if len(opts.Protos) > 0 {
+		return caller.NewServiceMetadataProto(opts.Protos, opts.ProtoImports)
+	}
+
+	if len(opts.Protosets) > 0 {
+		return caller.NewServiceMetadataProtoset(opts.Protosets, opts.ProtoImports)
+	}
+
+	return caller.NewServiceMetaData(&caller.ServiceMetaDataConfig{
+		ConnFact:       a.connFact,
+		Target:         opts.Target,
+		Deadline:       a.reflectTimeout(),
+		ProtoImports:   opts.ProtoImports,
+		ReflectVersion: opts.GrpcReflectVersion,
+	})
+}
+
 func (a *app) Start(message []byte) error {
 	for {
 		service, err := a.selectService(a.opts.Service)
@@ -162,9 +249,10 @@ func (a *app) Start(message []byte) error {
 			}
 
 			err = a.callService(method, message)
-			// Ctrl+D will trigger io.EOF if the line is empty
+			// Ctrl+D will trigger io.EOF if the line is empty,
+			// Ctrl+C interrupts the call in progress,
 			// go back to method selection
-			if err != io.EOF {
+			if err != io.EOF && err != ErrCallInterrupted {
 				return err
 			}
 		}
@@ -223,14 +311,14 @@ func (a *app) callService(method protoreflect.MethodDescriptor, message []byte)
 			return err
 		}
 
-		callTimeout := time.Duration(a.opts.Deadline) * time.Second
-		ctx, cancel := context.WithTimeout(rpc.WithStatsCtx(context.Background()), callTimeout)
+		ctx, cancel := a.callContext(method)
 		if method.IsStreamingServer() {
 			err = a.callStream(ctx, method, messages)
 		} else {
 			err = a.callClientStream(ctx, method, messages)
 		}
 
+		interrupted := errors.Is(context.Cause(ctx), ErrCallInterrupted)
 		if err != nil {
 			if !caller.IsErrTransient(err) {
 				cancel()
@@ -240,7 +328,13 @@ func (a *app) callService(method protoreflect.MethodDescriptor, message []byte)
 		}
 
 		if a.opts.Verbose {
-			printVerbose(a.w, rpc.ExtractRpcStats(ctx), errors.Unwrap(err))
+			a.printVerbose(rpc.ExtractRpcStats(ctx), errors.Unwrap(err))
+		}
+
+		// go back to method selection after Ctrl+C
+		if interrupted {
+			cancel()
+			return ErrCallInterrupted
 		}
 
 		// if we pass a single message, return
@@ -252,6 +346,61 @@ func (a *app) callService(method protoreflect.MethodDescriptor, message []byte)
 	}
 }
 
+// callContext returns context for the method call,
+// server streams with idle timeout are not limited by the call deadline
+func (a *app) callContext(method protoreflect.MethodDescriptor) (context.Context, context.CancelFunc) {
+	ctx := rpc.WithStatsCtx(context.Background())
+	var cancel context.CancelFunc
+	if method.IsStreamingServer() && a.opts.IdleTimeout > 0 {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, a.opts.Deadline)
+	}
+
+	if a.opts.IsInteractive {
+		return a.interruptContext(ctx, cancel)
+	}
+
+	return ctx, cancel
+}
+
+// interruptContext cancels the call on Ctrl-C instead of terminating the process,
+// so the interactive session can continue
+func (a *app) interruptContext(ctx context.Context, cancel context.CancelFunc) (context.Context, context.CancelFunc) {
+	ctx, cancelCause := context.WithCancelCause(ctx)
+
+	sig := make(chan os.Signal, 1)
+	stop := a.notifyInterrupt(sig)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sig:
+			cancelCause(ErrCallInterrupted)
+		case <-done:
+		}
+	}()
+
+	return ctx, sync.OnceFunc(func() {
+		stop()
+		close(done)
+		cancelCause(nil)
+		cancel()
+	})
+}
+
+func notifyInterrupt(c chan<- os.Signal) func() {
+	signal.Notify(c, os.Interrupt)
+	return func() { signal.Stop(c) }
+}
+
+func (a *app) reflectTimeout() time.Duration {
+	if a.opts.ReflectTimeout > 0 {
+		return a.opts.ReflectTimeout
+	}
+
+	return a.opts.Deadline
+}
+
 // callClientStream calls unary or client stream method
 func (a *app) callClientStream(ctx context.Context, method protoreflect.MethodDescriptor, messageJSON [][]byte) error {
 	serviceCaller := caller.NewServiceCaller(a.connFact, a.opts.InFormat, a.opts.OutFormat, a.opts.OutJsonNames)
@@ -266,6 +415,17 @@ func (a *app) callClientStream(ctx context.Context, method protoreflect.MethodDe
 	return nil
 }
 
+func (a *app) printVerbose(s *rpc.Stats, rpcErr error) {
+	if a.opts.VerboseFormat == verboseFormatJSON {
+		if err := printVerboseJSON(a.w, s, rpcErr); err != nil {
+			fmt.Printf("Error: %s\n", err)
+		}
+		return
+	}
+
+	printVerbose(a.w, s, rpcErr)
+}
+
 func (a *app) printResult(r []byte) {
 	a.printer.WriteMessage(r)
 	fmt.Fprintln(a.w)
@@ -273,6 +433,17 @@ func (a *app) printResult(r []byte) {
 
 // callStream calls both server or bi-directional stream methods
 func (a *app) callStream(ctx context.Context, method protoreflect.MethodDescriptor, messageJSON [][]byte) error {
+	var idle *time.Timer
+	if a.opts.IdleTimeout > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		idleErr := &idleTimeoutError{timeout: a.opts.IdleTimeout}
+		idle = time.AfterFunc(a.opts.IdleTimeout, func() { cancel(idleErr) })
+		defer idle.Stop()
+	}
+
 	serviceCaller := caller.NewServiceCaller(a.connFact, a.opts.InFormat, a.opts.OutFormat, a.opts.OutJsonNames)
 	result, errChan := serviceCaller.CallStream(ctx, a.opts.Target, method, messageJSON, grpc.WaitForReady(true))
 
@@ -282,6 +453,9 @@ func (a *app) callStream(ctx context.Context, method protoreflect.MethodDescript
 		select {
 		case r := <-result:
 			if r != nil {
+				if idle != nil {
+					idle.Reset(a.opts.IdleTimeout)
+				}
 				if next {
 					a.printer.ArrayDelim()
 				}
@@ -290,6 +464,10 @@ func (a *app) callStream(ctx context.Context, method protoreflect.MethodDescript
 			}
 		case err := <-errChan:
 			a.printer.EndArray()
+			var idleErr *idleTimeoutError
+			if err != nil && errors.As(context.Cause(ctx), &idleErr) {
+				return idleErr
+			}
 			return err
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/stretchr/testify/require"
@@ -13,7 +14,7 @@ import (
 func TestDiscoverCommand(t *testing.T) {
 	app, err := newApp(&startOpts{
 		Target:        app_testing.TestServerAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		Discover:      true,
 		Service:       "TestService",
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
 )
@@ -9,7 +10,7 @@ import (
 func TestAppServiceCallsNoReflect(t *testing.T) {
 	runAppServiceCalls(t, &startOpts{
 		Target:        app_testing.TestServerNoReflectAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		Protos:        []string{"../../testdata/test.proto"},
 	})
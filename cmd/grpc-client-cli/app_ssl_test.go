@@ -2,16 +2,30 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/spyzhov/ajson"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimi/grpc-client-cli/internal/rpc"
 	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
+	"software.sslmate.com/src/go-pkcs12"
 )
 
 func TestAppServiceTLSInvalidCerts(t *testing.T) {
 	_, err := newApp(&startOpts{
 		Target:        app_testing.TestServerTLSAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		TLS:           true,
 		CACert:        "../../testdata/certs/other_ca.crt",
@@ -26,7 +40,7 @@ func TestAppServiceTLSCalls(t *testing.T) {
 	buf := &bytes.Buffer{}
 	app, err := newApp(&startOpts{
 		Target:        app_testing.TestServerTLSAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		TLS:           true,
 		CACert:        "../../testdata/certs/test_ca.crt",
@@ -46,7 +60,7 @@ func TestAppServiceMTLSCalls(t *testing.T) {
 	buf := &bytes.Buffer{}
 	app, err := newApp(&startOpts{
 		Target:        app_testing.TestServerMTLSAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		TLS:           true,
 		CACert:        "../../testdata/certs/test_ca.crt",
@@ -87,7 +101,7 @@ func TestAppServiceMTLSInvalidCerts(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			_, err := newApp(&startOpts{
 				Target:        app_testing.TestServerMTLSAddr(),
-				Deadline:      15,
+				Deadline:      15 * time.Second,
 				IsInteractive: false,
 				TLS:           true,
 				CACert:        tt.cacert,
@@ -105,7 +119,7 @@ func TestAppServiceMTLSInvalidCerts(t *testing.T) {
 func TestAppServiceTLSInvalidCertsInsecure(t *testing.T) {
 	_, err := newApp(&startOpts{
 		Target:        app_testing.TestServerTLSAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		Insecure:      true,
 		TLS:           true,
@@ -114,3 +128,172 @@ func TestAppServiceTLSInvalidCertsInsecure(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestAppServiceTLSServerName(t *testing.T) {
+	_, err := newApp(&startOpts{
+		Target:        app_testing.TestServerTLSAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		TLS:           true,
+		CACert:        "../../testdata/certs/test_ca.crt",
+		TLSOptions:    &rpc.TLSOptions{ServerName: "localhost"},
+	})
+	assert.NoError(t, err)
+
+	_, err = newApp(&startOpts{
+		Target:        app_testing.TestServerTLSAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		TLS:           true,
+		CACert:        "../../testdata/certs/test_ca.crt",
+		TLSOptions:    &rpc.TLSOptions{ServerName: "other.example.com"},
+	})
+	assert.Error(t, err, "host name verification error is expected")
+}
+
+func TestAppServiceTLSPins(t *testing.T) {
+	b, err := os.ReadFile("../../testdata/certs/test_server.crt")
+	require.NoError(t, err)
+	block, _ := pem.Decode(b)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	tests := []struct {
+		name  string
+		pin   string
+		valid bool
+	}{
+		{"Match", "sha256/" + base64.StdEncoding.EncodeToString(spki[:]), true},
+		{"Mismatch", "sha256/" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// pins are checked even when certificate verification is skipped
+			_, err := newApp(&startOpts{
+				Target:        app_testing.TestServerTLSAddr(),
+				Deadline:      15 * time.Second,
+				IsInteractive: false,
+				TLS:           true,
+				Insecure:      true,
+				TLSOptions:    &rpc.TLSOptions{Pins: []string{tt.pin}},
+			})
+
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestAppServiceMTLSPKCS12(t *testing.T) {
+	keyPair, err := tls.LoadX509KeyPair("../../testdata/certs/test_client.crt", "../../testdata/certs/test_client.key")
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	require.NoError(t, err)
+
+	pfx, err := pkcs12.Modern.Encode(keyPair.PrivateKey, leaf, nil, "secret")
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "client.p12")
+	require.NoError(t, os.WriteFile(file, pfx, 0o600))
+
+	buf := &bytes.Buffer{}
+	app, err := newApp(&startOpts{
+		Target:        app_testing.TestServerMTLSAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		TLS:           true,
+		CACert:        "../../testdata/certs/test_ca.crt",
+		TLSOptions: &rpc.TLSOptions{
+			PKCS12:         file,
+			PKCS12Password: "secret",
+			MinVersion:     tls.VersionTLS13,
+		},
+		w: buf,
+	})
+	require.NoError(t, err)
+
+	t.Run("appCallUnaryTLS", func(t *testing.T) {
+		appCallUnary(t, app, buf)
+	})
+}
+
+func TestAppServiceTLSVerbose(t *testing.T) {
+	buf := &bytes.Buffer{}
+	app, err := newApp(&startOpts{
+		Target:        app_testing.TestServerTLSAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		Verbose:       true,
+		TLS:           true,
+		CACert:        "../../testdata/certs/test_ca.crt",
+		w:             buf,
+	})
+	require.NoError(t, err)
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+	require.True(t, ok)
+
+	require.NoError(t, app.callService(m, []byte(`{"user": {"id": 1, "name": "testuser"}}`)))
+
+	res := buf.String()
+	for _, e := range []string{"TLS:", "Version:", "TLS 1.3", "ALPN:", "h2", "CN=test_server",
+		"Name resolution:", "TCP connect:", "TLS handshake:", "Time to first header:"} {
+		assert.Contains(t, res, e)
+	}
+
+	// the second call reuses the connection, so its timing is not printed again
+	buf.Reset()
+	require.NoError(t, app.callService(m, []byte(`{"user": {"id": 1, "name": "testuser"}}`)))
+	assert.Contains(t, buf.String(), "Time to first header:")
+	assert.NotContains(t, buf.String(), "Name resolution:")
+}
+
+func TestAppServiceTLSVerboseJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	app, err := newApp(&startOpts{
+		Target:        app_testing.TestServerTLSAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		TLS:           true,
+		CACert:        "../../testdata/certs/test_ca.crt",
+		Verbose:       true,
+		VerboseFormat: verboseFormatJSON,
+		Compressor:    "gzip",
+		w:             buf,
+	})
+	require.NoError(t, err)
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+	require.True(t, ok)
+
+	msg := fmt.Appendf(nil, `{"user": {"id": 1, "name": "%s"}}`, strings.Repeat("testuser", 100))
+	require.NoError(t, app.callService(m, msg))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	root, err := ajson.Unmarshal([]byte(lines[len(lines)-1]))
+	require.NoError(t, err, "error unmarshaling verbose json")
+
+	assert.Equal(t, "TLS 1.3", jsonString(root, "$.tls.version"))
+	assert.NotEmpty(t, jsonString(root, "$.tls.cipher_suite"))
+	assert.Equal(t, "h2", jsonString(root, "$.tls.alpn"))
+
+	certs, err := root.JSONPath("$.tls.peer_certificates[*]")
+	require.NoError(t, err)
+	require.NotEmpty(t, certs)
+	subject, err := certs[0].GetKey("subject")
+	require.NoError(t, err)
+	assert.NotEmpty(t, subject.MustString())
+
+	msgs, err := root.JSONPath("$.messages[*]")
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	size, err := msgs[0].GetKey("size")
+	require.NoError(t, err)
+	compressed, err := msgs[0].GetKey("compressed_size")
+	require.NoError(t, err)
+	assert.Less(t, compressed.MustNumeric(), size.MustNumeric())
+}
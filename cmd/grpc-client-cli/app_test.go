@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -35,7 +37,7 @@ func TestMain(m *testing.M) {
 func TestAppServiceCalls(t *testing.T) {
 	runAppServiceCalls(t, &startOpts{
 		Target:        app_testing.TestServerAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 	})
 }
@@ -43,7 +45,7 @@ func TestAppServiceCalls(t *testing.T) {
 func TestAppWellKnownAnyServiceCall(t *testing.T) {
 	appOpts := &startOpts{
 		Target:        app_testing.TestServerAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 	}
 	buf := &bytes.Buffer{}
@@ -590,7 +592,7 @@ func TestStatsHandler(t *testing.T) {
 	buf := &bytes.Buffer{}
 	app, err := newApp(&startOpts{
 		Target:        app_testing.TestServerAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		Verbose:       true,
 		w:             buf,
@@ -692,7 +694,7 @@ func TestAuthorityHeader(t *testing.T) {
 
 			app, err := newApp(&startOpts{
 				Target:        tt.target,
-				Deadline:      15,
+				Deadline:      15 * time.Second,
 				Authority:     tt.authority,
 				IsInteractive: false,
 				w:             buf,
@@ -731,7 +733,7 @@ func checkStats(t *testing.T, app *app, msg []byte) {
 		return
 	}
 
-	callTimeout := time.Duration(app.opts.Deadline) * time.Second
+	callTimeout := app.opts.Deadline
 	ctx, cancel := context.WithTimeout(rpc.WithStatsCtx(context.Background()), callTimeout)
 	defer cancel()
 
@@ -751,7 +753,7 @@ func checkStats(t *testing.T, app *app, msg []byte) {
 func TestJSONNamesOutput(t *testing.T) {
 	appOpts := &startOpts{
 		Target:        app_testing.TestServerAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		OutJsonNames:  true,
 	}
@@ -813,7 +815,7 @@ func checkStatsInOutput(t *testing.T, app *app, msg []byte, buf *bytes.Buffer) {
 func TestJSONFieldMask(t *testing.T) {
 	appOpts := &startOpts{
 		Target:        app_testing.TestServerAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		OutJsonNames:  true,
 	}
@@ -843,3 +845,605 @@ func TestJSONFieldMask(t *testing.T) {
 
 	require.Equal(t, "some.field,other.field", jsonString(root, "$.updateMask"), "unexpected updateMask value")
 }
+
+func TestOAuth2Header(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "token123", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenSrv.Close()
+
+	app, err := newApp(&startOpts{
+		Target:        app_testing.TestServerAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		w:             &bytes.Buffer{},
+		OAuth2: &rpc.OAuth2Config{
+			TokenURL:     tokenSrv.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+		},
+	})
+	require.NoError(t, err)
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+	if !ok {
+		return
+	}
+
+	msg := [][]byte{[]byte(`{"user": {"id": 1}}`)}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), app_testing.CheckHeader, "authorization=Bearer token123")
+	err = app.callClientStream(ctx, m, msg)
+	require.NoError(t, err)
+
+	// make sure the header is actually sent
+	ctx = metadata.AppendToOutgoingContext(context.Background(), app_testing.CheckHeader, "authorization=Bearer invalid")
+	err = app.callClientStream(ctx, m, msg)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAuthExecHeaders(t *testing.T) {
+	app, err := newApp(&startOpts{
+		Target:        app_testing.TestServerAddr() + ",metadata=x-team:target",
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		w:             &bytes.Buffer{},
+		Headers: map[string][]string{
+			"x-team": {"header"},
+		},
+		AuthExec: []string{"sh", "-c", `echo '{"headers": {"authorization": "Bearer token123", "x-team": "exec"}}'`},
+	})
+	require.NoError(t, err)
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+	if !ok {
+		return
+	}
+
+	ctx := rpc.WithStatsCtx(context.Background())
+	err = app.callClientStream(ctx, m, [][]byte{[]byte(`{"user": {"id": 1}}`)})
+	require.NoError(t, err)
+
+	s := rpc.ExtractRpcStats(ctx)
+	assert.Equal(t, []string{"Bearer token123"}, s.ReqHeaders()["authorization"])
+	assert.Equal(t, []string{"header", "target", "exec"}, s.ReqHeaders()["x-team"])
+}
+
+func TestCompression(t *testing.T) {
+	for _, compressor := range []string{"gzip", "zstd", "snappy"} {
+		t.Run(compressor, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			app, err := newApp(&startOpts{
+				Target:        app_testing.TestServerAddr(),
+				Deadline:      15 * time.Second,
+				IsInteractive: false,
+				Verbose:       true,
+				Compressor:    compressor,
+				w:             buf,
+			})
+			require.NoError(t, err)
+
+			m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+			require.True(t, ok)
+
+			msg := fmt.Appendf(nil, `{"user": {"id": 1, "name": "%s"}}`, strings.Repeat("testuser", 100))
+			require.NoError(t, app.callService(m, msg))
+
+			res := buf.String()
+			assert.Contains(t, res, "Request compression:")
+			// the server responds using the same compressor
+			assert.Contains(t, res, "Response compression:")
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	cases := []struct {
+		name        string
+		failures    int
+		maxAttempts int
+		expErr      bool
+	}{
+		{name: "Succeeded", failures: 2, maxAttempts: 3},
+		{name: "AttemptsExceeded", failures: 3, maxAttempts: 3, expErr: true},
+	}
+
+	// HTTP based protocols send a new request for every attempt
+	protocols := []struct {
+		protocol string
+		target   string
+	}{
+		{rpc.ProtocolGRPC, app_testing.TestServerAddr()},
+		{rpc.ProtocolGRPCWeb, app_testing.TestServerWebAddr()},
+		{rpc.ProtocolConnect, app_testing.TestServerConnectAddr()},
+	}
+
+	for _, p := range protocols {
+		for _, c := range cases {
+			t.Run(p.protocol+"/"+c.name, func(t *testing.T) {
+				buf := &bytes.Buffer{}
+				app, err := newApp(&startOpts{
+					Target:        p.target,
+					Deadline:      15 * time.Second,
+					IsInteractive: false,
+					Protocol:      p.protocol,
+					w:             buf,
+					Headers: map[string][]string{
+						app_testing.FailAttempts: {fmt.Sprintf("%s=%d", t.Name(), c.failures)},
+					},
+					Retry: &rpc.RetryPolicy{
+						MaxAttempts:    c.maxAttempts,
+						Codes:          []codes.Code{codes.Unavailable},
+						InitialBackoff: 10 * time.Millisecond,
+						Multiplier:     2,
+					},
+				})
+				require.NoError(t, err)
+
+				m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+				require.True(t, ok)
+
+				ctx := rpc.WithStatsCtx(context.Background())
+				err = app.callClientStream(ctx, m, [][]byte{[]byte(`{"user": {"id": 1, "name": "testuser"}}`)})
+				attempts := rpc.ExtractRpcStats(ctx).Attempts()
+				if c.expErr {
+					assert.Equal(t, codes.Unavailable, status.Code(errors.Unwrap(err)))
+					assert.Len(t, attempts, c.maxAttempts-1)
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Len(t, attempts, c.failures)
+				assert.Contains(t, buf.String(), "testuser")
+
+				printVerbose(buf, rpc.ExtractRpcStats(ctx), nil)
+				assert.Contains(t, buf.String(), "Retried Attempts:")
+			})
+		}
+	}
+}
+
+func TestReflectTimeout(t *testing.T) {
+	a := &app{opts: &startOpts{Deadline: 3 * time.Second}}
+	assert.Equal(t, 3*time.Second, a.reflectTimeout())
+
+	a.opts.ReflectTimeout = time.Second
+	assert.Equal(t, time.Second, a.reflectTimeout())
+}
+
+func TestSubSecondDeadline(t *testing.T) {
+	app, err := newApp(&startOpts{
+		Target:         app_testing.TestServerAddr(),
+		Deadline:       100 * time.Millisecond,
+		ReflectTimeout: 15 * time.Second,
+		IsInteractive:  false,
+		w:              &bytes.Buffer{},
+	})
+	require.NoError(t, err)
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "StreamingOutputCall")
+	require.True(t, ok)
+
+	msg := []byte(`{"user": {"name": "testuser"}, "response_parameters": [{"size": 1, "interval_us": 500000}]}`)
+	ctx, cancel := app.callContext(m)
+	defer cancel()
+
+	start := time.Now()
+	err = app.callStream(ctx, m, [][]byte{msg})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(errors.Unwrap(err)))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	cases := []struct {
+		name        string
+		idleTimeout time.Duration
+		expErr      bool
+	}{
+		// total stream duration exceeds the deadline, but messages arrive within the idle timeout
+		{name: "Succeeded", idleTimeout: 500 * time.Millisecond},
+		{name: "Exceeded", idleTimeout: 100 * time.Millisecond, expErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			app, err := newApp(&startOpts{
+				Target:        app_testing.TestServerAddr(),
+				Deadline:      300 * time.Millisecond,
+				IdleTimeout:   c.idleTimeout,
+				IsInteractive: false,
+				w:             buf,
+			})
+			require.NoError(t, err)
+
+			m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "StreamingOutputCall")
+			require.True(t, ok)
+
+			msg := []byte(`{"user": {"name": "testuser"}, "response_parameters": [
+				{"size": 1, "interval_us": 200000},
+				{"size": 1, "interval_us": 200000},
+				{"size": 1, "interval_us": 200000}
+			]}`)
+			ctx, cancel := app.callContext(m)
+			defer cancel()
+
+			err = app.callStream(ctx, m, [][]byte{msg})
+			if c.expErr {
+				var idleErr *idleTimeoutError
+				require.ErrorAs(t, err, &idleErr)
+				assert.True(t, caller.IsErrTransient(err))
+				assert.Equal(t, codes.DeadlineExceeded, status.Code(errors.Unwrap(err)))
+				return
+			}
+
+			require.NoError(t, err)
+			root, err := ajson.Unmarshal(buf.Bytes())
+			require.NoError(t, err)
+			assert.Len(t, root.MustArray(), 3)
+		})
+	}
+}
+
+func TestCallInterrupt(t *testing.T) {
+	buf := &bytes.Buffer{}
+	app, err := newApp(&startOpts{
+		Target:        app_testing.TestServerAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: true,
+		Verbose:       true,
+		w:             buf,
+	})
+	require.NoError(t, err)
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "StreamingOutputCall")
+	require.True(t, ok)
+
+	msg := []byte(`{"user": {"name": "testuser"}, "response_parameters": [
+		{"size": 1},
+		{"size": 1, "interval_us": 10000000}
+	]}`)
+
+	app.notifyInterrupt = func(c chan<- os.Signal) func() {
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			c <- os.Interrupt
+		}()
+		return func() {}
+	}
+
+	start := time.Now()
+	err = app.callService(m, msg)
+	require.ErrorIs(t, err, ErrCallInterrupted)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	res := buf.String()
+	// partial results are printed
+	assert.Contains(t, res, "testuser")
+	assert.Contains(t, res, codes.Canceled.String())
+}
+
+func TestStreamMessageStats(t *testing.T) {
+	msg := []byte(`{"user": {"name": "testuser"}, "response_parameters": [
+		{"size": 1, "interval_us": 50000},
+		{"size": 2, "interval_us": 100000},
+		{"size": 3}
+	]}`)
+
+	t.Run("Text", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		app, err := newApp(&startOpts{
+			Target:        app_testing.TestServerAddr(),
+			Deadline:      15 * time.Second,
+			IsInteractive: false,
+			Verbose:       true,
+			w:             buf,
+		})
+		require.NoError(t, err)
+
+		m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "StreamingOutputCall")
+		require.True(t, ok)
+		require.NoError(t, app.callService(m, msg))
+
+		res := buf.String()
+		assert.Contains(t, res, "Messages:")
+		assert.Contains(t, res, "Time to first message:")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		app, err := newApp(&startOpts{
+			Target:        app_testing.TestServerAddr(),
+			Deadline:      15 * time.Second,
+			IsInteractive: false,
+			Verbose:       true,
+			VerboseFormat: verboseFormatJSON,
+			w:             buf,
+		})
+		require.NoError(t, err)
+
+		m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "StreamingOutputCall")
+		require.True(t, ok)
+		require.NoError(t, app.callService(m, msg))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		root, err := ajson.Unmarshal([]byte(lines[len(lines)-1]))
+		require.NoError(t, err, "error unmarshaling verbose json")
+
+		assert.Equal(t, "/grpc_client_cli.testing.TestService/StreamingOutputCall", jsonString(root, "$.method"))
+		assert.Equal(t, "OK", jsonString(root, "$.status.name"))
+
+		first, err := root.JSONPath("$.time_to_first_message_ms")
+		require.NoError(t, err)
+		require.Len(t, first, 1)
+		assert.GreaterOrEqual(t, first[0].MustNumeric(), float64(50))
+
+		msgs, err := root.JSONPath("$.messages[*]")
+		require.NoError(t, err)
+		require.Len(t, msgs, 3)
+
+		// exact gaps depend on delivery timing, they are checked with fixed receive times in rpc stats tests
+		gap, err := msgs[1].GetKey("gap_ms")
+		require.NoError(t, err)
+		assert.Positive(t, gap.MustNumeric())
+	})
+}
+
+func TestTracing(t *testing.T) {
+	file := t.TempDir() + "/spans.json"
+	buf := &bytes.Buffer{}
+	app, err := newApp(&startOpts{
+		Target:        app_testing.TestServerAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		Verbose:       true,
+		Tracing: &rpc.TracingConfig{
+			File:    file,
+			Baggage: map[string]string{"tenant": "test"},
+		},
+		w: buf,
+	})
+	require.NoError(t, err)
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+	require.True(t, ok)
+
+	ctx := rpc.WithStatsCtx(context.Background())
+	require.NoError(t, app.callClientStream(ctx, m, [][]byte{[]byte(`{"user": {"id": 1, "name": "testuser"}}`)}))
+
+	s := rpc.ExtractRpcStats(ctx)
+	traceID := s.TraceID()
+	require.NotEmpty(t, traceID)
+	require.Len(t, s.ReqHeaders()["traceparent"], 1)
+	assert.Contains(t, s.ReqHeaders()["traceparent"][0], traceID)
+	assert.Equal(t, []string{"tenant=test"}, s.ReqHeaders()["baggage"])
+
+	printVerbose(buf, s, nil)
+	assert.Contains(t, buf.String(), "Trace ID:")
+
+	// spans are flushed when the app is closed
+	require.NoError(t, app.Close())
+	spans, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(spans), traceID)
+	assert.Contains(t, string(spans), "grpc_client_cli.testing.TestService/UnaryCall")
+}
+
+func TestGrpcWeb(t *testing.T) {
+	for _, protocol := range []string{rpc.ProtocolGRPCWeb, rpc.ProtocolGRPCWebText} {
+		t.Run(protocol, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			app, err := newApp(&startOpts{
+				Target:        app_testing.TestServerWebAddr(),
+				Deadline:      15 * time.Second,
+				IsInteractive: false,
+				Protocol:      protocol,
+				w:             buf,
+			})
+			require.NoError(t, err)
+
+			t.Run("appCallUnaryServerError", func(t *testing.T) {
+				appCallUnaryServerError(t, app)
+			})
+
+			t.Run("appCallUnary", func(t *testing.T) {
+				buf.Reset()
+				appCallUnary(t, app, buf)
+			})
+
+			t.Run("appCallStreamOutput", func(t *testing.T) {
+				buf.Reset()
+				appCallStreamOutput(t, app, buf)
+			})
+
+			t.Run("appCallStreamOutputError", func(t *testing.T) {
+				appCallStreamOutputError(t, app)
+			})
+
+			t.Run("ClientStreamUnimplemented", func(t *testing.T) {
+				m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "StreamingInputCall")
+				require.True(t, ok)
+
+				err := app.callClientStream(context.Background(), m, [][]byte{[]byte(`{"user": {"id": 1}}`)})
+				assert.Equal(t, codes.Unimplemented, status.Code(errors.Unwrap(err)))
+			})
+
+			t.Run("Stats", func(t *testing.T) {
+				m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+				require.True(t, ok)
+
+				ctx := rpc.WithStatsCtx(context.Background())
+				require.NoError(t, app.callClientStream(ctx, m, [][]byte{[]byte(`{"user": {"id": 1}}`)}))
+
+				s := rpc.ExtractRpcStats(ctx)
+				assert.Contains(t, s.ReqHeaders()["content-type"][0], "application/"+protocol)
+				assert.Positive(t, s.RespSize())
+			})
+		})
+	}
+}
+
+func TestConnect(t *testing.T) {
+	for _, codec := range rpc.ConnectCodecs {
+		t.Run(codec, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			app, err := newApp(&startOpts{
+				Target:        app_testing.TestServerConnectAddr(),
+				Deadline:      15 * time.Second,
+				IsInteractive: false,
+				Protocol:      rpc.ProtocolConnect,
+				ConnectCodec:  codec,
+				w:             buf,
+			})
+			require.NoError(t, err)
+
+			t.Run("appCallUnaryServerError", func(t *testing.T) {
+				appCallUnaryServerError(t, app)
+			})
+
+			t.Run("appCallUnary", func(t *testing.T) {
+				buf.Reset()
+				appCallUnary(t, app, buf)
+			})
+
+			t.Run("appCallStreamOutput", func(t *testing.T) {
+				buf.Reset()
+				appCallStreamOutput(t, app, buf)
+			})
+
+			t.Run("appCallStreamOutputError", func(t *testing.T) {
+				appCallStreamOutputError(t, app)
+			})
+
+			t.Run("appCallClientStream", func(t *testing.T) {
+				buf.Reset()
+				appCallClientStream(t, app, buf)
+			})
+
+			t.Run("appCallClientStreamError", func(t *testing.T) {
+				appCallClientStreamError(t, app)
+			})
+
+			t.Run("Stats", func(t *testing.T) {
+				m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+				require.True(t, ok)
+
+				ctx := rpc.WithStatsCtx(context.Background())
+				require.NoError(t, app.callClientStream(ctx, m, [][]byte{[]byte(`{"user": {"id": 1}}`)}))
+
+				s := rpc.ExtractRpcStats(ctx)
+				assert.Equal(t, []string{"application/" + codec}, s.ReqHeaders()["content-type"])
+				assert.Equal(t, []string{"1"}, s.ReqHeaders()["connect-protocol-version"])
+				assert.Positive(t, s.RespSize())
+			})
+		})
+	}
+}
+
+func TestHTTPTranscoding(t *testing.T) {
+	buf := &bytes.Buffer{}
+	app, err := newApp(&startOpts{
+		Target:        app_testing.TestServerHTTPAddr(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		Protos:        []string{"../../testdata/http_service.proto"},
+		Protocol:      rpc.ProtocolHTTP,
+		Headers:       map[string][]string{"x-echo": {"echo"}},
+		w:             buf,
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		method   string
+		req      string
+		expected map[string]string
+	}{
+		{
+			method: "GetUser",
+			req:    `{"id": 1, "view": "full", "tags": ["a", "b"], "org": {"name": "acme"}, "updated_after": "2024-01-02T03:04:05Z"}`,
+			expected: map[string]string{
+				"$.method": "GET",
+				"$.path":   "/v1/users/1",
+				"$.query":  "org.name=acme&tags=a&tags=b&updated_after=2024-01-02T03%3A04%3A05Z&view=full",
+				"$.body":   "",
+			},
+		},
+		{
+			method: "CreateUser",
+			req:    `{"parent": "orgs/acme", "user": {"name": "test"}, "validate_only": true}`,
+			expected: map[string]string{
+				"$.method": "POST",
+				"$.path":   "/v1/orgs/acme/users",
+				"$.query":  "validate_only=true",
+				"$.body":   `{"name":"test"}`,
+			},
+		},
+		{
+			method: "UpdateUser",
+			req:    `{"id": 2, "name": "test user", "tags": ["a"]}`,
+			expected: map[string]string{
+				"$.method": "PATCH",
+				"$.path":   "/v1/users/2",
+				"$.query":  "",
+				"$.body":   `{"name":"test user","tags":["a"]}`,
+			},
+		},
+		{
+			method:   "GetUserMethod",
+			req:      `{"id": 3}`,
+			expected: map[string]string{"$.method": "GET"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method, func(t *testing.T) {
+			buf.Reset()
+			m, ok := findMethod(t, app, "grpc_client_cli.testing.http.HttpService", c.method)
+			require.True(t, ok)
+
+			ctx := rpc.WithStatsCtx(context.Background())
+			require.NoError(t, app.callClientStream(ctx, m, [][]byte{[]byte(c.req)}))
+
+			root, err := ajson.Unmarshal(buf.Bytes())
+			require.NoError(t, err)
+			for path, expected := range c.expected {
+				assert.Equal(t, expected, jsonString(root, path), path)
+			}
+
+			s := rpc.ExtractRpcStats(ctx)
+			assert.Equal(t, []string{"echo"}, s.RespHeaders()["grpc-metadata-x-echo"])
+			assert.Equal(t, []string{"done"}, s.RespTrailers()["x-trailer"])
+		})
+	}
+
+	t.Run("ServerStreaming", func(t *testing.T) {
+		buf.Reset()
+		m, ok := findMethod(t, app, "grpc_client_cli.testing.http.HttpService", "WatchUser")
+		require.True(t, ok)
+
+		require.NoError(t, app.callStream(context.Background(), m, [][]byte{[]byte(`{"id": 1, "count": 2}`)}))
+		assert.Equal(t, 2, strings.Count(buf.String(), `"/v1/users/1:watch"`))
+
+		err := app.callStream(context.Background(), m, [][]byte{[]byte(`{"id": 1, "count": 1, "fail": true}`)})
+		assert.Equal(t, codes.Internal, status.Code(errors.Unwrap(err)))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		m, ok := findMethod(t, app, "grpc_client_cli.testing.http.HttpService", "GetStatus")
+		require.True(t, ok)
+
+		err := app.callClientStream(context.Background(), m, [][]byte{[]byte(`{"code": 5}`)})
+		s, _ := status.FromError(errors.Unwrap(err))
+		assert.Equal(t, codes.NotFound, s.Code())
+		assert.Equal(t, "status error", s.Message())
+		assert.Len(t, s.Details(), 1)
+	})
+
+	t.Run("NoRule", func(t *testing.T) {
+		m, ok := findMethod(t, app, "grpc_client_cli.testing.http.HttpService", "NoRule")
+		require.True(t, ok)
+
+		err := app.callClientStream(context.Background(), m, [][]byte{[]byte(`{"id": 1}`)})
+		assert.Equal(t, codes.Unimplemented, status.Code(errors.Unwrap(err)))
+	})
+}
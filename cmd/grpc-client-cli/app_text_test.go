@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/vadimi/grpc-client-cli/internal/caller"
 	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
@@ -11,7 +12,7 @@ import (
 func TestAppServiceCallsProtoText(t *testing.T) {
 	appOpts := &startOpts{
 		Target:        app_testing.TestServerAddr(),
-		Deadline:      15,
+		Deadline:      15 * time.Second,
 		IsInteractive: false,
 		InFormat:      caller.Text,
 	}
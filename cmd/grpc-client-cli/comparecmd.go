@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"github.com/vadimi/grpc-client-cli/internal/caller"
+	"github.com/vadimi/grpc-client-cli/internal/contract"
+	"github.com/vadimi/grpc-client-cli/internal/fs"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func compareCmd(ctx context.Context, cmd *cli.Command) error {
+	opts := &startOpts{}
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if opts.Target == "" && len(opts.Protos) == 0 && len(opts.Protosets) == 0 {
+		return cli.Exit(errors.New("please provide service host:port to use reflection, proto or protoset files"), 1)
+	}
+
+	against := *opts
+	against.Target = cmd.String("against")
+	against.Protos = fs.NormalizePaths(cmd.StringSlice("against-proto"))
+	against.Protosets = fs.NormalizePaths(cmd.StringSlice("against-protoset"))
+	if against.Target == "" && len(against.Protos) == 0 && len(against.Protosets) == 0 {
+		return cli.Exit(errors.New("please provide --against host:port to use reflection, --against-proto or --against-protoset files"), 1)
+	}
+
+	if err := runCompare(ctx, opts, &against, cmd.Bool("wire-only")); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// errBreakingChanges is returned if the compared services have breaking changes
+var errBreakingChanges = errors.New("breaking changes found")
+
+// runCompare prints changes of services described by against opts relative to the services of opts,
+// JSON breaking changes are ignored if wireOnly is set
+func runCompare(ctx context.Context, opts, against *startOpts, wireOnly bool) error {
+	return withApp(opts, func(a *app) error {
+		againstServices, err := a.serviceMetaData(against).GetServiceMetaDataList(ctx)
+		if err != nil {
+			return err
+		}
+
+		breaking := 0
+		changes := contract.Compare(serviceDescriptors(a.servicesList), serviceDescriptors(againstServices))
+		for _, c := range changes {
+			if c.Severity == contract.WireBreaking || (c.Severity == contract.JSONBreaking && !wireOnly) {
+				breaking++
+			}
+			fmt.Fprintln(a.w, c)
+		}
+
+		fmt.Fprintf(a.w, "%d changes, %d breaking\n", len(changes), breaking)
+		if breaking > 0 {
+			return errBreakingChanges
+		}
+
+		return nil
+	})
+}
+
+// serviceDescriptors returns descriptors of the services, reflection services are skipped
+// as they are usually registered by servers only
+func serviceDescriptors(services caller.ServiceMetaList) []protoreflect.ServiceDescriptor {
+	res := []protoreflect.ServiceDescriptor{}
+	for _, s := range services {
+		if strings.HasPrefix(s.Name, "grpc.reflection.") {
+			continue
+		}
+
+		if sd := s.File.Services().ByName(protoreflect.FullName(s.Name).Name()); sd != nil {
+			res = append(res, sd)
+		}
+	}
+	return res
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
+)
+
+func TestCompareDescriptors(t *testing.T) {
+	source, err := os.ReadFile("../../testdata/test.proto")
+	require.NoError(t, err)
+
+	changed := filepath.Join(t.TempDir(), "test.proto")
+	require.NoError(t, os.WriteFile(changed, []byte(strings.Replace(string(source), "string name = 2;", "string full_name = 2;", 1)), 0o644))
+
+	cases := []struct {
+		name     string
+		against  *startOpts
+		wireOnly bool
+		expected string
+		expErr   error
+	}{
+		{
+			name:    "Reflection",
+			against: &startOpts{Target: app_testing.TestServerAddr()},
+			expected: "compatible     added    service grpc.health.v1.Health\n" +
+				"1 changes, 0 breaking\n",
+		},
+		{
+			name:    "Breaking",
+			against: &startOpts{Protos: []string{changed}},
+			expected: "json-breaking  changed  field grpc_client_cli.testing.User.name (2): name changed to full_name\n" +
+				"1 changes, 1 breaking\n",
+			expErr: errBreakingChanges,
+		},
+		{
+			name:     "WireOnly",
+			against:  &startOpts{Protos: []string{changed}},
+			wireOnly: true,
+			expected: "json-breaking  changed  field grpc_client_cli.testing.User.name (2): name changed to full_name\n" +
+				"1 changes, 0 breaking\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			c.against.Deadline = 15 * time.Second
+
+			err := runCompare(context.Background(), &startOpts{
+				Protos:   []string{"../../testdata/test.proto"},
+				Deadline: 15 * time.Second,
+				w:        buf,
+			}, c.against, c.wireOnly)
+			assert.Equal(t, c.expErr, err)
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+}
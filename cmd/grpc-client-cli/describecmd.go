@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoprint"
+	"github.com/urfave/cli/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func describeCmd(ctx context.Context, cmd *cli.Command) error {
+	opts := &startOpts{}
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	// the symbol goes first, the target follows it unless it's set with --address
+	symbol := cmd.Args().First()
+	if cmd.String("address") == "" {
+		opts.Target = cmd.Args().Get(1)
+	}
+
+	if symbol == "" {
+		return cli.Exit(errors.New("please provide fully qualified name of the symbol to describe"), 1)
+	}
+
+	if opts.Target == "" && len(opts.Protos) == 0 && len(opts.Protosets) == 0 {
+		return cli.Exit(errors.New("please provide service host:port to use reflection, proto or protoset files"), 1)
+	}
+
+	if err := runDescribe(opts, symbol, parseEnum(cmd.Value("format"))); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+type describeResult struct {
+	Name       string          `json:"name"`
+	Kind       string          `json:"kind"`
+	File       string          `json:"file"`
+	Descriptor json.RawMessage `json:"descriptor"`
+}
+
+// runDescribe prints the definition of the service, method, message, enum or field,
+// methods can be set as package.Service/Method as well
+func runDescribe(opts *startOpts, symbol, format string) error {
+	return withApp(opts, func(a *app) error {
+		name := protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(symbol, "."), "/", "."))
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+		if err != nil {
+			return fmt.Errorf("symbol %s not found", symbol)
+		}
+
+		kind, dp := describeDescriptor(d)
+		if kind == "" {
+			return fmt.Errorf("symbol %s is not a service, method, message, enum or field", symbol)
+		}
+
+		if format == outputFormatJSON {
+			b, err := protojson.Marshal(dp)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(a, describeResult{
+				Name:       string(d.FullName()),
+				Kind:       kind,
+				File:       d.ParentFile().Path(),
+				Descriptor: b,
+			})
+		}
+
+		wrapped, err := desc.WrapDescriptor(d)
+		if err != nil {
+			return err
+		}
+
+		text, err := (&protoprint.Printer{}).PrintProtoToString(wrapped)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(a.w, "// %s %s from %s\n%s", kind, d.FullName(), d.ParentFile().Path(), text)
+		return nil
+	})
+}
+
+// describeDescriptor returns the kind of the descriptor and its proto representation
+func describeDescriptor(d protoreflect.Descriptor) (string, proto.Message) {
+	switch v := d.(type) {
+	case protoreflect.ServiceDescriptor:
+		return "service", protodesc.ToServiceDescriptorProto(v)
+	case protoreflect.MethodDescriptor:
+		return "method", protodesc.ToMethodDescriptorProto(v)
+	case protoreflect.MessageDescriptor:
+		return "message", protodesc.ToDescriptorProto(v)
+	case protoreflect.EnumDescriptor:
+		return "enum", protodesc.ToEnumDescriptorProto(v)
+	case protoreflect.FieldDescriptor:
+		return "field", protodesc.ToFieldDescriptorProto(v)
+	default:
+		return "", nil
+	}
+}
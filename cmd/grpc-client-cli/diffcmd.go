@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+	"github.com/vadimi/grpc-client-cli/internal/caller"
+	"github.com/vadimi/grpc-client-cli/internal/jsonnum"
+	"github.com/vadimi/grpc-client-cli/internal/msgdiff"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func diffCmd(ctx context.Context, cmd *cli.Command) error {
+	opts := &startOpts{}
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	// the second target follows the first one, which can be set with --address as well
+	target2 := cmd.Args().Get(1)
+	if cmd.String("address") != "" {
+		target2 = cmd.Args().First()
+	}
+
+	if opts.Target == "" || target2 == "" {
+		return cli.Exit(errors.New("please provide two services host:port to compare"), 1)
+	}
+
+	message, err := getMessage(cmd.String("input"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if len(message) == 0 {
+		return cli.Exit(errors.New("please provide request message with --input or stdin"), 1)
+	}
+
+	diffOpts := &msgdiff.Options{
+		Ignore:    cmd.StringSlice("ignore"),
+		Unordered: cmd.Bool("unordered"),
+	}
+
+	if err := runDiff(ctx, opts, target2, message, diffOpts); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// errDiff is returned if responses of the targets are different
+var errDiff = errors.New("responses are different")
+
+// runDiff sends the requests to both targets and prints field level differences of the responses,
+// each request of unary and server streaming methods is sent in a separate call
+func runDiff(ctx context.Context, opts *startOpts, target2 string, message []byte, diffOpts *msgdiff.Options) error {
+	opts.InFormat = caller.JSON
+	opts.OutFormat = caller.JSON
+
+	return withApp(opts, func(a *app) error {
+		service, err := a.selectService(opts.Service)
+		if err != nil {
+			return err
+		}
+
+		method, err := a.selectMethod(a.getService(service), opts.Method)
+		if err != nil {
+			return err
+		}
+
+		messages, err := toJSONArray(message)
+		if err != nil {
+			return fmt.Errorf("invalid request json: %w", err)
+		}
+
+		calls := [][][]byte{messages}
+		if !method.IsStreamingClient() {
+			calls = make([][][]byte, len(messages))
+			for i, m := range messages {
+				calls[i] = [][]byte{m}
+			}
+		}
+
+		fmt.Fprintf(a.w, "--- %s\n+++ %s\n", opts.Target, target2)
+
+		different := 0
+		for i, requests := range calls {
+			left, err := a.diffCall(ctx, opts.Target, method, requests)
+			if err != nil {
+				return err
+			}
+
+			right, err := a.diffCall(ctx, target2, method, requests)
+			if err != nil {
+				return err
+			}
+
+			changes := []string{}
+			if left.code != right.code {
+				changes = append(changes, fmt.Sprintf("~ status: %s -> %s", left.code, right.code))
+			}
+
+			for _, c := range msgdiff.DiffValues(left.body, right.body, diffOpts) {
+				changes = append(changes, c.String())
+			}
+
+			if len(changes) == 0 {
+				continue
+			}
+
+			different++
+			fmt.Fprintf(a.w, "request #%d\n", i+1)
+			for _, c := range changes {
+				fmt.Fprintln(a.w, c)
+			}
+		}
+
+		fmt.Fprintf(a.w, "compared %d calls, %d with differences\n", len(calls), different)
+		if different > 0 {
+			return errDiff
+		}
+
+		return nil
+	})
+}
+
+type diffResult struct {
+	code string
+	// body is the response, array of responses for server streams
+	// or status message and details if the call failed
+	body any
+}
+
+func (a *app) diffCall(ctx context.Context, target string, method protoreflect.MethodDescriptor, requests [][]byte) (*diffResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.opts.Deadline)
+	defer cancel()
+
+	serviceCaller := caller.NewServiceCaller(a.connFact, caller.JSON, caller.JSON, a.opts.OutJsonNames)
+	result, errChan := serviceCaller.CallStream(ctx, target, method, requests, grpc.WaitForReady(true))
+
+	responses := []any{}
+	var callErr error
+	for done := false; !done; {
+		select {
+		case r := <-result:
+			if r == nil {
+				continue
+			}
+
+			var v any
+			if err := jsonnum.Unmarshal(r, &v); err != nil {
+				return nil, err
+			}
+			responses = append(responses, v)
+		case callErr = <-errChan:
+			done = true
+		}
+	}
+
+	if callErr != nil {
+		// the status of wrapped errors has the message of the whole chain
+		var grpcErr interface{ GRPCStatus() *status.Status }
+		if !errors.As(callErr, &grpcErr) {
+			return nil, fmt.Errorf("%s: %w", target, callErr)
+		}
+		st := grpcErr.GRPCStatus()
+
+		res := &diffResult{
+			code: st.Code().String(),
+			body: map[string]any{"message": st.Message()},
+		}
+
+		// details are compared as well if their types are known
+		b, err := protojson.MarshalOptions{
+			Resolver: dynamicpb.NewTypes(protoregistry.GlobalFiles),
+		}.Marshal(st.Proto())
+		var body map[string]any
+		if err == nil && jsonnum.Unmarshal(b, &body) == nil {
+			// the code is already compared
+			delete(body, "code")
+			res.body = body
+		}
+		return res, nil
+	}
+
+	res := &diffResult{code: "OK", body: responses}
+	if !method.IsStreamingServer() && len(responses) > 0 {
+		res.body = responses[len(responses)-1]
+	}
+
+	return res, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimi/grpc-client-cli/internal/mock"
+	"github.com/vadimi/grpc-client-cli/internal/msgdiff"
+	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
+	"github.com/vadimi/grpc-client-cli/internal/testing/grpc_testing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestDiffTargets(t *testing.T) {
+	s, err := mock.NewServer([]protoreflect.FileDescriptor{grpc_testing.File_test_proto}, []*mock.Stub{
+		{
+			Method:   "grpc_client_cli.testing.TestService/UnaryCall",
+			Match:    json.RawMessage(`{"user": {"id": 1}}`),
+			Response: json.RawMessage(`{"user": {"id": 1, "name": "changed"}}`),
+		},
+		{
+			Method:   "grpc_client_cli.testing.TestService/UnaryCall",
+			Match:    json.RawMessage(`{"user": {"id": 2}}`),
+			Response: json.RawMessage(`{"user": {"id": 2, "name": "same"}}`),
+		},
+		{
+			Method: "grpc_client_cli.testing.TestService/UnaryCall",
+			Status: &mock.StubStatus{Code: codes.NotFound, Message: "error"},
+		},
+	})
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	requests := []byte(`[
+		{"user": {"id": 1, "name": "test"}},
+		{"user": {"id": 2, "name": "same"}},
+		{"user": {"id": 3}, "response_status": {"code": 3}}
+	]`)
+
+	cases := []struct {
+		name     string
+		target2  string
+		opts     *msgdiff.Options
+		expected string
+		expErr   error
+	}{
+		{
+			name:     "Same",
+			target2:  app_testing.TestServerAddr(),
+			expected: "compared 3 calls, 0 with differences\n",
+		},
+		{
+			name:    "Different",
+			target2: lis.Addr().String(),
+			expected: "request #1\n" +
+				"~ user.name: \"test\" -> \"changed\"\n" +
+				"request #3\n" +
+				"~ status: InvalidArgument -> NotFound\n" +
+				"compared 3 calls, 2 with differences\n",
+			expErr: errDiff,
+		},
+		{
+			name:    "Ignore",
+			target2: lis.Addr().String(),
+			opts:    &msgdiff.Options{Ignore: []string{"user.name"}},
+			expected: "request #3\n" +
+				"~ status: InvalidArgument -> NotFound\n" +
+				"compared 3 calls, 1 with differences\n",
+			expErr: errDiff,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := runDiff(context.Background(), &startOpts{
+				Target:   app_testing.TestServerAddr(),
+				Service:  "grpc_client_cli.testing.TestService",
+				Method:   "UnaryCall",
+				Deadline: 15 * time.Second,
+				w:        buf,
+			}, c.target2, requests, c.opts)
+			assert.Equal(t, c.expErr, err)
+
+			header := "--- " + app_testing.TestServerAddr() + "\n+++ " + c.target2 + "\n"
+			assert.Equal(t, header+c.expected, buf.String())
+		})
+	}
+}
@@ -2,8 +2,32 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
-	ErrInterruptTerm = errors.New("interrupt terminal")
+	ErrInterruptTerm   = errors.New("interrupt terminal")
+	ErrCallInterrupted = errors.New("call interrupted")
 )
+
+// idleTimeoutError is returned when no stream message is received within the idle timeout,
+// it's temporary, so interactive session continues
+type idleTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e *idleTimeoutError) Error() string {
+	return fmt.Sprintf("no message received within %s idle timeout", e.timeout)
+}
+
+func (e *idleTimeoutError) Temporary() bool {
+	return true
+}
+
+func (e *idleTimeoutError) Unwrap() error {
+	return status.Error(codes.DeadlineExceeded, e.Error())
+}
@@ -40,7 +40,11 @@ func checkHealth(ctx context.Context, cmd *cli.Command, out io.Writer) error {
 		cACert := cmd.String("cacert")
 		cert := cmd.String("cert")
 		certKey := cmd.String("certkey")
-		cf = rpc.NewGrpcConnFactory(rpc.WithConnCred(insecure, cACert, cert, certKey))
+		tlsOpts, err := parseTLSOptions(cmd)
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		cf = rpc.NewGrpcConnFactory(rpc.WithConnCred(insecure, cACert, cert, certKey), rpc.WithTLSOptions(tlsOpts))
 	} else {
 		cf = rpc.NewGrpcConnFactory()
 	}
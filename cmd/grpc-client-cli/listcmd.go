@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/urfave/cli/v3"
+	"github.com/vadimi/grpc-client-cli/internal/cliext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+func listCmd(ctx context.Context, cmd *cli.Command) error {
+	opts := &startOpts{}
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if opts.Target == "" && len(opts.Protos) == 0 && len(opts.Protosets) == 0 {
+		return cli.Exit(errors.New("please provide service host:port to use reflection, proto or protoset files"), 1)
+	}
+
+	if err := runList(opts, parseEnum(cmd.Value("format"))); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+type listService struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+type listMethod struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// runList prints services or methods of the service if opts.Service is set
+func runList(opts *startOpts, format string) error {
+	return withApp(opts, func(a *app) error {
+		if opts.Service == "" {
+			services := make([]listService, len(a.servicesList))
+			for i, s := range a.servicesList {
+				services[i] = listService{Name: s.Name, File: s.File.Path()}
+			}
+			sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+			if format == outputFormatJSON {
+				return printJSON(a, services)
+			}
+
+			for _, s := range services {
+				fmt.Fprintln(a.w, s.Name)
+			}
+			return nil
+		}
+
+		name, err := a.selectService(opts.Service)
+		if err != nil {
+			return err
+		}
+
+		svc := a.getService(name)
+		methods := make([]listMethod, len(svc.Methods))
+		for i, m := range svc.Methods {
+			methods[i] = listMethod{
+				Name:   string(m.Name()),
+				Kind:   methodKind(m),
+				Input:  string(m.Input().FullName()),
+				Output: string(m.Output().FullName()),
+			}
+		}
+
+		if format == outputFormatJSON {
+			return printJSON(a, methods)
+		}
+
+		for _, m := range methods {
+			fmt.Fprintf(a.w, "%s\t%s\t%s\t%s\n", m.Name, m.Kind, m.Input, m.Output)
+		}
+		return nil
+	})
+}
+
+func methodKind(m protoreflect.MethodDescriptor) string {
+	switch {
+	case m.IsStreamingClient() && m.IsStreamingServer():
+		return "bidi_streaming"
+	case m.IsStreamingClient():
+		return "client_streaming"
+	case m.IsStreamingServer():
+		return "server_streaming"
+	default:
+		return "unary"
+	}
+}
+
+func printJSON(a *app, v any) error {
+	enc := json.NewEncoder(a.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func outputFormatFlag() cli.Flag {
+	return &cli.GenericFlag{
+		Name: "format",
+		Value: &cliext.EnumValue{
+			Enum:    []string{outputFormatText, outputFormatJSON},
+			Default: outputFormatText,
+		},
+		Usage: "output format: text or json",
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spyzhov/ajson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
+)
+
+func TestListServices(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := runList(&startOpts{
+		Target:   app_testing.TestServerAddr(),
+		Deadline: 15 * time.Second,
+		w:        buf,
+	}, outputFormatText)
+	require.NoError(t, err)
+
+	assert.Equal(t, "grpc.health.v1.Health\ngrpc.reflection.v1.ServerReflection\ngrpc.reflection.v1alpha.ServerReflection\ngrpc_client_cli.testing.TestService\n", buf.String())
+
+	buf.Reset()
+	err = runList(&startOpts{
+		Protos:   []string{"../../testdata/test.proto"},
+		Deadline: 15 * time.Second,
+		w:        buf,
+	}, outputFormatJSON)
+	require.NoError(t, err)
+
+	root, err := ajson.Unmarshal(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "grpc_client_cli.testing.TestService", jsonString(root, "$[0].name"))
+	assert.Equal(t, "test.proto", jsonString(root, "$[0].file"))
+}
+
+func TestListMethods(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := runList(&startOpts{
+		Target:   app_testing.TestServerAddr(),
+		Service:  "grpc_client_cli.testing.TestService",
+		Deadline: 15 * time.Second,
+		w:        buf,
+	}, outputFormatText)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Contains(t, lines, "UnaryCall\tunary\tgrpc_client_cli.testing.SimpleRequest\tgrpc_client_cli.testing.SimpleResponse")
+	assert.Contains(t, lines, "StreamingOutputCall\tserver_streaming\tgrpc_client_cli.testing.StreamingOutputCallRequest\tgrpc_client_cli.testing.StreamingOutputCallResponse")
+	assert.Contains(t, lines, "StreamingInputCall\tclient_streaming\tgrpc_client_cli.testing.StreamingInputCallRequest\tgrpc_client_cli.testing.StreamingInputCallResponse")
+	assert.Contains(t, lines, "FullDuplexCall\tbidi_streaming\tgrpc_client_cli.testing.StreamingOutputCallRequest\tgrpc_client_cli.testing.StreamingOutputCallResponse")
+
+	buf.Reset()
+	err = runList(&startOpts{
+		Target:   app_testing.TestServerAddr(),
+		Service:  "TestService",
+		Deadline: 15 * time.Second,
+		w:        buf,
+	}, outputFormatJSON)
+	require.NoError(t, err)
+
+	root, err := ajson.Unmarshal(buf.Bytes())
+	require.NoError(t, err)
+	methods, err := root.JSONPath("$[?(@.name == 'StreamingInputCall')].kind")
+	require.NoError(t, err)
+	require.Len(t, methods, 1)
+	assert.Equal(t, "client_streaming", methods[0].MustString())
+}
+
+func TestDescribe(t *testing.T) {
+	cases := []struct {
+		symbol   string
+		format   string
+		expected []string
+		jsonPath map[string]string
+	}{
+		{
+			symbol:   "grpc_client_cli.testing.User",
+			expected: []string{"// message grpc_client_cli.testing.User from test.proto", "message User {", "int32 id = 1;", "string name = 2;"},
+		},
+		{
+			symbol:   "grpc_client_cli.testing.TestService/UnaryCall",
+			expected: []string{"// method grpc_client_cli.testing.TestService.UnaryCall", "rpc UnaryCall ( SimpleRequest ) returns ( SimpleResponse );"},
+		},
+		{
+			symbol:   "grpc_client_cli.testing.User.name",
+			expected: []string{"// field grpc_client_cli.testing.User.name", "string name = 2;"},
+		},
+		{
+			symbol: "grpc_client_cli.testing.User",
+			format: outputFormatJSON,
+			jsonPath: map[string]string{
+				"$.kind":                       "message",
+				"$.name":                       "grpc_client_cli.testing.User",
+				"$.file":                       "test.proto",
+				"$.descriptor.field[1].name":   "name",
+				"$.descriptor.field[1].type":   "TYPE_STRING",
+				"$.descriptor.field[1].number": "2",
+			},
+		},
+		{
+			symbol: "grpc_client_cli.testing.TestService.StreamingOutputCall",
+			format: outputFormatJSON,
+			jsonPath: map[string]string{
+				"$.kind":                       "method",
+				"$.descriptor.inputType":       ".grpc_client_cli.testing.StreamingOutputCallRequest",
+				"$.descriptor.serverStreaming": "true",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.symbol+c.format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := runDescribe(&startOpts{
+				Target:   app_testing.TestServerAddr(),
+				Deadline: 15 * time.Second,
+				w:        buf,
+			}, c.symbol, c.format)
+			require.NoError(t, err)
+
+			for _, e := range c.expected {
+				assert.Contains(t, buf.String(), e)
+			}
+
+			if len(c.jsonPath) == 0 {
+				return
+			}
+
+			root, err := ajson.Unmarshal(buf.Bytes())
+			require.NoError(t, err)
+			for path, expected := range c.jsonPath {
+				nodes, err := root.JSONPath(path)
+				require.NoError(t, err)
+				require.Len(t, nodes, 1, path)
+				assert.Equal(t, expected, strings.Trim(nodes[0].String(), `"`), path)
+			}
+		})
+	}
+}
+
+func TestDescribeNotFound(t *testing.T) {
+	err := runDescribe(&startOpts{
+		Target:   app_testing.TestServerAddr(),
+		Deadline: 15 * time.Second,
+		w:        &bytes.Buffer{},
+	}, "grpc_client_cli.testing.Missing", outputFormatText)
+	assert.EqualError(t, err, "symbol grpc_client_cli.testing.Missing not found")
+}
@@ -3,14 +3,18 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/kballard/go-shellquote"
 	"github.com/urfave/cli/v3"
 	"github.com/vadimi/grpc-client-cli/internal/caller"
 	"github.com/vadimi/grpc-client-cli/internal/cliext"
 	"github.com/vadimi/grpc-client-cli/internal/fs"
+	"github.com/vadimi/grpc-client-cli/internal/rpc"
 )
 
 const (
@@ -51,13 +55,32 @@ func main() {
 				Name:    "deadline",
 				Aliases: []string{"d"},
 				Value:   "15s",
-				Usage:   "grpc call deadline in go duration format, e.g. 15s, 3m, 1h, etc. If no format is specified, defaults to seconds",
+				Usage:   "grpc call deadline in go duration format, e.g. 500ms, 15s, 3m, 1h, etc. If no format is specified, defaults to seconds",
+			},
+			&cli.StringFlag{
+				Name:  "reflect-timeout",
+				Value: "",
+				Usage: "timeout for grpc reflection calls used to discover services, in the same format as --deadline, defaults to --deadline value",
+			},
+			&cli.StringFlag{
+				Name:  "idle-timeout",
+				Value: "",
+				Usage: "cancel server and bidi streams only if no message is received within this duration, " +
+					"the call deadline is not applied to such streams",
 			},
 			&cli.BoolFlag{
 				Name:    "verbose",
 				Aliases: []string{"V"},
 				Usage:   "output some additional information like request time and message size",
 			},
+			&cli.GenericFlag{
+				Name: "verbose-format",
+				Value: &cliext.EnumValue{
+					Enum:    []string{verboseFormatText, verboseFormatJSON},
+					Default: verboseFormatText,
+				},
+				Usage: "format of verbose output: text or json, json is printed as a single line object",
+			},
 			&cli.BoolFlag{
 				Name:  "tls",
 				Value: false,
@@ -83,6 +106,54 @@ func main() {
 				Value: "",
 				Usage: "client private key, only valid with -cert option",
 			},
+			&cli.StringFlag{
+				Name:    "certkey-password",
+				Value:   "",
+				Usage:   "password for encrypted client private key",
+				Sources: cli.EnvVars("GRPC_CLIENT_CLI_CERTKEY_PASSWORD"),
+			},
+			&cli.StringFlag{
+				Name:  "pkcs12",
+				Value: "",
+				Usage: "PKCS#12 bundle with client certificate and private key, it can be used instead of -cert and -certkey options",
+			},
+			&cli.StringFlag{
+				Name:    "pkcs12-password",
+				Value:   "",
+				Usage:   "password for PKCS#12 bundle",
+				Sources: cli.EnvVars("GRPC_CLIENT_CLI_PKCS12_PASSWORD"),
+			},
+			&cli.BoolFlag{
+				Name:  "cacert-append",
+				Value: false,
+				Usage: "append --cacert certificate to the system CA pool instead of replacing it",
+			},
+			&cli.StringFlag{
+				Name:  "servername",
+				Value: "",
+				Usage: "override server name used for SNI and certificate verification, unlike --authority it doesn't change :authority header",
+			},
+			&cli.StringFlag{
+				Name:        "tls-min-version",
+				Value:       "",
+				Usage:       "minimum TLS version, one of 1.0, 1.1, 1.2, 1.3",
+				DefaultText: "1.2",
+			},
+			&cli.StringFlag{
+				Name:        "tls-max-version",
+				Value:       "",
+				Usage:       "maximum TLS version, one of 1.0, 1.1, 1.2, 1.3",
+				DefaultText: "1.3",
+			},
+			&cli.StringSliceFlag{
+				Name:  "tls-ciphers",
+				Usage: "TLS 1.0-1.2 cipher suites to use, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, separate multiple suites with comma",
+			},
+			&cli.StringSliceFlag{
+				Name: "tls-pin",
+				Usage: "server certificate pin, either sha256/<base64 SHA-256 of public key> or cert-sha256/<hex SHA-256 fingerprint>. " +
+					"One of the certificates in the chain must match any of the pins, the check is done even with --insecure option",
+			},
 			&cli.StringSliceFlag{
 				Name:     "proto",
 				Required: false,
@@ -90,6 +161,12 @@ func main() {
 					"if this option is provided service reflection would be ignored. " +
 					"In order to provide multiple paths, separate them with comma",
 			},
+			&cli.StringSliceFlag{
+				Name:     "protoset",
+				Required: false,
+				Usage: "binary FileDescriptorSet files, e.g. produced by protoc --descriptor_set_out --include_imports, " +
+					"if this option is provided service reflection would be ignored",
+			},
 			&cli.StringSliceFlag{
 				Name:     "protoimports",
 				Required: false,
@@ -103,6 +180,107 @@ func main() {
 				Usage:       "extra header(s) to include in the request",
 				DefaultText: "no extra headers",
 			},
+			&cli.StringFlag{
+				Name:  "auth-exec",
+				Value: "",
+				Usage: "command that prints JSON with headers to include in the request and optional expiration timestamp, " +
+					`e.g. {"headers": {"authorization": "Bearer token"}, "expirationTimestamp": "2025-01-01T00:00:00Z"}. ` +
+					"The command is invoked again when the headers expire",
+			},
+			&cli.StringFlag{
+				Name:  "oauth2-token-url",
+				Value: "",
+				Usage: "OAuth2 token endpoint, if specified access token is fetched using client credentials grant and sent in authorization header",
+			},
+			&cli.StringFlag{
+				Name:  "oauth2-client-id",
+				Value: "",
+				Usage: "OAuth2 client id",
+			},
+			&cli.StringFlag{
+				Name:    "oauth2-client-secret",
+				Value:   "",
+				Usage:   "OAuth2 client secret",
+				Sources: cli.EnvVars("GRPC_CLIENT_CLI_OAUTH2_CLIENT_SECRET"),
+			},
+			&cli.StringSliceFlag{
+				Name:  "oauth2-scopes",
+				Usage: "OAuth2 scopes to request, separate multiple scopes with comma",
+			},
+			&cli.StringFlag{
+				Name:  "oauth2-audience",
+				Value: "",
+				Usage: "OAuth2 audience to request",
+			},
+			&cli.StringFlag{
+				Name:    "oauth2-subject-token",
+				Value:   "",
+				Usage:   "if specified OAuth2 token exchange grant is used instead of client credentials to exchange this token for an access token",
+				Sources: cli.EnvVars("GRPC_CLIENT_CLI_OAUTH2_SUBJECT_TOKEN"),
+			},
+			&cli.StringFlag{
+				Name:        "oauth2-subject-token-type",
+				Value:       "",
+				Usage:       "OAuth2 token exchange subject token type",
+				DefaultText: "urn:ietf:params:oauth:token-type:access_token",
+			},
+			&cli.StringFlag{
+				Name:  "jwt-key",
+				Value: "",
+				Usage: "PEM private key (RSA, ECDSA P-256, Ed25519) or service account JSON key file, " +
+					"if specified self-signed JWT token is sent in authorization header",
+			},
+			&cli.StringFlag{
+				Name:        "jwt-issuer",
+				Value:       "",
+				Usage:       "JWT iss claim",
+				DefaultText: "client_email from service account key",
+			},
+			&cli.StringFlag{
+				Name:        "jwt-subject",
+				Value:       "",
+				Usage:       "JWT sub claim",
+				DefaultText: "issuer",
+			},
+			&cli.StringFlag{
+				Name:        "jwt-audience",
+				Value:       "",
+				Usage:       "JWT aud claim",
+				DefaultText: "fully qualified service name",
+			},
+			&cli.GenericFlag{
+				Name:        "jwt-claim",
+				Value:       cliext.NewMapValue(),
+				Usage:       `extra JWT claim(s) in "key: value" format, JSON values (numbers, booleans, objects) are supported`,
+				DefaultText: "no extra claims",
+			},
+			&cli.StringFlag{
+				Name:  "jwt-lifetime",
+				Value: "15m",
+				Usage: "JWT lifetime, the token is refreshed before it expires. Examples: 10m, 1h",
+			},
+			&cli.StringFlag{
+				Name:  "otel-endpoint",
+				Value: "",
+				Usage: "export OpenTelemetry client spans to OTLP gRPC collector, e.g. http://localhost:4317 for plaintext " +
+					"or collector:4317 for TLS, trace context is propagated to the server in traceparent header",
+			},
+			&cli.StringFlag{
+				Name:  "otel-file",
+				Value: "",
+				Usage: "append OpenTelemetry client spans as JSON objects to the file",
+			},
+			&cli.StringFlag{
+				Name:  "otel-service-name",
+				Value: "grpc-client-cli",
+				Usage: "service.name resource attribute of exported spans",
+			},
+			&cli.GenericFlag{
+				Name:        "otel-baggage",
+				Value:       cliext.NewMapValue(),
+				Usage:       `baggage propagated to the server in "key: value" format`,
+				DefaultText: "no baggage",
+			},
 			&cli.StringFlag{
 				Name:  "authority",
 				Value: "",
@@ -154,6 +332,62 @@ func main() {
 				Value: false,
 				Usage: "If true uses json_name properties/camel casing in message output",
 			},
+			&cli.IntFlag{
+				Name:  "retry",
+				Value: 0,
+				Usage: "max number of call attempts including the original one, calls are retried until the first response message is received",
+			},
+			&cli.StringSliceFlag{
+				Name:  "retry-codes",
+				Value: []string{"UNAVAILABLE"},
+				Usage: "status codes to retry, separate multiple codes with comma",
+			},
+			&cli.StringFlag{
+				Name:  "retry-initial-backoff",
+				Value: "100ms",
+				Usage: "delay before the first retry",
+			},
+			&cli.StringFlag{
+				Name:  "retry-max-backoff",
+				Value: "5s",
+				Usage: "max delay between retries",
+			},
+			&cli.FloatFlag{
+				Name:  "retry-multiplier",
+				Value: 2,
+				Usage: "backoff multiplier applied after each retry",
+			},
+			&cli.FloatFlag{
+				Name:  "retry-jitter",
+				Value: 0.2,
+				Usage: "randomize backoff by up to this fraction, from 0 to 1",
+			},
+			&cli.GenericFlag{
+				Name: "compress",
+				Value: &cliext.EnumValue{
+					Enum:    rpc.Compressors,
+					Default: "none",
+				},
+				Usage: "compress requests using one of the algorithms: " + strings.Join(rpc.Compressors, ", "),
+			},
+			&cli.GenericFlag{
+				Name: "protocol",
+				Value: &cliext.EnumValue{
+					Enum:    rpc.Protocols,
+					Default: rpc.ProtocolGRPC,
+				},
+				Usage: "protocol used for service calls: " + strings.Join(rpc.Protocols, ", ") +
+					", grpc-web and http support unary and server streaming calls only, http calls methods using google.api.http rules," +
+					" reflection always uses grpc",
+			},
+			&cli.GenericFlag{
+				Name: "connect-codec",
+				Value: &cliext.EnumValue{
+					Enum:    rpc.ConnectCodecs,
+					Default: rpc.ConnectCodecProto,
+				},
+				Usage: "message encoding used by connect protocol: " + strings.Join(rpc.ConnectCodecs, ", "),
+			},
 			&cli.GenericFlag{
 				Name: "reflect-version",
 				Value: &cliext.EnumValue{
@@ -178,6 +412,126 @@ func main() {
 				Usage:  "grpc health check",
 				Action: healthCmd,
 			},
+			{
+				Name:   "tls-info",
+				Usage:  "perform TLS handshake and print negotiated parameters and server certificate chain",
+				Action: tlsInfoCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "expiry-window",
+						Value: "",
+						Usage: "exit with non-zero code if any certificate in the chain expires within this duration, e.g. 720h",
+					},
+				},
+			},
+			{
+				Name:   "serve-mock",
+				Usage:  "start local grpc server implementing services from reflection of the target, proto or protoset files",
+				Action: serveMockCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Value: "localhost:50051",
+						Usage: "address the mock server listens on",
+					},
+					&cli.StringFlag{
+						Name:  "stubs",
+						Value: "",
+						Usage: "json file with canned responses, methods without matching stubs return sample messages",
+					},
+				},
+			},
+			{
+				Name:   "proxy",
+				Usage:  "forward calls from the local address to the target and record them to the file",
+				Action: proxyCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Value: "localhost:50052",
+						Usage: "address the proxy listens on",
+					},
+					&cli.StringFlag{
+						Name:  "record",
+						Value: "grpc-client-cli.rec.jsonl",
+						Usage: "file to append recorded calls to",
+					},
+				},
+			},
+			{
+				Name:   "replay",
+				Usage:  "send requests recorded by proxy to the target and report differences in responses",
+				Action: replayCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "record",
+						Value: "grpc-client-cli.rec.jsonl",
+						Usage: "file with recorded calls",
+					},
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "send the same requests to two targets and print field level differences of the responses",
+				ArgsUsage: "host:port host:port",
+				Action:    diffCmd,
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "ignore",
+						Usage: "response field paths to skip without indexes of repeated fields, e.g. created_at or items.id",
+					},
+					&cli.BoolFlag{
+						Name:  "unordered",
+						Usage: "compare repeated fields regardless of the order of their elements",
+					},
+				},
+			},
+			{
+				Name:   "compare",
+				Usage:  "compare services with another descriptor source and report breaking changes",
+				Action: compareCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "against",
+						Value: "",
+						Usage: "host:port of the service to compare with using reflection",
+					},
+					&cli.StringSliceFlag{
+						Name:  "against-proto",
+						Usage: "proto files or directories to compare with",
+					},
+					&cli.StringSliceFlag{
+						Name:  "against-protoset",
+						Usage: "protoset files to compare with",
+					},
+					&cli.BoolFlag{
+						Name:  "wire-only",
+						Usage: "exit with non-zero code only if changes break binary encoding",
+					},
+				},
+			},
+			{
+				Name:   "list",
+				Usage:  "list services or methods of the service set by --service",
+				Action: listCmd,
+				Flags: []cli.Flag{
+					outputFormatFlag(),
+				},
+			},
+			{
+				Name:      "describe",
+				Usage:     "print definition of the service, method, message, enum or field by fully qualified name",
+				ArgsUsage: "symbol [host:port]",
+				Action:    describeCmd,
+				Flags: []cli.Flag{
+					outputFormatFlag(),
+				},
+			},
+			{
+				Name:   "template",
+				Usage:  "print sample request of the method set by --service and --method with every field populated",
+				Action: templateCmd,
+			},
 		},
 	}
 	app.Run(context.Background(), os.Args)
@@ -203,6 +557,51 @@ func baseCmd(ctx context.Context, cmd *cli.Command) (e error) {
 }
 
 func runApp(_ context.Context, cmd *cli.Command, opts *startOpts) (e error) {
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return err
+	}
+
+	if opts.Target == "" {
+		err := errors.New("please provide service host:port")
+		return err
+	}
+
+	input := cmd.String("input")
+
+	message, err := getMessage(input)
+	if err != nil {
+		return err
+	}
+
+	// if message is not empty we are not in interactive mode
+	opts.IsInteractive = len(message) == 0
+
+	a, err := newApp(opts)
+	defer func() {
+		if a == nil {
+			return
+		}
+
+		if err := a.Close(); err != nil {
+			e = err
+		}
+	}()
+
+	if err != nil {
+		return err
+	}
+
+	err = a.Start(message)
+
+	if err != nil && err != terminal.InterruptErr && err != ErrInterruptTerm {
+		return err
+	}
+
+	return nil
+}
+
+// parseStartOpts reads global flags, target is empty if it's not specified
+func parseStartOpts(cmd *cli.Command, opts *startOpts) error {
 	target := cmd.String("address")
 	if target == "" {
 		if cmd.Args().Len() > 0 {
@@ -210,20 +609,30 @@ func runApp(_ context.Context, cmd *cli.Command, opts *startOpts) (e error) {
 		}
 	}
 
-	if target == "" {
-		err := errors.New("please provide service host:port")
-		return err
-	}
-
 	deadline, err := cliext.ParseDuration(cmd.String("deadline"))
 	if err != nil {
 		return err
 	}
 
+	if v := cmd.String("reflect-timeout"); v != "" {
+		opts.ReflectTimeout, err = cliext.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid reflect-timeout: %w", err)
+		}
+	}
+
+	if v := cmd.String("idle-timeout"); v != "" {
+		opts.IdleTimeout, err = cliext.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid idle-timeout: %w", err)
+		}
+	}
+
 	opts.Service = cmd.String("service")
 	opts.Method = cmd.String("method")
-	opts.Deadline = int(deadline.Seconds())
+	opts.Deadline = deadline
 	opts.Verbose = cmd.Bool("verbose")
+	opts.VerboseFormat = parseEnum(cmd.Value("verbose-format"))
 	opts.Target = target
 	opts.Authority = cmd.String("authority")
 	opts.TLS = cmd.Bool("tls")
@@ -231,7 +640,12 @@ func runApp(_ context.Context, cmd *cli.Command, opts *startOpts) (e error) {
 	opts.CACert = cmd.String("cacert")
 	opts.Cert = cmd.String("cert")
 	opts.CertKey = cmd.String("certkey")
+	opts.TLSOptions, err = parseTLSOptions(cmd)
+	if err != nil {
+		return err
+	}
 	opts.Protos = fs.NormalizePaths(cmd.StringSlice("proto"))
+	opts.Protosets = fs.NormalizePaths(cmd.StringSlice("protoset"))
 	opts.ProtoImports = fs.NormalizePaths(cmd.StringSlice("protoimports"))
 	opts.InFormat = parseMsgFormat(cmd.Value("informat"))
 	opts.OutFormat = parseMsgFormat(cmd.Value("outformat"))
@@ -241,38 +655,56 @@ func runApp(_ context.Context, cmd *cli.Command, opts *startOpts) (e error) {
 	opts.MaxRecvMsgSize = int(cmd.Int("max-receive-message-size"))
 	opts.OutJsonNames = cmd.Bool("out-json-names")
 	opts.GrpcReflectVersion = parseReflectVersion(cmd.Value("reflect-version"))
+	opts.Compressor = parseEnum(cmd.Value("compress"))
+	opts.Protocol = parseEnum(cmd.Value("protocol"))
+	opts.ConnectCodec = parseEnum(cmd.Value("connect-codec"))
+	// the flags are not defined by every subcommand
+	compress := opts.Compressor != "" && opts.Compressor != "none"
+	if compress && opts.Protocol != "" && opts.Protocol != rpc.ProtocolGRPC {
+		return fmt.Errorf("--compress is not supported by %s protocol", opts.Protocol)
+	}
 
-	input := cmd.String("input")
-
-	message, err := getMessage(input)
+	opts.Retry, err = parseRetryPolicy(cmd)
 	if err != nil {
 		return err
 	}
 
-	// if message is not empty we are not in interactive mode
-	opts.IsInteractive = len(message) == 0
-
-	a, err := newApp(opts)
-	defer func() {
-		if a == nil {
-			return
+	if authExec := cmd.String("auth-exec"); authExec != "" {
+		opts.AuthExec, err = shellquote.Split(authExec)
+		if err != nil {
+			return fmt.Errorf("invalid auth-exec command: %w", err)
 		}
+	}
 
-		if err := a.Close(); err != nil {
-			e = err
+	if tokenURL := cmd.String("oauth2-token-url"); tokenURL != "" {
+		opts.OAuth2 = &rpc.OAuth2Config{
+			TokenURL:         tokenURL,
+			ClientID:         cmd.String("oauth2-client-id"),
+			ClientSecret:     cmd.String("oauth2-client-secret"),
+			Scopes:           cmd.StringSlice("oauth2-scopes"),
+			Audience:         cmd.String("oauth2-audience"),
+			SubjectToken:     cmd.String("oauth2-subject-token"),
+			SubjectTokenType: cmd.String("oauth2-subject-token-type"),
 		}
-	}()
-
-	if err != nil {
-		return err
 	}
 
-	err = a.Start(message)
+	if jwtKey := cmd.String("jwt-key"); jwtKey != "" {
+		lifetime, err := cliext.ParseDuration(cmd.String("jwt-lifetime"))
+		if err != nil {
+			return err
+		}
 
-	if err != nil && err != terminal.InterruptErr && err != ErrInterruptTerm {
-		return err
+		opts.JWT = &rpc.JWTConfig{
+			KeyFile:  jwtKey,
+			Issuer:   cmd.String("jwt-issuer"),
+			Subject:  cmd.String("jwt-subject"),
+			Audience: cmd.String("jwt-audience"),
+			Claims:   rpc.ParseJWTClaims(cliext.ParseMapValue(cmd.Value("jwt-claim"))),
+			Lifetime: lifetime,
+		}
 	}
 
+	opts.Tracing = parseTracingConfig(cmd)
 	return nil
 }
 
@@ -344,3 +776,99 @@ func parseReflectVersion(val any) caller.GrpcReflectVersion {
 
 	return caller.GrpcReflectV1Alpha
 }
+
+func parseEnum(val any) string {
+	if enum, ok := val.(*cliext.EnumValue); ok {
+		return enum.String()
+	}
+
+	return ""
+}
+
+func parseRetryPolicy(cmd *cli.Command) (*rpc.RetryPolicy, error) {
+	maxAttempts := int(cmd.Int("retry"))
+	if maxAttempts < 2 {
+		return nil, nil
+	}
+
+	retryCodes, err := rpc.ParseRetryCodes(cmd.StringSlice("retry-codes"))
+	if err != nil {
+		return nil, err
+	}
+
+	initialBackoff, err := cliext.ParseDuration(cmd.String("retry-initial-backoff"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry-initial-backoff: %w", err)
+	}
+
+	maxBackoff, err := cliext.ParseDuration(cmd.String("retry-max-backoff"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry-max-backoff: %w", err)
+	}
+
+	jitter := cmd.Float("retry-jitter")
+	if jitter < 0 || jitter > 1 {
+		return nil, errors.New("retry-jitter should be between 0 and 1")
+	}
+
+	return &rpc.RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		Codes:          retryCodes,
+		InitialBackoff: initialBackoff,
+		MaxBackoff:     maxBackoff,
+		Multiplier:     cmd.Float("retry-multiplier"),
+		Jitter:         jitter,
+	}, nil
+}
+
+func parseTLSOptions(cmd *cli.Command) (*rpc.TLSOptions, error) {
+	minVersion, err := rpc.ParseTLSVersion(cmd.String("tls-min-version"))
+	if err != nil {
+		return nil, err
+	}
+
+	maxVersion, err := rpc.ParseTLSVersion(cmd.String("tls-max-version"))
+	if err != nil {
+		return nil, err
+	}
+
+	ciphers, err := rpc.ParseCipherSuites(cmd.StringSlice("tls-ciphers"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.TLSOptions{
+		ServerName:      cmd.String("servername"),
+		MinVersion:      minVersion,
+		MaxVersion:      maxVersion,
+		CipherSuites:    ciphers,
+		AppendSystemCAs: cmd.Bool("cacert-append"),
+		CertKeyPassword: cmd.String("certkey-password"),
+		PKCS12:          cmd.String("pkcs12"),
+		PKCS12Password:  cmd.String("pkcs12-password"),
+		Pins:            cmd.StringSlice("tls-pin"),
+	}, nil
+}
+
+func parseTracingConfig(cmd *cli.Command) *rpc.TracingConfig {
+	endpoint, file := cmd.String("otel-endpoint"), cmd.String("otel-file")
+	if endpoint == "" && file == "" {
+		return nil
+	}
+
+	cfg := &rpc.TracingConfig{
+		Endpoint:    endpoint,
+		File:        file,
+		ServiceName: cmd.String("otel-service-name"),
+		Baggage:     map[string]string{},
+	}
+
+	// the last value wins if the key is repeated
+	for k, v := range cliext.ParseMapValue(cmd.Value("otel-baggage")) {
+		if len(v) > 0 {
+			cfg.Baggage[k] = v[len(v)-1]
+		}
+	}
+
+	return cfg
+}
@@ -25,7 +25,9 @@ type msgBuffer struct {
 	nextPrompt string
 	helpText   string
 	protoText  string
-	w          io.Writer
+	// templateText is built when the template is requested for the first time
+	templateText string
+	w            io.Writer
 }
 
 type msgBufferOptions struct {
@@ -68,6 +70,9 @@ func (b *msgBuffer) ReadMessage(opts ...ReadLineOpt) ([]byte, error) {
 		case "??", "proto":
 			fmt.Fprintln(b.w, b.protoText)
 			continue
+		case "???", "template":
+			fmt.Fprintln(b.w, b.template())
+			continue
 		}
 
 		if err := b.validate(normMsg); err != nil {
@@ -79,6 +84,13 @@ func (b *msgBuffer) ReadMessage(opts ...ReadLineOpt) ([]byte, error) {
 	}
 }
 
+func (b *msgBuffer) template() string {
+	if b.templateText == "" {
+		b.templateText = caller.MessageTemplate(b.opts.messageDesc, b.opts.msgFormat)
+	}
+	return b.templateText
+}
+
 func (b *msgBuffer) ReadMessages() ([][]byte, error) {
 	if b.opts == nil || b.opts.reader == nil {
 		return nil, errors.New("no msg reader is configured")
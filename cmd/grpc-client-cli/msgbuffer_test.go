@@ -92,3 +92,43 @@ func TestHelpCmdMsgBuffer(t *testing.T) {
 		t.Errorf("response_size is invalid: %s", res)
 	}
 }
+
+func TestTemplateCmdMsgBuffer(t *testing.T) {
+	rl := newTestMsgReader([]testMsg{
+		{[]byte("template"), nil},
+		{nil, ErrInterruptTerm},
+	})
+
+	md := (*grpc_testing.SimpleRequest)(nil).ProtoReflect().Descriptor()
+
+	buf := &bytes.Buffer{}
+	b := newMsgBuffer(&msgBufferOptions{
+		reader:      rl,
+		messageDesc: md,
+		msgFormat:   caller.JSON,
+		w:           buf,
+	})
+
+	if b.templateText != "" {
+		t.Error("template is built before it's requested")
+	}
+
+	_, err := b.ReadMessage()
+	if err != nil && err != ErrInterruptTerm {
+		t.Fatal(err)
+	}
+
+	res := buf.Bytes()
+	root, err := ajson.Unmarshal(res)
+	if err != nil {
+		t.Fatalf("error unmarshaling result json: %v", err)
+	}
+
+	if jsonString(root, "$.payload.type") != "COMPRESSABLE" {
+		t.Errorf("payload type is invalid: %s", res)
+	}
+
+	if err := b.validate(res); err != nil {
+		t.Errorf("template is not a valid message: %v", err)
+	}
+}
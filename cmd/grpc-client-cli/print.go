@@ -1,13 +1,19 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/gookit/color"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoprint"
 	"github.com/vadimi/grpc-client-cli/internal/rpc"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
@@ -25,6 +31,9 @@ func printVerbose(w io.Writer, s *rpc.Stats, rpcErr error) {
 	fmt.Fprintln(w)
 
 	fmt.Fprintln(w, color.Bold.Sprint("Method: ")+s.FullMethod())
+	if traceID := s.TraceID(); traceID != "" {
+		fmt.Fprintln(w, color.Bold.Sprint("Trace ID: ")+traceID)
+	}
 
 	rpcStatus := status.Code(rpcErr)
 	fmt.Fprintln(w, color.Bold.Sprint("Status: ")+color.FgLightYellow.Sprintf("%d", rpcStatus)+" "+color.OpItalic.Sprint(rpcStatus))
@@ -48,9 +57,279 @@ func printVerbose(w io.Writer, s *rpc.Stats, rpcErr error) {
 		}
 	}
 
+	if attempts := s.Attempts(); len(attempts) > 0 {
+		fmt.Fprintln(w, color.OpItalic.Sprint("\nRetried Attempts:"))
+		for i, a := range attempts {
+			code := status.Code(a.Err)
+			fmt.Fprintln(w, color.Bold.Sprintf("%d: ", i+1)+color.FgLightYellow.Sprintf("%d", code)+" "+color.OpItalic.Sprint(code)+
+				" "+status.Convert(a.Err).Message()+color.LightGreen.Sprintf(" (retried after %s)", a.Backoff.Round(time.Millisecond)))
+		}
+	}
+
+	if state := s.TLSState(); state != nil {
+		fmt.Fprintln(w, color.OpItalic.Sprint("\nTLS:"))
+		printTLSState(w, state)
+	}
+
+	// per message stats are useful for streams only
+	if msgs := s.Messages(); len(msgs) > 1 {
+		fmt.Fprintln(w, color.OpItalic.Sprint("\nMessages:"))
+		begin := s.BeginTime()
+		for i, m := range msgs {
+			fmt.Fprintln(w, color.Bold.Sprintf("%d: ", i+1)+color.FgLightYellow.Sprintf("+%s", m.RecvTime.Sub(begin).Round(time.Microsecond))+
+				color.LightGreen.Sprintf(" gap %s", m.Gap.Round(time.Microsecond))+fmt.Sprintf(" %d bytes", m.Size)+compressedSize(m))
+		}
+	}
+
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, color.Bold.Sprint("Request duration: ")+color.FgLightYellow.Sprint(s.Duration))
+	if ct := s.ConnTiming(); ct != nil {
+		fmt.Fprintln(w, color.Bold.Sprint("Name resolution: ")+color.FgLightYellow.Sprint(optionalDuration(ct.Resolve)))
+		fmt.Fprintln(w, color.Bold.Sprint("TCP connect: ")+color.FgLightYellow.Sprint(optionalDuration(ct.Connect)))
+		if ct.TLSHandshake > 0 {
+			fmt.Fprintln(w, color.Bold.Sprint("TLS handshake: ")+color.FgLightYellow.Sprint(ct.TLSHandshake))
+		}
+	}
+	if ttfh := s.TimeToFirstHeader(); ttfh > 0 {
+		fmt.Fprintln(w, color.Bold.Sprint("Time to first header: ")+color.FgLightYellow.Sprint(ttfh))
+	}
+	if len(s.Messages()) > 0 {
+		fmt.Fprintln(w, color.Bold.Sprint("Time to first message: ")+color.FgLightYellow.Sprint(s.FirstMessageLatency()))
+	}
 	fmt.Fprintln(w, color.Bold.Sprint("Request size: ")+color.FgLightYellow.Sprintf("%d bytes", s.ReqSize()))
 	fmt.Fprintln(w, color.Bold.Sprint("Response size: ")+color.FgLightYellow.Sprintf("%d bytes", s.RespSize()))
+	if s.ReqPayloadSize() != s.ReqCompressedSize() {
+		fmt.Fprintln(w, color.Bold.Sprint("Request compression: ")+
+			color.FgLightYellow.Sprintf("%d bytes uncompressed, %d bytes compressed", s.ReqPayloadSize(), s.ReqCompressedSize()))
+	}
+	if s.RespPayloadSize() != s.RespCompressedSize() {
+		fmt.Fprintln(w, color.Bold.Sprint("Response compression: ")+
+			color.FgLightYellow.Sprintf("%d bytes uncompressed, %d bytes compressed", s.RespPayloadSize(), s.RespCompressedSize()))
+	}
 	fmt.Fprintln(w)
 }
+
+const (
+	verboseFormatText = "text"
+	verboseFormatJSON = "json"
+)
+
+// verboseEnvelope is the JSON representation of verbose output
+type verboseEnvelope struct {
+	Method           string                `json:"method"`
+	TraceID          string                `json:"trace_id,omitempty"`
+	Status           verboseStatus         `json:"status"`
+	RequestHeaders   metadata.MD           `json:"request_headers,omitempty"`
+	ResponseHeaders  metadata.MD           `json:"response_headers,omitempty"`
+	ResponseTrailers metadata.MD           `json:"response_trailers,omitempty"`
+	Attempts         []verboseAttempt      `json:"retried_attempts,omitempty"`
+	DurationMs       float64               `json:"duration_ms"`
+	ConnTiming       *verboseConnTiming    `json:"connection,omitempty"`
+	FirstHeaderMs    *float64              `json:"time_to_first_header_ms,omitempty"`
+	FirstMessageMs   *float64              `json:"time_to_first_message_ms,omitempty"`
+	RequestSize      int64                 `json:"request_size"`
+	ResponseSize     int64                 `json:"response_size"`
+	TLS              *verboseTLS           `json:"tls,omitempty"`
+	Messages         []verboseMessageStats `json:"messages,omitempty"`
+}
+
+type verboseStatus struct {
+	Code    int    `json:"code"`
+	Name    string `json:"name"`
+	Message string `json:"message,omitempty"`
+}
+
+type verboseAttempt struct {
+	Status    verboseStatus `json:"status"`
+	BackoffMs float64       `json:"backoff_ms"`
+}
+
+// verboseConnTiming has null resolve and connect time if they are not measured
+type verboseConnTiming struct {
+	ResolveMs      *float64 `json:"resolve_ms"`
+	ConnectMs      *float64 `json:"connect_ms"`
+	TLSHandshakeMs float64  `json:"tls_handshake_ms,omitempty"`
+}
+
+type verboseMessageStats struct {
+	RecvTime       time.Time `json:"recv_time"`
+	OffsetMs       float64   `json:"offset_ms"`
+	GapMs          float64   `json:"gap_ms"`
+	Size           int       `json:"size"`
+	CompressedSize int       `json:"compressed_size"`
+	WireSize       int       `json:"wire_size"`
+}
+
+// verboseTLS is the negotiated TLS state, the same as printed in text format
+type verboseTLS struct {
+	Version          string               `json:"version"`
+	CipherSuite      string               `json:"cipher_suite"`
+	ALPN             string               `json:"alpn,omitempty"`
+	PeerCertificates []verboseCertificate `json:"peer_certificates"`
+}
+
+type verboseCertificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	SANs      []string  `json:"sans,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+func newVerboseTLS(state *tls.ConnectionState) *verboseTLS {
+	res := &verboseTLS{
+		Version:          tls.VersionName(state.Version),
+		CipherSuite:      tls.CipherSuiteName(state.CipherSuite),
+		ALPN:             state.NegotiatedProtocol,
+		PeerCertificates: []verboseCertificate{},
+	}
+
+	for _, c := range state.PeerCertificates {
+		res.PeerCertificates = append(res.PeerCertificates, verboseCertificate{
+			Subject:   c.Subject.String(),
+			Issuer:    c.Issuer.String(),
+			SANs:      certificateSANs(c),
+			NotBefore: c.NotBefore.UTC(),
+			NotAfter:  c.NotAfter.UTC(),
+		})
+	}
+
+	return res
+}
+
+func newVerboseStatus(err error) verboseStatus {
+	st := status.Convert(err)
+	return verboseStatus{
+		Code:    int(st.Code()),
+		Name:    st.Code().String(),
+		Message: st.Message(),
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func optionalDurationMs(d *time.Duration) *float64 {
+	if d == nil {
+		return nil
+	}
+	ms := durationMs(*d)
+	return &ms
+}
+
+// optionalDuration formats not measured durations as n/a
+func optionalDuration(d *time.Duration) string {
+	if d == nil {
+		return "n/a"
+	}
+	return d.String()
+}
+
+// printVerboseJSON writes verbose output as a single line JSON object
+func printVerboseJSON(w io.Writer, s *rpc.Stats, rpcErr error) error {
+	env := verboseEnvelope{
+		Method:           s.FullMethod(),
+		TraceID:          s.TraceID(),
+		Status:           newVerboseStatus(rpcErr),
+		RequestHeaders:   s.ReqHeaders(),
+		ResponseHeaders:  s.RespHeaders(),
+		ResponseTrailers: s.RespTrailers(),
+		DurationMs:       durationMs(s.Duration),
+		RequestSize:      s.ReqSize(),
+		ResponseSize:     s.RespSize(),
+	}
+
+	for _, a := range s.Attempts() {
+		env.Attempts = append(env.Attempts, verboseAttempt{
+			Status:    newVerboseStatus(a.Err),
+			BackoffMs: durationMs(a.Backoff),
+		})
+	}
+
+	if ct := s.ConnTiming(); ct != nil {
+		env.ConnTiming = &verboseConnTiming{
+			ResolveMs:      optionalDurationMs(ct.Resolve),
+			ConnectMs:      optionalDurationMs(ct.Connect),
+			TLSHandshakeMs: durationMs(ct.TLSHandshake),
+		}
+	}
+
+	if state := s.TLSState(); state != nil {
+		env.TLS = newVerboseTLS(state)
+	}
+
+	if ttfh := s.TimeToFirstHeader(); ttfh > 0 {
+		firstHeader := durationMs(ttfh)
+		env.FirstHeaderMs = &firstHeader
+	}
+
+	if msgs := s.Messages(); len(msgs) > 0 {
+		first := durationMs(s.FirstMessageLatency())
+		env.FirstMessageMs = &first
+
+		begin := s.BeginTime()
+		for _, m := range msgs {
+			env.Messages = append(env.Messages, verboseMessageStats{
+				RecvTime:       m.RecvTime,
+				OffsetMs:       durationMs(m.RecvTime.Sub(begin)),
+				GapMs:          durationMs(m.Gap),
+				Size:           m.Size,
+				CompressedSize: m.CompressedSize,
+				WireSize:       m.WireSize,
+			})
+		}
+	}
+
+	return json.NewEncoder(w).Encode(env)
+}
+
+func printTLSState(w io.Writer, state *tls.ConnectionState) {
+	fmt.Fprintln(w, color.Bold.Sprint("Version: ")+color.FgLightYellow.Sprint(tls.VersionName(state.Version)))
+	fmt.Fprintln(w, color.Bold.Sprint("Cipher suite: ")+color.FgLightYellow.Sprint(tls.CipherSuiteName(state.CipherSuite)))
+
+	alpn := state.NegotiatedProtocol
+	if alpn == "" {
+		alpn = "none"
+	}
+	fmt.Fprintln(w, color.Bold.Sprint("ALPN: ")+color.FgLightYellow.Sprint(alpn))
+
+	fmt.Fprintln(w, color.Bold.Sprint("Certificate chain:"))
+	now := time.Now()
+	for i, c := range state.PeerCertificates {
+		fmt.Fprintf(w, "%2d %s%s\n", i, color.Bold.Sprint("Subject: "), c.Subject)
+		fmt.Fprintf(w, "   %s%s\n", color.Bold.Sprint("Issuer: "), c.Issuer)
+
+		if sans := certificateSANs(c); len(sans) > 0 {
+			fmt.Fprintf(w, "   %s%s\n", color.Bold.Sprint("SANs: "), strings.Join(sans, ", "))
+		}
+
+		fmt.Fprintf(w, "   %s%s\n", color.Bold.Sprint("Not before: "), c.NotBefore.UTC().Format(time.RFC3339))
+
+		expiry := color.LightGreen
+		if c.NotAfter.Before(now) {
+			expiry = color.FgRed
+		}
+		fmt.Fprintf(w, "   %s%s\n", color.Bold.Sprint("Not after: "), expiry.Sprint(c.NotAfter.UTC().Format(time.RFC3339)))
+	}
+}
+
+func certificateSANs(c *x509.Certificate) []string {
+	sans := make([]string, 0, len(c.DNSNames)+len(c.IPAddresses)+len(c.URIs)+len(c.EmailAddresses))
+	sans = append(sans, c.DNSNames...)
+	for _, ip := range c.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range c.URIs {
+		sans = append(sans, u.String())
+	}
+	return append(sans, c.EmailAddresses...)
+}
+
+// compressedSize formats the size of compressed message, empty for uncompressed messages
+func compressedSize(m rpc.MessageStat) string {
+	if m.CompressedSize == m.Size {
+		return ""
+	}
+	return fmt.Sprintf(" (%d bytes compressed)", m.CompressedSize)
+}
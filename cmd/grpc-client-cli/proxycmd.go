@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v3"
+	"github.com/vadimi/grpc-client-cli/internal/proxy"
+)
+
+func proxyCmd(ctx context.Context, cmd *cli.Command) error {
+	opts := &startOpts{}
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if opts.Target == "" {
+		return cli.Exit(errors.New("please provide service host:port"), 1)
+	}
+
+	lis, err := net.Listen("tcp", cmd.String("listen"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := runProxy(ctx, opts, cmd.String("record"), lis); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// runProxy forwards calls from the listener to the target and appends them to the record file until ctx is done
+func runProxy(ctx context.Context, opts *startOpts, recordFile string, lis net.Listener) error {
+	return withApp(opts, func(a *app) error {
+		conn, err := a.connFact.GetConn(opts.Target)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(recordFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		p := proxy.NewProxy(conn, proxy.NewRecorder(f), proxy.WithLog(a.w))
+
+		go func() {
+			<-ctx.Done()
+			p.Stop()
+		}()
+
+		fmt.Fprintf(a.w, "proxy is listening on %s, forwarding to %s\n", lis.Addr(), opts.Target)
+		return p.Serve(lis)
+	})
+}
+
+func replayCmd(ctx context.Context, cmd *cli.Command) error {
+	opts := &startOpts{}
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if opts.Target == "" {
+		return cli.Exit(errors.New("please provide service host:port"), 1)
+	}
+
+	if err := runReplay(ctx, opts, cmd.String("record")); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// errReplayDiff is returned if any replayed call differs from the recorded one
+var errReplayDiff = errors.New("replayed calls differ from the recorded ones")
+
+// runReplay sends recorded requests to the target and prints the differences
+func runReplay(ctx context.Context, opts *startOpts, recordFile string) error {
+	records, err := proxy.ReadRecords(recordFile)
+	if err != nil {
+		return err
+	}
+
+	return withApp(opts, func(a *app) error {
+		conn, err := a.connFact.GetConn(opts.Target)
+		if err != nil {
+			return err
+		}
+
+		diffs := 0
+		for _, rec := range records {
+			callCtx, cancel := context.WithTimeout(ctx, opts.Deadline)
+			res, err := proxy.Replay(callCtx, conn, rec)
+			cancel()
+			if err != nil {
+				return err
+			}
+
+			if len(res.Diffs) == 0 {
+				fmt.Fprintf(a.w, "OK   %s\n", rec.Method)
+				continue
+			}
+
+			diffs++
+			fmt.Fprintf(a.w, "DIFF %s\n", rec.Method)
+			for _, d := range res.Diffs {
+				fmt.Fprintf(a.w, "  %s\n", d)
+			}
+		}
+
+		fmt.Fprintf(a.w, "replayed %d calls, %d with differences\n", len(records), diffs)
+		if diffs > 0 {
+			return errReplayDiff
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spyzhov/ajson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimi/grpc-client-cli/internal/proxy"
+	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
+)
+
+func TestProxyReplay(t *testing.T) {
+	recordFile := filepath.Join(t.TempDir(), "calls.jsonl")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runProxy(ctx, &startOpts{
+			Target:   app_testing.TestServerAddr(),
+			Deadline: 15 * time.Second,
+			w:        &bytes.Buffer{},
+		}, recordFile, lis)
+	}()
+
+	buf := &bytes.Buffer{}
+	// reflection calls go through the proxy as well
+	app, err := newApp(&startOpts{
+		Target:        lis.Addr().String(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		w:             buf,
+	})
+	require.NoError(t, err)
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+	require.True(t, ok)
+
+	err = app.callClientStream(context.Background(), m, [][]byte{[]byte(`{"user": {"id": 1, "name": "proxied"}}`)})
+	require.NoError(t, err)
+	app.Close()
+
+	root, err := ajson.Unmarshal(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "proxied", jsonString(root, "$.user.name"))
+
+	cancel()
+	require.NoError(t, <-done)
+
+	records, err := proxy.ReadRecords(recordFile)
+	require.NoError(t, err)
+
+	var unary *proxy.Record
+	for _, rec := range records {
+		if rec.Method == "/grpc_client_cli.testing.TestService/UnaryCall" {
+			unary = rec
+		}
+	}
+	require.NotNil(t, unary, "unary call is not recorded")
+	require.Len(t, unary.Requests, 1)
+	assert.JSONEq(t, `{"user": {"id": 1, "name": "proxied"}}`, string(unary.Requests[0]))
+
+	out := &bytes.Buffer{}
+	err = runReplay(context.Background(), &startOpts{
+		Target:   app_testing.TestServerAddr(),
+		Deadline: 15 * time.Second,
+		w:        out,
+	}, recordFile)
+	require.NoError(t, err, out.String())
+	assert.Contains(t, out.String(), "OK   /grpc_client_cli.testing.TestService/UnaryCall")
+	assert.Contains(t, out.String(), ", 0 with differences")
+}
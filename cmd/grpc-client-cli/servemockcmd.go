@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v3"
+	"github.com/vadimi/grpc-client-cli/internal/mock"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func serveMockCmd(ctx context.Context, cmd *cli.Command) error {
+	opts := &startOpts{}
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if opts.Target == "" && len(opts.Protos) == 0 && len(opts.Protosets) == 0 {
+		return cli.Exit(errors.New("please provide service host:port to use reflection, proto or protoset files"), 1)
+	}
+
+	lis, err := net.Listen("tcp", cmd.String("listen"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := serveMock(ctx, opts, cmd.String("stubs"), lis); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// serveMock serves services described by opts on the listener until ctx is done
+func serveMock(ctx context.Context, opts *startOpts, stubsFile string, lis net.Listener) error {
+	return withApp(opts, func(a *app) error {
+		var stubs []*mock.Stub
+		if stubsFile != "" {
+			var err error
+			stubs, err = mock.LoadStubs(stubsFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		files := []protoreflect.FileDescriptor{}
+		seen := map[string]bool{}
+		for _, s := range a.servicesList {
+			if !seen[s.File.Path()] {
+				seen[s.File.Path()] = true
+				files = append(files, s.File)
+			}
+		}
+
+		srv, err := mock.NewServer(files, stubs, mock.WithLog(a.w))
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			<-ctx.Done()
+			srv.Stop()
+		}()
+
+		fmt.Fprintf(a.w, "mock server is listening on %s\n", lis.Addr())
+		return srv.Serve(lis)
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spyzhov/ajson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServeMock(t *testing.T) {
+	stubs := filepath.Join(t.TempDir(), "stubs.json")
+	err := os.WriteFile(stubs, []byte(`[
+		{"method": "grpc_client_cli.testing.TestService/UnaryCall", "match": {"user": {"id": 1}}, "response": {"user": {"id": 1, "name": "mocked"}}},
+		{"method": "grpc_client_cli.testing.TestService/UnaryCall", "match": {"user": {"id": 2}}, "status": {"code": "NOT_FOUND", "message": "no user"}}
+	]`), 0o644)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveMock(ctx, &startOpts{
+			Target:   app_testing.TestServerAddr(),
+			Deadline: 15 * time.Second,
+			w:        &bytes.Buffer{},
+		}, stubs, lis)
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	buf := &bytes.Buffer{}
+	// services are resolved using reflection of the mock server
+	app, err := newApp(&startOpts{
+		Target:        lis.Addr().String(),
+		Deadline:      15 * time.Second,
+		IsInteractive: false,
+		w:             buf,
+	})
+	require.NoError(t, err)
+	defer app.Close()
+
+	m, ok := findMethod(t, app, "grpc_client_cli.testing.TestService", "UnaryCall")
+	require.True(t, ok)
+
+	cases := []struct {
+		req      string
+		expected string
+	}{
+		{req: `{"user": {"id": 1}}`, expected: "mocked"},
+		{req: `{"user": {"id": 3}}`, expected: "name"},
+	}
+
+	for _, c := range cases {
+		buf.Reset()
+		err = app.callClientStream(context.Background(), m, [][]byte{[]byte(c.req)})
+		require.NoError(t, err)
+
+		root, err := ajson.Unmarshal(buf.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, c.expected, jsonString(root, "$.user.name"))
+	}
+
+	err = app.callClientStream(context.Background(), m, [][]byte{[]byte(`{"user": {"id": 2}}`)})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+	"github.com/vadimi/grpc-client-cli/internal/caller"
+)
+
+func templateCmd(ctx context.Context, cmd *cli.Command) error {
+	opts := &startOpts{}
+	if err := parseStartOpts(cmd, opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if opts.Target == "" && len(opts.Protos) == 0 && len(opts.Protosets) == 0 {
+		return cli.Exit(errors.New("please provide service host:port to use reflection, proto or protoset files"), 1)
+	}
+
+	if opts.Service == "" || opts.Method == "" {
+		return cli.Exit(errors.New("please provide --service and --method to generate the request for"), 1)
+	}
+
+	if err := runTemplate(opts); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// runTemplate prints the sample request of the method in the input format
+func runTemplate(opts *startOpts) error {
+	return withApp(opts, func(a *app) error {
+		name, err := a.selectService(opts.Service)
+		if err != nil {
+			return err
+		}
+
+		method, err := a.selectMethod(a.getService(name), opts.Method)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(a.w, caller.MessageTemplate(method.Input(), opts.InFormat))
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimi/grpc-client-cli/internal/caller"
+	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
+)
+
+func TestTemplate(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   caller.MsgFormat
+		expected string
+	}{
+		{
+			name:   "JSON",
+			format: caller.JSON,
+			expected: `{
+  "response_status": {
+    "code": 0,
+    "message": ""
+  },
+  "user": {
+    "id": 0,
+    "name": ""
+  }
+}
+`,
+		},
+		{
+			name:   "Text",
+			format: caller.Text,
+			expected: `response_status: {
+  code: 0
+  message: ""
+}
+user: {
+  id: 0
+  name: ""
+}
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := runTemplate(&startOpts{
+				Target:   app_testing.TestServerAddr(),
+				Service:  "TestService",
+				Method:   "UnaryCall",
+				InFormat: c.format,
+				Deadline: 15 * time.Second,
+				w:        buf,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+}
+
+func TestTemplateMethodNotFound(t *testing.T) {
+	err := runTemplate(&startOpts{
+		Target:   app_testing.TestServerAddr(),
+		Service:  "TestService",
+		Method:   "Missing",
+		Deadline: 15 * time.Second,
+		w:        &bytes.Buffer{},
+	})
+	assert.EqualError(t, err, "method name not found or invalid")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/urfave/cli/v3"
+	"github.com/vadimi/grpc-client-cli/internal/cliext"
+	"github.com/vadimi/grpc-client-cli/internal/rpc"
+)
+
+func tlsInfoCmd(ctx context.Context, cmd *cli.Command) error {
+	return checkTLS(ctx, cmd, os.Stdout)
+}
+
+func checkTLS(ctx context.Context, cmd *cli.Command, out io.Writer) error {
+	target := cmd.String("address")
+	if target == "" {
+		if cmd.Args().Len() > 0 {
+			target = cmd.Args().First()
+		}
+	}
+
+	if target == "" {
+		err := errors.New("please provide service host:port")
+		fmt.Printf("Error: %s\n", err)
+		return err
+	}
+
+	tlsOpts, err := parseTLSOptions(cmd)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	deadline, err := cliext.ParseDuration(cmd.String("deadline"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var window time.Duration
+	if v := cmd.String("expiry-window"); v != "" {
+		window, err = cliext.ParseDuration(v)
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+
+	cf := rpc.NewGrpcConnFactory(
+		rpc.WithConnCred(cmd.Bool("insecure"), cmd.String("cacert"), cmd.String("cert"), cmd.String("certkey")),
+		rpc.WithTLSOptions(tlsOpts),
+		rpc.WithAuthority(cmd.String("authority")),
+	)
+
+	hctx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+	info, err := cf.TLSHandshake(hctx, target)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	fmt.Fprintln(out, color.Bold.Sprint("Server name: ")+color.FgLightYellow.Sprint(info.ServerName))
+	printTLSState(out, &info.State)
+
+	verification := color.LightGreen.Sprint("OK")
+	if info.VerifyErr != nil {
+		verification = color.FgRed.Sprint("failed, " + info.VerifyErr.Error())
+	} else if cmd.Bool("insecure") {
+		verification = color.FgLightYellow.Sprint("skipped")
+	}
+	fmt.Fprintln(out, color.Bold.Sprint("Verification: ")+verification)
+
+	if info.VerifyErr != nil {
+		return cli.Exit("", 1)
+	}
+
+	if window > 0 {
+		deadline := time.Now().Add(window)
+		for _, c := range info.State.PeerCertificates {
+			if c.NotAfter.Before(deadline) {
+				return cli.Exit(fmt.Sprintf("certificate %s expires within %s", c.Subject, window), 1)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+	app_testing "github.com/vadimi/grpc-client-cli/internal/testing"
+)
+
+func newTLSInfoCmd(t *testing.T, args ...string) *cli.Command {
+	cmd := &cli.Command{
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "deadline", Value: "15s"},
+			&cli.StringFlag{Name: "address"},
+			&cli.BoolFlag{Name: "insecure"},
+			&cli.StringFlag{Name: "cacert"},
+			&cli.StringFlag{Name: "servername"},
+			&cli.StringFlag{Name: "expiry-window"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return nil
+		},
+	}
+	require.NoError(t, cmd.Run(context.Background(), append([]string{"test", "--address", app_testing.TestServerTLSAddr()}, args...)))
+	return cmd
+}
+
+func TestTLSInfo(t *testing.T) {
+	cmd := newTLSInfoCmd(t, "--cacert", "../../testdata/certs/test_ca.crt", "--expiry-window", "24h")
+
+	buf := &bytes.Buffer{}
+	err := checkTLS(context.Background(), cmd, buf)
+	require.NoError(t, err)
+
+	res := buf.String()
+	expected := []string{
+		"Version:", "TLS 1.3", "Cipher suite:", "ALPN:", "h2", "Verification:", "OK",
+		"Subject:", "CN=test_server", "SANs:", "localhost, 127.0.0.1", "Not after:",
+	}
+
+	for _, e := range expected {
+		assert.Contains(t, res, e)
+	}
+}
+
+func TestTLSInfoError(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		output string
+	}{
+		{name: "UntrustedCA", args: []string{"--cacert", "../../testdata/certs/other_ca.crt"}, output: "failed"},
+		{name: "ServerName", args: []string{"--cacert", "../../testdata/certs/test_ca.crt", "--servername", "other.example.com"}, output: "failed"},
+		{name: "Expiry", args: []string{"--insecure", "--expiry-window", "876000h"}, output: "skipped"},
+	}
+
+	expectedExitCode := 1
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cmd := newTLSInfoCmd(t, c.args...)
+
+			buf := &bytes.Buffer{}
+			err := checkTLS(context.Background(), cmd, buf)
+			require.Error(t, err)
+
+			var ec cli.ExitCoder
+			require.True(t, errors.As(err, &ec))
+			assert.Equal(t, expectedExitCode, ec.ExitCode())
+			assert.Contains(t, buf.String(), c.output)
+		})
+	}
+}
@@ -7,11 +7,13 @@ import (
 // FieldWalker walks fields message fields tree calling func for every field
 type FieldWalker struct {
 	processed map[protoreflect.Name]struct{}
+	path      map[protoreflect.FullName]struct{}
 }
 
 func NewFieldWalker() *FieldWalker {
 	return &FieldWalker{
 		processed: map[protoreflect.Name]struct{}{},
+		path:      map[protoreflect.FullName]struct{}{},
 	}
 }
 
@@ -31,3 +33,18 @@ func (fw *FieldWalker) Walk(md protoreflect.MessageDescriptor, walkFn func(proto
 		walkFn(f)
 	}
 }
+
+// Enter adds the message to the current path of the walker,
+// it returns false if the message is on the path already which means the type is recursive
+func (fw *FieldWalker) Enter(md protoreflect.MessageDescriptor) bool {
+	if _, ok := fw.path[md.FullName()]; ok {
+		return false
+	}
+	fw.path[md.FullName()] = struct{}{}
+	return true
+}
+
+// Leave removes the message from the current path of the walker
+func (fw *FieldWalker) Leave(md protoreflect.MessageDescriptor) {
+	delete(fw.path, md.FullName())
+}
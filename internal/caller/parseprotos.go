@@ -7,7 +7,9 @@ import (
 	"io/fs"
 	"path"
 	"path/filepath"
+	"strings"
 
+	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
 	clifs "github.com/vadimi/grpc-client-cli/internal/fs"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -15,6 +17,15 @@ import (
 
 var errNoProtoFilesFound = errors.New("no proto files found")
 
+// lookupGoogleAPI resolves google/api files not found in import paths from the compiled protos,
+// e.g. google/api/annotations.proto used by HTTP transcoding, other missing imports are still errors
+func lookupGoogleAPI(filename string) (*desc.FileDescriptor, error) {
+	if !strings.HasPrefix(filename, "google/api/") {
+		return nil, fmt.Errorf("%s: %w", filename, fs.ErrNotExist)
+	}
+	return desc.LoadFileDescriptor(filename)
+}
+
 func parseProtoFiles(protoDirs []string, protoImports []string) ([]protoreflect.FileDescriptor, error) {
 	protofiles, err := findProtoFiles(protoDirs)
 	if err != nil {
@@ -42,6 +53,7 @@ func parseProtoFiles(protoDirs []string, protoImports []string) ([]protoreflect.
 		Accessor: func(filename string) (io.ReadCloser, error) {
 			return clifs.NewFileReader(filename)
 		},
+		LookupImport: lookupGoogleAPI,
 	}
 
 	resolvedFiles, err := protoparse.ResolveFilenames(importPaths, protofiles...)
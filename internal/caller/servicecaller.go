@@ -214,14 +214,14 @@ func (sc *ServiceCaller) CallClientStream(ctx context.Context, serviceTarget str
 	}
 }
 
-func (sc *ServiceCaller) getConn(target string) (*grpc.ClientConn, error) {
-	conn, err := sc.connFact.GetConn(target)
+func (sc *ServiceCaller) getConn(target string) (grpc.ClientConnInterface, error) {
+	conn, err := sc.connFact.GetCallConn(target)
 	if err != nil {
 		return nil, err
 	}
 
-	if conn.GetState() != connectivity.Ready {
-		conn.ResetConnectBackoff()
+	if cc, ok := conn.(*grpc.ClientConn); ok && cc.GetState() != connectivity.Ready {
+		cc.ResetConnectBackoff()
 	}
 
 	return conn, err
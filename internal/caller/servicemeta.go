@@ -14,7 +14,7 @@ import (
 type serviceMetaData struct {
 	connFact       *rpc.GrpcConnFactory
 	target         string
-	deadline       int
+	deadline       time.Duration
 	protoImports   []string
 	reflectVersion GrpcReflectVersion
 
@@ -25,7 +25,7 @@ type ServiceMetaDataConfig struct {
 	ConnFact       *rpc.GrpcConnFactory
 	Target         string
 	ProtoImports   []string
-	Deadline       int
+	Deadline       time.Duration
 	ReflectVersion GrpcReflectVersion
 }
 
@@ -46,7 +46,7 @@ func (s *serviceMetaData) GetServiceMetaDataList(ctx context.Context) (ServiceMe
 	if err != nil {
 		return nil, err
 	}
-	callctx, cancel := context.WithTimeout(ctx, time.Duration(s.deadline)*time.Second)
+	callctx, cancel := context.WithTimeout(ctx, s.deadline)
 	defer cancel()
 	rc := s.grpcReflectClient(callctx, conn)
 
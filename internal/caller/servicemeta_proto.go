@@ -31,33 +31,7 @@ func (smp *serviceMetadataProto) GetServiceMetaDataList(ctx context.Context) (Se
 		return nil, fmt.Errorf("error parsing proto files: %w", err)
 	}
 
-	res := []*ServiceMeta{}
-
-	for _, fd := range fileDesc {
-		for i := 0; i < fd.Services().Len(); i++ {
-			svc := fd.Services().Get(i)
-
-			methods := make([]protoreflect.MethodDescriptor, svc.Methods().Len())
-			for j := 0; j < svc.Methods().Len(); j++ {
-				methods[j] = svc.Methods().Get(j)
-			}
-
-			svcData := &ServiceMeta{
-				File:    fd,
-				Name:    string(svc.FullName()),
-				Methods: methods,
-			}
-
-			for _, m := range svcData.Methods {
-				u := newJsonNamesUpdater()
-				u.updateJSONNames(m.Input())
-				u.updateJSONNames(m.Output())
-			}
-			res = append(res, svcData)
-		}
-	}
-
-	return res, nil
+	return servicesFromFiles(fileDesc), nil
 }
 
 func (smp *serviceMetadataProto) GetAdditionalFiles() ([]protoreflect.FileDescriptor, error) {
@@ -2,6 +2,8 @@ package caller
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"slices"
 	"testing"
 )
@@ -128,3 +130,34 @@ func findSvc(services []*ServiceMeta, name string) *ServiceMeta {
 func stringInArray(arr []string, s string) bool {
 	return slices.Contains(arr, s)
 }
+
+func TestMetaDataListCompiledImports(t *testing.T) {
+	tests := []struct {
+		name   string
+		proto  string
+		expErr bool
+	}{
+		// google/api files are resolved from the compiled protos if they are not in import paths
+		{name: "GoogleAPI", proto: `import "google/api/annotations.proto";`},
+		// other files linked into the binary are not used
+		{name: "Reflection", proto: `import "grpc/reflection/v1/reflection.proto";`, expErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "service.proto")
+			content := "syntax = \"proto3\";\npackage test;\n" + tt.proto + "\nmessage Empty {}\nservice Test { rpc Call(Empty) returns (Empty); }\n"
+			if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := NewServiceMetadataProto([]string{file}, nil).GetServiceMetaDataList(context.Background())
+			if tt.expErr && err == nil {
+				t.Error("error is expected for missing import")
+			}
+			if !tt.expErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
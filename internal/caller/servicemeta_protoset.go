@@ -0,0 +1,125 @@
+package caller
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type serviceMetadataProtoset struct {
+	protosets    []string
+	protoImports []string
+
+	serviceMetaBase
+}
+
+// NewServiceMetadataProtoset returns new instance of ServiceMetaData
+// that reads service metadata from binary FileDescriptorSet files.
+// protosets - descriptor set files, dependencies missing in the sets are looked up in the compiled protos
+// protoImports - additional directories to search for proto files for Any type (un)marshal
+func NewServiceMetadataProtoset(protosets, protoImports []string) ServiceMetaData {
+	return &serviceMetadataProtoset{
+		protosets:    protosets,
+		protoImports: protoImports,
+	}
+}
+
+func (sms *serviceMetadataProtoset) GetServiceMetaDataList(ctx context.Context) (ServiceMetaList, error) {
+	fileDesc, err := parseProtosets(sms.protosets)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing protoset files: %w", err)
+	}
+
+	return servicesFromFiles(fileDesc), nil
+}
+
+func (sms *serviceMetadataProtoset) GetAdditionalFiles() ([]protoreflect.FileDescriptor, error) {
+	return sms.serviceMetaBase.GetAdditionalFiles(sms.protoImports)
+}
+
+func parseProtosets(protosets []string) ([]protoreflect.FileDescriptor, error) {
+	protos := map[string]*descriptorpb.FileDescriptorProto{}
+	names := []string{}
+	for _, protoset := range protosets {
+		b, err := os.ReadFile(protoset)
+		if err != nil {
+			return nil, err
+		}
+
+		set := &descriptorpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(b, set); err != nil {
+			return nil, fmt.Errorf("%s: %w", protoset, err)
+		}
+
+		for _, fdp := range set.GetFile() {
+			if _, ok := protos[fdp.GetName()]; ok {
+				continue
+			}
+			protos[fdp.GetName()] = fdp
+			names = append(names, fdp.GetName())
+		}
+	}
+
+	files := &protoregistry.Files{}
+	var build func(name string) (protoreflect.FileDescriptor, error)
+	build = func(name string) (protoreflect.FileDescriptor, error) {
+		if fd, err := files.FindFileByPath(name); err == nil {
+			return fd, nil
+		}
+
+		fdp, ok := protos[name]
+		if !ok {
+			return protoregistry.GlobalFiles.FindFileByPath(name)
+		}
+
+		// dependencies are built first, so the resolver can find them
+		for _, dep := range fdp.GetDependency() {
+			if _, err := build(dep); err != nil {
+				return nil, fmt.Errorf("%s: dependency %s: %w", name, dep, err)
+			}
+		}
+
+		fd, err := protodesc.NewFile(fdp, protosetResolver{files})
+		if err != nil {
+			return nil, err
+		}
+
+		return fd, files.RegisterFile(fd)
+	}
+
+	result := make([]protoreflect.FileDescriptor, 0, len(names))
+	for _, name := range names {
+		fd, err := build(name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fd)
+	}
+
+	return result, nil
+}
+
+// protosetResolver looks up descriptors in the files from protosets first and then in the compiled protos
+type protosetResolver struct {
+	files *protoregistry.Files
+}
+
+func (r protosetResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r protosetResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
@@ -0,0 +1,66 @@
+package caller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestMetaDataListProtoset(t *testing.T) {
+	fds, err := parseProtoFiles([]string{"../../testdata/testapi/multiple"}, nil)
+	require.NoError(t, err)
+
+	// dependencies are written before the files using them, so write them in reverse order
+	set := &descriptorpb.FileDescriptorSet{}
+	for i := len(fds) - 1; i >= 0; i-- {
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fds[i]))
+	}
+
+	b, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	protoset := filepath.Join(t.TempDir(), "api.protoset")
+	require.NoError(t, os.WriteFile(protoset, b, 0o644))
+
+	services, err := NewServiceMetadataProtoset([]string{protoset}, nil).GetServiceMetaDataList(context.Background())
+	require.NoError(t, err)
+
+	expected, err := NewServiceMetadataProto([]string{"../../testdata/testapi/multiple"}, nil).GetServiceMetaDataList(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, services, len(expected))
+	for _, svc := range expected {
+		s := findSvc(services, svc.Name)
+		require.NotNil(t, s, svc.Name)
+		assert.Len(t, s.Methods, len(svc.Methods))
+	}
+}
+
+func TestMetaDataListProtosetMissingDependency(t *testing.T) {
+	fds, err := parseProtoFiles([]string{"../../testdata/testapi/withthirdparty"}, []string{"../../testdata/testapi/third_party"})
+	require.NoError(t, err)
+
+	// only the service file without its third party dependency
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range fds {
+		if fd.Services().Len() > 0 {
+			set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+		}
+	}
+
+	b, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	protoset := filepath.Join(t.TempDir(), "api.protoset")
+	require.NoError(t, os.WriteFile(protoset, b, 0o644))
+
+	_, err = NewServiceMetadataProtoset([]string{protoset}, nil).GetServiceMetaDataList(context.Background())
+	assert.ErrorContains(t, err, "shared")
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vadimi/grpc-client-cli/internal/rpc"
@@ -46,7 +47,7 @@ func TestGrpcReflectVersions(t *testing.T) {
 				ConnFact:       rpc.NewGrpcConnFactory(),
 				Target:         lis.Addr().String(),
 				ReflectVersion: tt.version,
-				Deadline:       15,
+				Deadline:       15 * time.Second,
 			})
 
 			_, err = svc.GetServiceMetaDataList(context.Background())
@@ -37,6 +37,37 @@ func (l ServiceMetaList) Files() []protoreflect.FileDescriptor {
 	return res
 }
 
+// servicesFromFiles returns services declared in the files
+func servicesFromFiles(fileDesc []protoreflect.FileDescriptor) ServiceMetaList {
+	res := []*ServiceMeta{}
+
+	for _, fd := range fileDesc {
+		for i := 0; i < fd.Services().Len(); i++ {
+			svc := fd.Services().Get(i)
+
+			methods := make([]protoreflect.MethodDescriptor, svc.Methods().Len())
+			for j := 0; j < svc.Methods().Len(); j++ {
+				methods[j] = svc.Methods().Get(j)
+			}
+
+			svcData := &ServiceMeta{
+				File:    fd,
+				Name:    string(svc.FullName()),
+				Methods: methods,
+			}
+
+			for _, m := range svcData.Methods {
+				u := newJsonNamesUpdater()
+				u.updateJSONNames(m.Input())
+				u.updateJSONNames(m.Output())
+			}
+			res = append(res, svcData)
+		}
+	}
+
+	return res
+}
+
 type serviceMetaBase struct{}
 
 func (s serviceMetaBase) GetAdditionalFiles(protoImports []string) ([]protoreflect.FileDescriptor, error) {
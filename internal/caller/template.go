@@ -0,0 +1,282 @@
+package caller
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// jsonWellKnownTypes contains sample values of well known types that have special JSON mapping
+var jsonWellKnownTypes = map[protoreflect.FullName]string{
+	"google.protobuf.Any":       "{}",
+	"google.protobuf.Duration":  `"0s"`,
+	"google.protobuf.Empty":     "{}",
+	"google.protobuf.FieldMask": `""`,
+	"google.protobuf.ListValue": "[]",
+	"google.protobuf.Struct":    "{}",
+	"google.protobuf.Timestamp": `"1970-01-01T00:00:00Z"`,
+	"google.protobuf.Value":     "null",
+}
+
+// MessageTemplate returns a sample message in the format with every field set:
+// nested messages are expanded unless the type is recursive, every type is expanded once
+// and its other occurrences are empty so shared types don't blow up the template,
+// repeated and map fields have one element and enum fields are set to the name of the first value.
+// Text format lists every field of a oneof with all but the first expanded one commented out,
+// JSON has only the first expanded one as it doesn't support comments
+func MessageTemplate(md protoreflect.MessageDescriptor, format MsgFormat) string {
+	t := &templateBuilder{
+		walker:   NewFieldWalker(),
+		expanded: map[protoreflect.FullName]bool{},
+	}
+	if format == Text {
+		t.enter(md)
+		return strings.Join(t.textFields(md, ""), "\n")
+	}
+
+	msg, _ := t.jsonMessage(md, "")
+	return msg
+}
+
+type templateBuilder struct {
+	walker *FieldWalker
+	// expanded contains the types with the fields already in the template
+	expanded map[protoreflect.FullName]bool
+}
+
+// enter returns false if the message type is recursive and expand is false if the type is expanded already
+func (t *templateBuilder) enter(md protoreflect.MessageDescriptor) (ok, expand bool) {
+	if !t.walker.Enter(md) {
+		return false, false
+	}
+
+	if t.expanded[md.FullName()] {
+		return true, false
+	}
+	t.expanded[md.FullName()] = true
+	return true, true
+}
+
+func (t *templateBuilder) jsonMessage(md protoreflect.MessageDescriptor, indent string) (string, bool) {
+	if v, ok := jsonWellKnownTypes[md.FullName()]; ok {
+		return v, true
+	}
+
+	if isWrapper(md) {
+		return t.jsonValue(md.Fields().ByName("value"), indent)
+	}
+
+	ok, expand := t.enter(md)
+	if !ok {
+		return "", false
+	}
+	defer t.walker.Leave(md)
+
+	if !expand {
+		return "{}", true
+	}
+
+	fields := []string{}
+	active := map[protoreflect.Name]bool{}
+	for i := range md.Fields().Len() {
+		fd := md.Fields().Get(i)
+		oneof := realOneof(fd)
+		if oneof != nil && active[oneof.Name()] {
+			continue
+		}
+
+		v, ok := t.jsonField(fd, indent+"  ")
+		if !ok {
+			continue
+		}
+
+		if oneof != nil {
+			active[oneof.Name()] = true
+		}
+		fields = append(fields, indent+"  "+strconv.Quote(string(fd.Name()))+": "+v)
+	}
+
+	if len(fields) == 0 {
+		return "{}", true
+	}
+
+	return "{\n" + strings.Join(fields, ",\n") + "\n" + indent + "}", true
+}
+
+func (t *templateBuilder) jsonField(fd protoreflect.FieldDescriptor, indent string) (string, bool) {
+	switch {
+	case fd.IsMap():
+		v, ok := t.jsonValue(fd.MapValue(), indent+"  ")
+		if !ok {
+			return "", false
+		}
+		return "{\n" + indent + "  " + strconv.Quote(sampleMapKey(fd.MapKey())) + ": " + v + "\n" + indent + "}", true
+	case fd.IsList():
+		v, ok := t.jsonValue(fd, indent+"  ")
+		if !ok {
+			return "", false
+		}
+		return "[\n" + indent + "  " + v + "\n" + indent + "]", true
+	default:
+		return t.jsonValue(fd, indent)
+	}
+}
+
+func (t *templateBuilder) jsonValue(fd protoreflect.FieldDescriptor, indent string) (string, bool) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return t.jsonMessage(fd.Message(), indent)
+	case protoreflect.EnumKind:
+		if fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return "null", true
+		}
+		return strconv.Quote(firstEnumValue(fd.Enum())), true
+	default:
+		return sampleScalar(fd), true
+	}
+}
+
+func (t *templateBuilder) textFields(md protoreflect.MessageDescriptor, indent string) []string {
+	lines := []string{}
+	active := map[protoreflect.Name]bool{}
+	for i := range md.Fields().Len() {
+		fd := md.Fields().Get(i)
+		oneof := realOneof(fd)
+		alternative := oneof != nil && active[oneof.Name()]
+
+		// commented out alternatives don't count as expansions of their types
+		expanded := t.expanded
+		if alternative {
+			t.expanded = maps.Clone(expanded)
+		}
+
+		field, ok := t.textField(fd, indent)
+		t.expanded = expanded
+		if !ok {
+			continue
+		}
+
+		if alternative {
+			for j, l := range field {
+				field[j] = indent + "# " + strings.TrimPrefix(l, indent)
+			}
+		} else if oneof != nil {
+			active[oneof.Name()] = true
+		}
+		lines = append(lines, field...)
+	}
+	return lines
+}
+
+func (t *templateBuilder) textField(fd protoreflect.FieldDescriptor, indent string) ([]string, bool) {
+	name := string(fd.Name())
+	if fd.Kind() == protoreflect.GroupKind {
+		name = string(fd.Message().Name())
+	}
+
+	switch {
+	case fd.IsMap():
+		v, ok := t.textValue(fd.MapValue(), indent+"  ")
+		if !ok {
+			return nil, false
+		}
+
+		key := sampleScalar(fd.MapKey())
+		if fd.MapKey().Kind() == protoreflect.StringKind {
+			key = strconv.Quote(sampleMapKey(fd.MapKey()))
+		}
+
+		v[0] = indent + "  value: " + v[0]
+		lines := []string{indent + name + " {", indent + "  key: " + key}
+		lines = append(lines, v...)
+		return append(lines, indent+"}"), true
+	case fd.IsList():
+		v, ok := t.textValue(fd, indent)
+		if !ok {
+			return nil, false
+		}
+
+		v[0] = indent + name + ": [" + v[0]
+		v[len(v)-1] += "]"
+		return v, true
+	default:
+		v, ok := t.textValue(fd, indent)
+		if !ok {
+			return nil, false
+		}
+
+		v[0] = indent + name + ": " + v[0]
+		return v, true
+	}
+}
+
+// textValue returns lines of the field value, the first line is not indented
+// so it can be appended to the field name
+func (t *templateBuilder) textValue(fd protoreflect.FieldDescriptor, indent string) ([]string, bool) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		md := fd.Message()
+		ok, expand := t.enter(md)
+		if !ok {
+			return nil, false
+		}
+		defer t.walker.Leave(md)
+
+		if !expand {
+			return []string{"{}"}, true
+		}
+
+		fields := t.textFields(md, indent+"  ")
+		if len(fields) == 0 {
+			return []string{"{}"}, true
+		}
+
+		lines := append([]string{"{"}, fields...)
+		return append(lines, indent+"}"), true
+	case protoreflect.EnumKind:
+		return []string{firstEnumValue(fd.Enum())}, true
+	default:
+		return []string{sampleScalar(fd)}, true
+	}
+}
+
+// realOneof returns the oneof of the field, nil for proto3 optional fields
+func realOneof(fd protoreflect.FieldDescriptor) protoreflect.OneofDescriptor {
+	if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		return oneof
+	}
+	return nil
+}
+
+func isWrapper(md protoreflect.MessageDescriptor) bool {
+	return md.ParentFile() != nil &&
+		md.ParentFile().Path() == "google/protobuf/wrappers.proto" &&
+		md.Fields().ByName("value") != nil
+}
+
+func firstEnumValue(ed protoreflect.EnumDescriptor) string {
+	if ed.Values().Len() == 0 {
+		return "0"
+	}
+	return string(ed.Values().Get(0).Name())
+}
+
+func sampleMapKey(fd protoreflect.FieldDescriptor) string {
+	if fd.Kind() == protoreflect.StringKind {
+		return "key"
+	}
+	return sampleScalar(fd)
+}
+
+func sampleScalar(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "false"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return `""`
+	default:
+		return "0"
+	}
+}
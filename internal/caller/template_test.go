@@ -0,0 +1,153 @@
+package caller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const templateProto = `
+syntax = "proto3";
+package test;
+
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+message Node {
+  string name = 1;
+  Status status = 2;
+  repeated int64 ids = 3;
+  map<string, Node> children = 4;
+  Node parent = 5;
+  oneof contact {
+    string email = 6;
+    Address address = 7;
+  }
+  google.protobuf.Timestamp created = 8;
+  google.protobuf.Int32Value limit = 9;
+  repeated Address addresses = 10;
+  optional bool active = 11;
+  map<int32, Address> locations = 12;
+  // the first option is recursive, so the second one is active
+  oneof target {
+    Node next = 13;
+    string label = 14;
+  }
+}
+
+message Address {
+  string city = 1;
+}
+
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+}
+`
+
+func templateMessage(t *testing.T) protoreflect.MessageDescriptor {
+	p := protoparse.Parser{
+		Accessor:              protoparse.FileContentsFromMap(map[string]string{"template.proto": templateProto}),
+		IncludeSourceCodeInfo: true,
+	}
+	fds, err := p.ParseFiles("template.proto")
+	require.NoError(t, err)
+	return fds[0].UnwrapFile().Messages().ByName("Node")
+}
+
+func TestMessageTemplateJSON(t *testing.T) {
+	md := templateMessage(t)
+
+	tmpl := MessageTemplate(md, JSON)
+	expected := `{
+  "name": "",
+  "status": "UNKNOWN",
+  "ids": [
+    0
+  ],
+  "email": "",
+  "created": "1970-01-01T00:00:00Z",
+  "limit": 0,
+  "addresses": [
+    {
+      "city": ""
+    }
+  ],
+  "active": false,
+  "locations": {
+    "0": {}
+  },
+  "label": ""
+}`
+	assert.Equal(t, expected, tmpl)
+	assert.NoError(t, protojson.Unmarshal([]byte(tmpl), dynamicpb.NewMessage(md)))
+}
+
+func TestMessageTemplateText(t *testing.T) {
+	md := templateMessage(t)
+
+	tmpl := MessageTemplate(md, Text)
+	expected := `name: ""
+status: UNKNOWN
+ids: [0]
+email: ""
+# address: {
+#   city: ""
+# }
+created: {
+  seconds: 0
+  nanos: 0
+}
+limit: {
+  value: 0
+}
+addresses: [{
+  city: ""
+}]
+active: false
+locations {
+  key: 0
+  value: {}
+}
+label: ""`
+	assert.Equal(t, expected, tmpl)
+	assert.NoError(t, prototext.Unmarshal([]byte(tmpl), dynamicpb.NewMessage(md)))
+}
+
+func TestMessageTemplateSharedTypes(t *testing.T) {
+	// every level has two fields of the next level type, expanding all of them would produce 2^depth lines
+	const depth = 30
+	proto := &strings.Builder{}
+	proto.WriteString("syntax = \"proto3\";\npackage test;\n")
+	for i := range depth {
+		fmt.Fprintf(proto, "message Level%d {\n  string name = 1;\n", i)
+		if i < depth-1 {
+			fmt.Fprintf(proto, "  Level%d left = 2;\n  Level%d right = 3;\n", i+1, i+1)
+		}
+		proto.WriteString("}\n")
+	}
+
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"shared.proto": proto.String()}),
+	}
+	fds, err := p.ParseFiles("shared.proto")
+	require.NoError(t, err)
+	md := fds[0].UnwrapFile().Messages().ByName("Level0")
+
+	for _, format := range []MsgFormat{JSON, Text} {
+		tmpl := MessageTemplate(md, format)
+		assert.Less(t, strings.Count(tmpl, "\n"), 10*depth)
+		assert.Contains(t, tmpl, "right")
+	}
+
+	assert.NoError(t, protojson.Unmarshal([]byte(MessageTemplate(md, JSON)), dynamicpb.NewMessage(md)))
+	assert.NoError(t, prototext.Unmarshal([]byte(MessageTemplate(md, Text)), dynamicpb.NewMessage(md)))
+}
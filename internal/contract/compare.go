@@ -0,0 +1,367 @@
+// Package contract compares two versions of services and reports changes breaking their clients
+package contract
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type Severity int
+
+const (
+	// Compatible changes don't affect existing clients
+	Compatible Severity = iota
+	// JSONBreaking changes break clients using JSON encoding, binary encoding is still compatible
+	JSONBreaking
+	// WireBreaking changes break clients using binary encoding
+	WireBreaking
+)
+
+func (s Severity) String() string {
+	switch s {
+	case JSONBreaking:
+		return "json-breaking"
+	case WireBreaking:
+		return "wire-breaking"
+	default:
+		return "compatible"
+	}
+}
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change describes the difference of the element, e.g. field pkg.User.name (2)
+type Change struct {
+	Severity Severity
+	Kind     ChangeKind
+	Element  string
+	Detail   string
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%-13s  %-7s  %s", c.Severity, c.Kind, c.Element)
+	if c.Detail != "" {
+		s += ": " + c.Detail
+	}
+	return s
+}
+
+// Compare returns changes of the services and all messages and enums used by their methods,
+// the changes are reported from the point of view of the clients built using old services
+func Compare(oldServices, newServices []protoreflect.ServiceDescriptor) []Change {
+	c := &comparer{
+		messages: map[messageKey]bool{},
+		enums:    map[protoreflect.FullName]bool{},
+		reported: map[changeKey]int{},
+	}
+
+	oldByName := servicesByName(oldServices)
+	newByName := servicesByName(newServices)
+
+	for _, name := range sortedNames(oldByName) {
+		n, ok := newByName[name]
+		if !ok {
+			c.add(WireBreaking, Removed, "service "+string(name), "")
+			continue
+		}
+		c.compareService(oldByName[name], n)
+	}
+
+	for _, name := range sortedNames(newByName) {
+		if _, ok := oldByName[name]; !ok {
+			c.add(Compatible, Added, "service "+string(name), "")
+		}
+	}
+
+	return c.changes
+}
+
+// direction tells whether a message is sent by clients or received by them
+type direction int
+
+const (
+	request direction = iota
+	response
+)
+
+type messageKey struct {
+	old, new  protoreflect.FullName
+	direction direction
+}
+
+type changeKey struct {
+	kind            ChangeKind
+	element, detail string
+}
+
+type comparer struct {
+	changes []Change
+	// messages and enums contain already compared types, messages can be recursive
+	messages map[messageKey]bool
+	enums    map[protoreflect.FullName]bool
+	// reported contains indexes of the changes, messages used by requests and responses are compared twice
+	reported map[changeKey]int
+}
+
+func (c *comparer) add(severity Severity, kind ChangeKind, element, detail string) {
+	key := changeKey{kind: kind, element: element, detail: detail}
+	if i, ok := c.reported[key]; ok {
+		c.changes[i].Severity = max(c.changes[i].Severity, severity)
+		return
+	}
+
+	c.reported[key] = len(c.changes)
+	c.changes = append(c.changes, Change{Severity: severity, Kind: kind, Element: element, Detail: detail})
+}
+
+func (c *comparer) compareService(o, n protoreflect.ServiceDescriptor) {
+	methods := o.Methods()
+	for i := range methods.Len() {
+		om := methods.Get(i)
+		nm := n.Methods().ByName(om.Name())
+		if nm == nil {
+			c.add(WireBreaking, Removed, "method "+methodName(om), "")
+			continue
+		}
+		c.compareMethod(om, nm)
+	}
+
+	methods = n.Methods()
+	for i := range methods.Len() {
+		nm := methods.Get(i)
+		if o.Methods().ByName(nm.Name()) == nil {
+			c.add(Compatible, Added, "method "+methodName(nm), "")
+		}
+	}
+}
+
+func (c *comparer) compareMethod(o, n protoreflect.MethodDescriptor) {
+	element := "method " + methodName(o)
+	if o.IsStreamingClient() != n.IsStreamingClient() || o.IsStreamingServer() != n.IsStreamingServer() {
+		c.add(WireBreaking, Changed, element, fmt.Sprintf("streaming changed from %s to %s", streamingKind(o), streamingKind(n)))
+	}
+
+	// type names are not sent, renamed types are compatible if their fields are
+	if o.Input().FullName() != n.Input().FullName() {
+		c.add(Compatible, Changed, element, fmt.Sprintf("input type changed from %s to %s", o.Input().FullName(), n.Input().FullName()))
+	}
+	c.compareMessage(o.Input(), n.Input(), request)
+
+	if o.Output().FullName() != n.Output().FullName() {
+		c.add(Compatible, Changed, element, fmt.Sprintf("output type changed from %s to %s", o.Output().FullName(), n.Output().FullName()))
+	}
+	c.compareMessage(o.Output(), n.Output(), response)
+}
+
+func (c *comparer) compareMessage(o, n protoreflect.MessageDescriptor, dir direction) {
+	key := messageKey{old: o.FullName(), new: n.FullName(), direction: dir}
+	if c.messages[key] {
+		return
+	}
+	c.messages[key] = true
+
+	fields := o.Fields()
+	for i := range fields.Len() {
+		of := fields.Get(i)
+		nf := n.Fields().ByNumber(of.Number())
+		if nf != nil {
+			c.compareField(of, nf, dir)
+			continue
+		}
+
+		if moved := n.Fields().ByName(of.Name()); moved != nil {
+			c.add(WireBreaking, Changed, fieldName(of), fmt.Sprintf("number changed from %d to %d", of.Number(), moved.Number()))
+			continue
+		}
+
+		// unknown fields are skipped in binary encoding, JSON parsers reject them,
+		// clients don't get removed fields of responses
+		severity := JSONBreaking
+		if dir == response {
+			severity = Compatible
+		}
+		c.add(severity, Removed, fieldName(of), "")
+	}
+
+	fields = n.Fields()
+	for i := range fields.Len() {
+		nf := fields.Get(i)
+		if o.Fields().ByNumber(nf.Number()) != nil {
+			continue
+		}
+
+		// number changes are reported already
+		if moved := o.Fields().ByName(nf.Name()); moved == nil || n.Fields().ByNumber(moved.Number()) != nil {
+			c.add(Compatible, Added, fieldName(nf), "")
+		}
+	}
+}
+
+func (c *comparer) compareField(o, n protoreflect.FieldDescriptor, dir direction) {
+	element := fieldName(o)
+	if o.Name() != n.Name() {
+		c.add(JSONBreaking, Changed, element, fmt.Sprintf("name changed to %s", n.Name()))
+	} else if o.JSONName() != n.JSONName() {
+		c.add(JSONBreaking, Changed, element, fmt.Sprintf("json name changed from %s to %s", o.JSONName(), n.JSONName()))
+	}
+
+	if o.IsList() != n.IsList() || o.IsMap() != n.IsMap() {
+		c.add(WireBreaking, Changed, element, fmt.Sprintf("label changed from %s to %s", fieldLabel(o), fieldLabel(n)))
+		return
+	}
+
+	if oneofName(o) != oneofName(n) {
+		c.add(WireBreaking, Changed, element, fmt.Sprintf("oneof changed from %q to %q", oneofName(o), oneofName(n)))
+	}
+
+	if o.Kind() != n.Kind() {
+		severity := WireBreaking
+		if wireGroup(o.Kind()) != "" && wireGroup(o.Kind()) == wireGroup(n.Kind()) {
+			severity = JSONBreaking
+		}
+		c.add(severity, Changed, element, fmt.Sprintf("type changed from %s to %s", fieldType(o), fieldType(n)))
+		return
+	}
+
+	switch {
+	case o.Message() != nil:
+		if o.Message().FullName() != n.Message().FullName() {
+			c.add(Compatible, Changed, element, fmt.Sprintf("type changed from %s to %s", fieldType(o), fieldType(n)))
+		}
+		c.compareMessage(o.Message(), n.Message(), dir)
+	case o.Enum() != nil:
+		// enums are encoded as numbers, JSON uses value names
+		if o.Enum().FullName() != n.Enum().FullName() {
+			c.add(JSONBreaking, Changed, element, fmt.Sprintf("type changed from %s to %s", fieldType(o), fieldType(n)))
+			return
+		}
+		c.compareEnum(o.Enum(), n.Enum())
+	}
+}
+
+func (c *comparer) compareEnum(o, n protoreflect.EnumDescriptor) {
+	if c.enums[o.FullName()] {
+		return
+	}
+	c.enums[o.FullName()] = true
+
+	values := o.Values()
+	for i := range values.Len() {
+		ov := values.Get(i)
+		nv := n.Values().ByNumber(ov.Number())
+		element := enumValueName(o, ov)
+		if nv == nil {
+			c.add(JSONBreaking, Removed, element, "")
+		} else if nv.Name() != ov.Name() {
+			c.add(JSONBreaking, Changed, element, fmt.Sprintf("name changed to %s", nv.Name()))
+		}
+	}
+
+	values = n.Values()
+	for i := range values.Len() {
+		nv := values.Get(i)
+		if o.Values().ByNumber(nv.Number()) == nil {
+			c.add(Compatible, Added, enumValueName(n, nv), "")
+		}
+	}
+}
+
+// wireGroup returns the name of the group of the kinds encoded the same way,
+// the values of the same group can be decoded with possible truncation
+func wireGroup(k protoreflect.Kind) string {
+	switch k {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.BoolKind, protoreflect.EnumKind:
+		return "varint"
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "zigzag"
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		return "fixed32"
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind:
+		return "fixed64"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "bytes"
+	default:
+		return ""
+	}
+}
+
+func servicesByName(services []protoreflect.ServiceDescriptor) map[protoreflect.FullName]protoreflect.ServiceDescriptor {
+	res := make(map[protoreflect.FullName]protoreflect.ServiceDescriptor, len(services))
+	for _, s := range services {
+		res[s.FullName()] = s
+	}
+	return res
+}
+
+func sortedNames(services map[protoreflect.FullName]protoreflect.ServiceDescriptor) []protoreflect.FullName {
+	names := make([]protoreflect.FullName, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func methodName(m protoreflect.MethodDescriptor) string {
+	return fmt.Sprintf("%s/%s", m.Parent().FullName(), m.Name())
+}
+
+func fieldName(f protoreflect.FieldDescriptor) string {
+	return fmt.Sprintf("field %s (%d)", f.FullName(), f.Number())
+}
+
+// enumValueName returns the value name qualified by the enum, values are siblings of the enum in proto scopes
+func enumValueName(e protoreflect.EnumDescriptor, v protoreflect.EnumValueDescriptor) string {
+	return fmt.Sprintf("enum value %s.%s (%d)", e.FullName(), v.Name(), v.Number())
+}
+
+func fieldType(f protoreflect.FieldDescriptor) string {
+	switch {
+	case f.Message() != nil:
+		return string(f.Message().FullName())
+	case f.Enum() != nil:
+		return string(f.Enum().FullName())
+	default:
+		return f.Kind().String()
+	}
+}
+
+func fieldLabel(f protoreflect.FieldDescriptor) string {
+	switch {
+	case f.IsMap():
+		return "map"
+	case f.IsList():
+		return "repeated"
+	default:
+		return "singular"
+	}
+}
+
+func oneofName(f protoreflect.FieldDescriptor) string {
+	if o := f.ContainingOneof(); o != nil && !o.IsSynthetic() {
+		return string(o.Name())
+	}
+	return ""
+}
+
+func streamingKind(m protoreflect.MethodDescriptor) string {
+	switch {
+	case m.IsStreamingClient() && m.IsStreamingServer():
+		return "bidi streaming"
+	case m.IsStreamingClient():
+		return "client streaming"
+	case m.IsStreamingServer():
+		return "server streaming"
+	default:
+		return "unary"
+	}
+}
@@ -0,0 +1,191 @@
+package contract
+
+import (
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const oldProto = `
+syntax = "proto3";
+package test;
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc ListUsers(ListUsersRequest) returns (stream User);
+  rpc DeleteUser(GetUserRequest) returns (User);
+  rpc CountUsers(CountRequest) returns (Count);
+}
+
+service LegacyService {
+  rpc Ping(GetUserRequest) returns (User);
+}
+
+message GetUserRequest {
+  int32 id = 1;
+  string view = 2;
+}
+
+message ListUsersRequest {
+  int32 page_size = 1;
+}
+
+message User {
+  int32 id = 1;
+  string name = 2;
+  Status status = 3;
+  repeated string tags = 4;
+  User manager = 5;
+  int64 created = 6;
+  oneof contact {
+    string email = 7;
+    string phone = 8;
+  }
+  string nickname = 9;
+  bytes avatar = 10;
+  string bio = 12;
+}
+
+message CountRequest {
+  int32 status = 1;
+  string filter = 2;
+  Page page = 3;
+}
+
+message Count {
+  int64 total = 1;
+  Page page = 2;
+}
+
+message Page {
+  int32 size = 1;
+  int32 number = 2;
+}
+
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+  BLOCKED = 2;
+  DELETED = 3;
+}
+`
+
+const newProto = `
+syntax = "proto3";
+package test;
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc ListUsers(ListUsersRequest) returns (User);
+  rpc CreateUser(User) returns (User);
+  rpc CountUsers(CountUsersRequest) returns (Count);
+}
+
+service AdminService {
+  rpc Ping(GetUserRequest) returns (User);
+}
+
+message GetUserRequest {
+  int64 id = 1;
+}
+
+message ListUsersRequest {
+  int32 page_size = 1;
+}
+
+message User {
+  int32 id = 1;
+  string full_name = 2;
+  Status status = 3;
+  string tags = 4;
+  User manager = 5;
+  string created = 6;
+  string email = 7;
+  oneof contact {
+    string phone = 8;
+  }
+  string nickname = 19;
+  string avatar = 10;
+  string title = 11;
+}
+
+message CountUsersRequest {
+  int32 status = 1;
+  PageInfo page = 3;
+}
+
+message Count {
+  int64 total = 1;
+  PageInfo page = 2;
+}
+
+message PageInfo {
+  int32 size = 1;
+}
+
+enum Status {
+  UNKNOWN = 0;
+  ENABLED = 1;
+  BLOCKED = 2;
+  ARCHIVED = 4;
+}
+`
+
+func parseServices(t *testing.T, source string) []protoreflect.ServiceDescriptor {
+	t.Helper()
+
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"test.proto": source}),
+	}
+	fds, err := p.ParseFiles("test.proto")
+	require.NoError(t, err)
+
+	fd := fds[0].UnwrapFile()
+	res := []protoreflect.ServiceDescriptor{}
+	for i := range fd.Services().Len() {
+		res = append(res, fd.Services().Get(i))
+	}
+	return res
+}
+
+func TestCompare(t *testing.T) {
+	changes := Compare(parseServices(t, oldProto), parseServices(t, newProto))
+
+	actual := []string{}
+	for _, c := range changes {
+		actual = append(actual, c.String())
+	}
+
+	assert.Equal(t, []string{
+		"wire-breaking  removed  service test.LegacyService",
+		"json-breaking  changed  field test.GetUserRequest.id (1): type changed from int32 to int64",
+		"json-breaking  removed  field test.GetUserRequest.view (2)",
+		"json-breaking  changed  field test.User.name (2): name changed to full_name",
+		"json-breaking  changed  enum value test.Status.ACTIVE (1): name changed to ENABLED",
+		"json-breaking  removed  enum value test.Status.DELETED (3)",
+		"compatible     added    enum value test.Status.ARCHIVED (4)",
+		"wire-breaking  changed  field test.User.tags (4): label changed from repeated to singular",
+		"wire-breaking  changed  field test.User.created (6): type changed from int64 to string",
+		"wire-breaking  changed  field test.User.email (7): oneof changed from \"contact\" to \"\"",
+		"wire-breaking  changed  field test.User.nickname (9): number changed from 9 to 19",
+		"json-breaking  changed  field test.User.avatar (10): type changed from bytes to string",
+		"compatible     removed  field test.User.bio (12)",
+		"compatible     added    field test.User.title (11)",
+		"wire-breaking  changed  method test.UserService/ListUsers: streaming changed from server streaming to unary",
+		"wire-breaking  removed  method test.UserService/DeleteUser",
+		"compatible     changed  method test.UserService/CountUsers: input type changed from test.CountRequest to test.CountUsersRequest",
+		"json-breaking  removed  field test.CountRequest.filter (2)",
+		"compatible     changed  field test.CountRequest.page (3): type changed from test.Page to test.PageInfo",
+		"json-breaking  removed  field test.Page.number (2)",
+		"compatible     changed  field test.Count.page (2): type changed from test.Page to test.PageInfo",
+		"compatible     added    method test.UserService/CreateUser",
+		"compatible     added    service test.AdminService",
+	}, actual)
+}
+
+func TestCompareSame(t *testing.T) {
+	assert.Empty(t, Compare(parseServices(t, oldProto), parseServices(t, oldProto)))
+}
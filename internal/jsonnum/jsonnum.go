@@ -0,0 +1,15 @@
+// Package jsonnum decodes JSON keeping numbers as json.Number,
+// so int64 and uint64 values are not rounded by float64 conversion
+package jsonnum
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Unmarshal decodes JSON the same way as json.Unmarshal, but numbers in interface values are json.Number
+func Unmarshal(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,17 @@
+package jsonnum
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+	var v map[string]any
+	require.NoError(t, Unmarshal([]byte(`{"id": 9007199254740993}`), &v))
+	assert.Equal(t, json.Number("9007199254740993"), v["id"])
+
+	assert.Error(t, Unmarshal([]byte(`{`), &v))
+}
@@ -0,0 +1,96 @@
+package mock
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxSampleDepth limits nesting of sample messages for recursive types
+const maxSampleDepth = 3
+
+// SampleMessage returns the message with all fields populated with sample values,
+// only the first field of every oneof is set
+func SampleMessage(md protoreflect.MessageDescriptor) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(md)
+	fillSample(msg, 0)
+	return msg
+}
+
+func fillSample(msg protoreflect.Message, depth int) {
+	fields := msg.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() && oneof.Fields().Get(0) != fd {
+			continue
+		}
+
+		if fd.Message() != nil && !canSample(fd.Message(), depth+1) {
+			continue
+		}
+
+		switch {
+		case fd.IsList():
+			list := msg.Mutable(fd).List()
+			if fd.Message() != nil {
+				item := list.NewElement()
+				fillSample(item.Message(), depth+1)
+				list.Append(item)
+			} else {
+				list.Append(sampleScalar(fd))
+			}
+		case fd.IsMap():
+			m := msg.Mutable(fd).Map()
+			key := sampleScalar(fd.MapKey()).MapKey()
+			if fd.MapValue().Message() != nil {
+				val := m.NewValue()
+				fillSample(val.Message(), depth+1)
+				m.Set(key, val)
+			} else {
+				m.Set(key, sampleScalar(fd.MapValue()))
+			}
+		case fd.Message() != nil:
+			fillSample(msg.Mutable(fd).Message(), depth+1)
+		default:
+			msg.Set(fd, sampleScalar(fd))
+		}
+	}
+}
+
+// canSample reports if the message field can be populated,
+// Any requires a type that is not known beforehand
+func canSample(md protoreflect.MessageDescriptor, depth int) bool {
+	return depth < maxSampleDepth && md.FullName() != "google.protobuf.Any"
+}
+
+func sampleScalar(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		for i := range values.Len() {
+			if values.Get(i).Number() != 0 {
+				return protoreflect.ValueOfEnum(values.Get(i).Number())
+			}
+		}
+		return protoreflect.ValueOfEnum(0)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(1)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(1)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(1)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(1)
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(1.5)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1.5)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(string(fd.Name()))
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(fd.Name()))
+	default:
+		return fd.Default()
+	}
+}
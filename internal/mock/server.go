@@ -0,0 +1,324 @@
+package mock
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/vadimi/grpc-client-cli/internal/jsonnum"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Server implements all methods of the services dynamically, responses are taken from stubs,
+// methods without matching stubs return sample messages
+type Server struct {
+	grpcServer *grpc.Server
+	files      *protoregistry.Files
+	types      *dynamicpb.Types
+	services   map[string]grpc.ServiceInfo
+	methods    map[string]protoreflect.MethodDescriptor
+	stubs      []*Stub
+	responses  map[*Stub][]proto.Message
+	log        io.Writer
+	// stopTimeout limits the time Stop waits for active calls, e.g. streams kept open by clients
+	stopTimeout time.Duration
+}
+
+// default time Stop waits for active calls to finish
+const defaultStopTimeout = 5 * time.Second
+
+type Option func(*Server)
+
+// WithLog writes a line per handled call to w
+func WithLog(w io.Writer) Option {
+	return func(s *Server) {
+		s.log = w
+	}
+}
+
+// NewServer creates mock server for all services declared in the files,
+// reflection services are served by the mock server itself
+func NewServer(files []protoreflect.FileDescriptor, stubs []*Stub, opts ...Option) (*Server, error) {
+	s := &Server{
+		files:       &protoregistry.Files{},
+		services:    map[string]grpc.ServiceInfo{},
+		methods:     map[string]protoreflect.MethodDescriptor{},
+		stubs:       stubs,
+		responses:   map[*Stub][]proto.Message{},
+		log:         io.Discard,
+		stopTimeout: defaultStopTimeout,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	for _, fd := range files {
+		s.registerFile(fd)
+		s.addServices(fd)
+	}
+	s.types = dynamicpb.NewTypes(s.files)
+
+	if len(s.methods) == 0 {
+		return nil, fmt.Errorf("no services to mock")
+	}
+
+	for i, stub := range stubs {
+		if err := s.initStub(stub); err != nil {
+			return nil, fmt.Errorf("invalid stub #%d: %w", i+1, err)
+		}
+	}
+
+	s.grpcServer = grpc.NewServer(grpc.UnknownServiceHandler(s.handleStream))
+
+	reflectionOpts := reflection.ServerOptions{
+		Services:           s,
+		DescriptorResolver: s.files,
+	}
+	reflectionv1.RegisterServerReflectionServer(s.grpcServer, reflection.NewServerV1(reflectionOpts))
+	reflectionv1alpha.RegisterServerReflectionServer(s.grpcServer, reflection.NewServer(reflectionOpts))
+
+	return s, nil
+}
+
+// Serve accepts connections on the listener until Stop is called
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpcServer.Serve(lis)
+}
+
+// Stop stops the server waiting for active calls to finish,
+// the calls still running after the stop timeout are canceled
+func (s *Server) Stop() {
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(s.stopTimeout):
+		s.grpcServer.Stop()
+		<-done
+	}
+}
+
+// GetServiceInfo returns mocked services together with reflection services, used by reflection to list services
+func (s *Server) GetServiceInfo() map[string]grpc.ServiceInfo {
+	res := s.grpcServer.GetServiceInfo()
+	for name, info := range s.services {
+		res[name] = info
+	}
+	return res
+}
+
+// registerFile adds the file and all its imports to the server registry, so reflection can resolve them
+func (s *Server) registerFile(fd protoreflect.FileDescriptor) {
+	if _, err := s.files.FindFileByPath(fd.Path()); err == nil {
+		return
+	}
+
+	imports := fd.Imports()
+	for i := range imports.Len() {
+		s.registerFile(imports.Get(i).FileDescriptor)
+	}
+
+	// conflicts are ignored, the first registered file wins
+	_ = s.files.RegisterFile(fd)
+}
+
+func (s *Server) addServices(fd protoreflect.FileDescriptor) {
+	services := fd.Services()
+	for i := range services.Len() {
+		svc := services.Get(i)
+		name := string(svc.FullName())
+		// reflection is implemented by the mock server
+		if strings.HasPrefix(name, "grpc.reflection.") {
+			continue
+		}
+
+		info := grpc.ServiceInfo{Metadata: fd.Path()}
+		methods := svc.Methods()
+		for j := range methods.Len() {
+			md := methods.Get(j)
+			s.methods[fmt.Sprintf("/%s/%s", name, md.Name())] = md
+			info.Methods = append(info.Methods, grpc.MethodInfo{
+				Name:           string(md.Name()),
+				IsClientStream: md.IsStreamingClient(),
+				IsServerStream: md.IsStreamingServer(),
+			})
+		}
+		s.services[name] = info
+	}
+}
+
+// initStub validates the stub against the method and parses its responses
+func (s *Server) initStub(stub *Stub) error {
+	if err := stub.init(); err != nil {
+		return err
+	}
+
+	md, ok := s.methods[stub.Method]
+	if !ok {
+		return fmt.Errorf("method %s not found", stub.Method)
+	}
+
+	responses := stub.responses()
+	if !md.IsStreamingServer() && len(responses) > 1 {
+		return fmt.Errorf("%s: only one response is allowed for the method", stub.Method)
+	}
+
+	for _, r := range responses {
+		msg := dynamicpb.NewMessage(md.Output())
+		if err := (protojson.UnmarshalOptions{Resolver: s.types}).Unmarshal(r, msg); err != nil {
+			return fmt.Errorf("%s: invalid response: %w", stub.Method, err)
+		}
+		s.responses[stub] = append(s.responses[stub], msg)
+	}
+
+	return nil
+}
+
+func (s *Server) handleStream(_ any, stream grpc.ServerStream) error {
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	md, ok := s.methods[fullMethod]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+
+	switch {
+	case md.IsStreamingClient() && md.IsStreamingServer():
+		// every request gets its own response
+		for {
+			req := dynamicpb.NewMessage(md.Input())
+			if err := stream.RecvMsg(req); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if err := s.respond(stream, fullMethod, md, req); err != nil {
+				return err
+			}
+		}
+	case md.IsStreamingClient():
+		// the first request is used for matching
+		var first proto.Message
+		for {
+			req := dynamicpb.NewMessage(md.Input())
+			if err := stream.RecvMsg(req); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			if first == nil {
+				first = req
+			}
+		}
+
+		if first == nil {
+			first = dynamicpb.NewMessage(md.Input())
+		}
+		return s.respond(stream, fullMethod, md, first)
+	default:
+		req := dynamicpb.NewMessage(md.Input())
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		return s.respond(stream, fullMethod, md, req)
+	}
+}
+
+func (s *Server) respond(stream grpc.ServerStream, fullMethod string, md protoreflect.MethodDescriptor, req proto.Message) error {
+	stub, err := s.findStub(fullMethod, req)
+	if err != nil {
+		return err
+	}
+
+	if stub == nil {
+		fmt.Fprintf(s.log, "%s: sample response\n", fullMethod)
+		return stream.SendMsg(SampleMessage(md.Output()))
+	}
+	fmt.Fprintf(s.log, "%s: stub response\n", fullMethod)
+
+	if len(stub.Headers) > 0 {
+		stream.SetHeader(metadata.New(stub.Headers))
+	}
+	if len(stub.Trailers) > 0 {
+		stream.SetTrailer(metadata.New(stub.Trailers))
+	}
+
+	if stub.delay > 0 {
+		select {
+		case <-time.After(stub.delay):
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+
+	failed := stub.Status != nil && stub.Status.Code != codes.OK
+	responses := s.responses[stub]
+	// unary response is either a message or an error
+	if !md.IsStreamingServer() && (failed || len(responses) == 0) {
+		responses = nil
+		if !failed {
+			responses = []proto.Message{dynamicpb.NewMessage(md.Output())}
+		}
+	}
+
+	for _, r := range responses {
+		if err := stream.SendMsg(r); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return status.Error(stub.Status.Code, stub.Status.Message)
+	}
+
+	return nil
+}
+
+// findStub returns the first stub of the method matching the request, nil if there are none
+func (s *Server) findStub(fullMethod string, req proto.Message) (*Stub, error) {
+	var reqJSON any
+	for _, stub := range s.stubs {
+		if stub.Method != fullMethod {
+			continue
+		}
+
+		if stub.match != nil && reqJSON == nil {
+			b, err := protojson.MarshalOptions{
+				Resolver:        s.types,
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			}.Marshal(req)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "error marshaling request: %v", err)
+			}
+			if err := jsonnum.Unmarshal(b, &reqJSON); err != nil {
+				return nil, status.Errorf(codes.Internal, "error marshaling request: %v", err)
+			}
+		}
+
+		if stub.matches(reqJSON) {
+			return stub, nil
+		}
+	}
+
+	return nil, nil
+}
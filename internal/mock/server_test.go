@@ -0,0 +1,239 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimi/grpc-client-cli/internal/testing/grpc_testing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func startServer(t *testing.T, stubs []*Stub) grpc_testing.TestServiceClient {
+	t.Helper()
+
+	s, err := NewServer([]protoreflect.FileDescriptor{grpc_testing.File_test_proto}, stubs)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return grpc_testing.NewTestServiceClient(conn)
+}
+
+func TestServerUnaryStubs(t *testing.T) {
+	client := startServer(t, []*Stub{
+		{
+			Method:   "grpc_client_cli.testing.TestService/UnaryCall",
+			Match:    json.RawMessage(`{"user": {"id": 1}}`),
+			Response: json.RawMessage(`{"user": {"id": 1, "name": "first"}}`),
+			Headers:  map[string]string{"x-stub": "first"},
+		},
+		{
+			Method: "grpc_client_cli.testing.TestService.UnaryCall",
+			Match:  json.RawMessage(`{"user": {"name": "missing"}}`),
+			Status: &StubStatus{Code: codes.NotFound, Message: "user not found"},
+		},
+		{
+			Method:   "grpc_client_cli.testing.TestService/UnaryCall",
+			Response: json.RawMessage(`{"user": {"name": "default"}}`),
+		},
+	})
+
+	var header metadata.MD
+	res, err := client.UnaryCall(context.Background(), &grpc_testing.SimpleRequest{User: &grpc_testing.User{Id: 1}}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "first", res.GetUser().GetName())
+	assert.Equal(t, []string{"first"}, header.Get("x-stub"))
+
+	_, err = client.UnaryCall(context.Background(), &grpc_testing.SimpleRequest{User: &grpc_testing.User{Name: "missing"}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "user not found", status.Convert(err).Message())
+
+	res, err = client.UnaryCall(context.Background(), &grpc_testing.SimpleRequest{User: &grpc_testing.User{Id: 2}})
+	require.NoError(t, err)
+	assert.Equal(t, "default", res.GetUser().GetName())
+}
+
+func TestServerSampleResponse(t *testing.T) {
+	client := startServer(t, nil)
+
+	res, err := client.UnaryCall(context.Background(), &grpc_testing.SimpleRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), res.GetUser().GetId())
+	assert.Equal(t, "name", res.GetUser().GetName())
+
+	stream, err := client.StreamingOutputCall(context.Background(), &grpc_testing.StreamingOutputCallRequest{})
+	require.NoError(t, err)
+	res2, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "name", res2.GetUser().GetName())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServerStreamingStubs(t *testing.T) {
+	client := startServer(t, []*Stub{
+		{
+			Method:    "/grpc_client_cli.testing.TestService/StreamingOutputCall",
+			Responses: []json.RawMessage{[]byte(`{"user": {"id": 1}}`), []byte(`{"user": {"id": 2}}`)},
+			Status:    &StubStatus{Code: codes.Aborted, Message: "stream aborted"},
+			Trailers:  map[string]string{"x-trailer": "done"},
+		},
+		{
+			Method:   "grpc_client_cli.testing.TestService/StreamingInputCall",
+			Match:    json.RawMessage(`{"user": {"name": "first"}}`),
+			Response: json.RawMessage(`{"aggregated_payload_size": 42}`),
+		},
+		{
+			Method:    "grpc_client_cli.testing.TestService/FullDuplexCall",
+			Match:     json.RawMessage(`{"user": {"id": 7}}`),
+			Responses: []json.RawMessage{[]byte(`{"user": {"name": "seven"}}`)},
+		},
+	})
+
+	t.Run("server", func(t *testing.T) {
+		stream, err := client.StreamingOutputCall(context.Background(), &grpc_testing.StreamingOutputCallRequest{})
+		require.NoError(t, err)
+
+		ids := []int32{}
+		for {
+			res, err := stream.Recv()
+			if err != nil {
+				assert.Equal(t, codes.Aborted, status.Code(err))
+				break
+			}
+			ids = append(ids, res.GetUser().GetId())
+		}
+		assert.Equal(t, []int32{1, 2}, ids)
+		assert.Equal(t, []string{"done"}, stream.Trailer().Get("x-trailer"))
+	})
+
+	t.Run("client", func(t *testing.T) {
+		stream, err := client.StreamingInputCall(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&grpc_testing.StreamingInputCallRequest{User: &grpc_testing.User{Name: "first"}}))
+		require.NoError(t, stream.Send(&grpc_testing.StreamingInputCallRequest{User: &grpc_testing.User{Name: "second"}}))
+
+		res, err := stream.CloseAndRecv()
+		require.NoError(t, err)
+		assert.Equal(t, int32(42), res.GetAggregatedPayloadSize())
+	})
+
+	t.Run("bidi", func(t *testing.T) {
+		stream, err := client.FullDuplexCall(context.Background())
+		require.NoError(t, err)
+
+		require.NoError(t, stream.Send(&grpc_testing.StreamingOutputCallRequest{User: &grpc_testing.User{Id: 7}}))
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "seven", res.GetUser().GetName())
+
+		require.NoError(t, stream.Send(&grpc_testing.StreamingOutputCallRequest{User: &grpc_testing.User{Id: 8}}))
+		res, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "name", res.GetUser().GetName())
+
+		require.NoError(t, stream.CloseSend())
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err)
+	})
+}
+
+func TestServerInvalidStubs(t *testing.T) {
+	files := []protoreflect.FileDescriptor{grpc_testing.File_test_proto}
+
+	_, err := NewServer(files, []*Stub{{Method: "grpc_client_cli.testing.TestService/Missing"}})
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = NewServer(files, []*Stub{{
+		Method:   "grpc_client_cli.testing.TestService/UnaryCall",
+		Response: json.RawMessage(`{"unknown": 1}`),
+	}})
+	assert.ErrorContains(t, err, "invalid response")
+
+	_, err = NewServer(files, []*Stub{{
+		Method:    "grpc_client_cli.testing.TestService/UnaryCall",
+		Responses: []json.RawMessage{[]byte(`{}`), []byte(`{}`)},
+	}})
+	assert.ErrorContains(t, err, "only one response")
+}
+
+func TestServerReflection(t *testing.T) {
+	s, err := NewServer([]protoreflect.FileDescriptor{grpc_testing.File_test_proto}, nil)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rc := grpcreflect.NewClientAuto(context.Background(), conn)
+	defer rc.Reset()
+
+	services, err := rc.ListServices()
+	require.NoError(t, err)
+	assert.Contains(t, services, "grpc_client_cli.testing.TestService")
+	assert.Contains(t, services, "grpc.reflection.v1.ServerReflection")
+
+	svc, err := rc.ResolveService("grpc_client_cli.testing.TestService")
+	require.NoError(t, err)
+	assert.NotNil(t, svc.FindMethodByName("UnaryCall"))
+}
+
+func TestServerStopOpenStream(t *testing.T) {
+	s, err := NewServer([]protoreflect.FileDescriptor{grpc_testing.File_test_proto}, nil)
+	require.NoError(t, err)
+	s.stopTimeout = 100 * time.Millisecond
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the stream is kept open by the client
+	stream, err := grpc_testing.NewTestServiceClient(conn).FullDuplexCall(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&grpc_testing.StreamingOutputCallRequest{}))
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server is not stopped")
+	}
+
+	_, err = stream.Recv()
+	assert.Error(t, err)
+}
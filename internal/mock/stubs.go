@@ -0,0 +1,180 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vadimi/grpc-client-cli/internal/jsonnum"
+	"google.golang.org/grpc/codes"
+)
+
+// Stub is a canned response of the method, stubs are matched in the order they are defined in the file
+type Stub struct {
+	// Method is full method name, e.g. package.Service/Method
+	Method string `json:"method"`
+	// Match is a partial request message in JSON format using proto field names,
+	// the stub is used if all the specified fields are equal to the request fields,
+	// the stub without Match is used for any request
+	Match json.RawMessage `json:"match,omitempty"`
+	// Response is returned by unary and client streaming methods
+	Response json.RawMessage `json:"response,omitempty"`
+	// Responses are sent by server and bidi streaming methods
+	Responses []json.RawMessage `json:"responses,omitempty"`
+	// Status is returned after the responses are sent, OK by default
+	Status   *StubStatus       `json:"status,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Trailers map[string]string `json:"trailers,omitempty"`
+	// Delay is applied before the first response is sent, e.g. 200ms
+	Delay string `json:"delay,omitempty"`
+
+	delay time.Duration
+	match any
+}
+
+type StubStatus struct {
+	// Code is either a number or a name, e.g. 5 or "NOT_FOUND"
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message"`
+}
+
+// LoadStubs reads JSON array of stubs from the file
+func LoadStubs(file string) ([]*Stub, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var stubs []*Stub
+	if err := json.Unmarshal(b, &stubs); err != nil {
+		return nil, fmt.Errorf("invalid stubs file %s: %w", file, err)
+	}
+
+	for i, stub := range stubs {
+		if err := stub.init(); err != nil {
+			return nil, fmt.Errorf("invalid stub #%d: %w", i+1, err)
+		}
+	}
+
+	return stubs, nil
+}
+
+func (s *Stub) init() error {
+	method, err := normalizeMethod(s.Method)
+	if err != nil {
+		return err
+	}
+	s.Method = method
+
+	if s.Delay != "" {
+		s.delay, err = time.ParseDuration(s.Delay)
+		if err != nil {
+			return fmt.Errorf("%s: invalid delay: %w", s.Method, err)
+		}
+	}
+
+	if len(s.Match) > 0 {
+		if err := jsonnum.Unmarshal(s.Match, &s.match); err != nil {
+			return fmt.Errorf("%s: invalid match: %w", s.Method, err)
+		}
+	}
+
+	return nil
+}
+
+// responses returns all messages of the stub, Response goes first
+func (s *Stub) responses() []json.RawMessage {
+	if len(s.Response) == 0 {
+		return s.Responses
+	}
+	return append([]json.RawMessage{s.Response}, s.Responses...)
+}
+
+// matches checks if the request in JSON format contains all fields of the matcher
+func (s *Stub) matches(req any) bool {
+	return s.match == nil || matchJSON(s.match, req)
+}
+
+// normalizeMethod converts package.Service/Method and package.Service.Method to /package.Service/Method
+func normalizeMethod(method string) (string, error) {
+	method = strings.TrimPrefix(method, "/")
+	if !strings.Contains(method, "/") {
+		if i := strings.LastIndexByte(method, '.'); i > 0 {
+			method = method[:i] + "/" + method[i+1:]
+		}
+	}
+
+	service, name, ok := strings.Cut(method, "/")
+	if !ok || service == "" || name == "" {
+		return "", errors.New("method should be in package.Service/Method format, got: " + method)
+	}
+
+	return "/" + method, nil
+}
+
+func matchJSON(pattern, actual any) bool {
+	switch p := pattern.(type) {
+	case map[string]any:
+		a, ok := actual.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range p {
+			av, ok := a[k]
+			if !ok || !matchJSON(v, av) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		a, ok := actual.([]any)
+		if !ok || len(a) != len(p) {
+			return false
+		}
+		for i := range p {
+			if !matchJSON(p[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return scalarEqual(pattern, actual)
+	}
+}
+
+// scalarEqual compares JSON scalars, 64-bit integers are encoded as strings in proto JSON,
+// so numbers are compared by value
+func scalarEqual(pattern, actual any) bool {
+	ps, pok := scalarString(pattern)
+	as, aok := scalarString(actual)
+	if !pok || !aok {
+		return false
+	}
+
+	if ps == as {
+		return true
+	}
+
+	pf, perr := strconv.ParseFloat(ps, 64)
+	af, aerr := strconv.ParseFloat(as, 64)
+	return perr == nil && aerr == nil && pf == af
+}
+
+func scalarString(v any) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "null", true
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	case bool:
+		return strconv.FormatBool(val), true
+	default:
+		return "", false
+	}
+}
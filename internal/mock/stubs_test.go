@@ -0,0 +1,67 @@
+package mock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimi/grpc-client-cli/internal/jsonnum"
+	"google.golang.org/grpc/codes"
+)
+
+func TestLoadStubs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stubs.json")
+	err := os.WriteFile(file, []byte(`[
+		{"method": "pkg.Service.Method", "delay": "200ms", "status": {"code": "NOT_FOUND", "message": "missing"}},
+		{"method": "/pkg.Service/Method", "match": {"id": 1}, "status": {"code": 3}}
+	]`), 0o644)
+	require.NoError(t, err)
+
+	stubs, err := LoadStubs(file)
+	require.NoError(t, err)
+	require.Len(t, stubs, 2)
+
+	assert.Equal(t, "/pkg.Service/Method", stubs[0].Method)
+	assert.Equal(t, 200*time.Millisecond, stubs[0].delay)
+	assert.Equal(t, codes.NotFound, stubs[0].Status.Code)
+	assert.Equal(t, "/pkg.Service/Method", stubs[1].Method)
+	assert.Equal(t, codes.InvalidArgument, stubs[1].Status.Code)
+}
+
+func TestLoadStubsInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stubs.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[{"method": "Method"}]`), 0o644))
+
+	_, err := LoadStubs(file)
+	assert.ErrorContains(t, err, "stub #1")
+}
+
+func TestMatchJSON(t *testing.T) {
+	var actual any
+	require.NoError(t, jsonnum.Unmarshal([]byte(`{"id": "10", "name": "user", "score": 1.5, "tags": ["a", "b"], "nested": {"ok": true}}`), &actual))
+
+	tests := []struct {
+		pattern string
+		match   bool
+	}{
+		{`{}`, true},
+		{`{"id": 10}`, true},
+		{`{"id": "10", "name": "user"}`, true},
+		{`{"score": 1.50}`, true},
+		{`{"tags": ["a", "b"]}`, true},
+		{`{"nested": {"ok": true}}`, true},
+		{`{"id": 11}`, false},
+		{`{"tags": ["a"]}`, false},
+		{`{"nested": {"ok": false}}`, false},
+		{`{"missing": null}`, false},
+	}
+
+	for _, tt := range tests {
+		var pattern any
+		require.NoError(t, jsonnum.Unmarshal([]byte(tt.pattern), &pattern))
+		assert.Equal(t, tt.match, matchJSON(pattern, actual), tt.pattern)
+	}
+}
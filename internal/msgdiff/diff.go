@@ -0,0 +1,236 @@
+// Package msgdiff compares messages in JSON format field by field
+package msgdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/vadimi/grpc-client-cli/internal/jsonnum"
+)
+
+type ChangeKind int
+
+const (
+	// Modified means the values are different in both messages
+	Modified ChangeKind = iota
+	// Removed means the value is present in the left message only
+	Removed
+	// Added means the value is present in the right message only
+	Added
+)
+
+// Change is a difference of the value at Path, e.g. user.tags[1]
+type Change struct {
+	Kind  ChangeKind
+	Path  string
+	Left  any
+	Right any
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, formatValue(c.Left))
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, formatValue(c.Right))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, formatValue(c.Left), formatValue(c.Right))
+	}
+}
+
+type Options struct {
+	// Ignore contains paths of the fields to skip, indexes of repeated fields are omitted, e.g. user.tags or items.created_at
+	Ignore []string
+	// Unordered compares repeated fields regardless of the order of their elements
+	Unordered bool
+}
+
+// Diff returns differences between JSON documents
+func Diff(left, right []byte, opts *Options) ([]Change, error) {
+	var l, r any
+	if err := jsonnum.Unmarshal(left, &l); err != nil {
+		return nil, fmt.Errorf("invalid left message: %w", err)
+	}
+
+	if err := jsonnum.Unmarshal(right, &r); err != nil {
+		return nil, fmt.Errorf("invalid right message: %w", err)
+	}
+
+	return DiffValues(l, r, opts), nil
+}
+
+// DiffValues returns differences between values decoded from JSON with json.Decoder.UseNumber
+func DiffValues(left, right any, opts *Options) []Change {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	d := &differ{
+		ignore:    map[string]bool{},
+		unordered: opts.Unordered,
+	}
+	for _, p := range opts.Ignore {
+		d.ignore[p] = true
+	}
+
+	d.diff("", "", left, right)
+	return d.changes
+}
+
+type differ struct {
+	ignore    map[string]bool
+	unordered bool
+	changes   []Change
+}
+
+// diff compares values at the path, field is the path without indexes used to match ignored fields
+func (d *differ) diff(path, field string, left, right any) {
+	if field != "" && d.ignore[field] {
+		return
+	}
+
+	switch l := left.(type) {
+	case map[string]any:
+		r, ok := right.(map[string]any)
+		if !ok {
+			d.add(Modified, path, left, right)
+			return
+		}
+		d.diffObjects(path, field, l, r)
+	case []any:
+		r, ok := right.([]any)
+		if !ok {
+			d.add(Modified, path, left, right)
+			return
+		}
+		if d.unordered {
+			d.diffUnordered(path, field, l, r)
+		} else {
+			d.diffOrdered(path, field, l, r)
+		}
+	default:
+		if !scalarEqual(left, right) {
+			d.add(Modified, path, left, right)
+		}
+	}
+}
+
+func (d *differ) diffObjects(path, field string, left, right map[string]any) {
+	keys := make([]string, 0, len(left)+len(right))
+	for k := range left {
+		keys = append(keys, k)
+	}
+	for k := range right {
+		if _, ok := left[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p, f := join(path, k), join(field, k)
+		if d.ignore[f] {
+			continue
+		}
+
+		lv, lok := left[k]
+		rv, rok := right[k]
+		switch {
+		case !rok:
+			d.add(Removed, p, lv, nil)
+		case !lok:
+			d.add(Added, p, nil, rv)
+		default:
+			d.diff(p, f, lv, rv)
+		}
+	}
+}
+
+func (d *differ) diffOrdered(path, field string, left, right []any) {
+	for i := 0; i < len(left) || i < len(right); i++ {
+		p := index(path, i)
+		switch {
+		case i >= len(right):
+			d.add(Removed, p, left[i], nil)
+		case i >= len(left):
+			d.add(Added, p, nil, right[i])
+		default:
+			d.diff(p, field, left[i], right[i])
+		}
+	}
+}
+
+// diffUnordered matches equal elements first, the rest of the elements are reported as removed and added
+func (d *differ) diffUnordered(path, field string, left, right []any) {
+	matched := make([]bool, len(right))
+	for i, lv := range left {
+		found := false
+		for j, rv := range right {
+			if !matched[j] && d.equal(field, lv, rv) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			d.add(Removed, index(path, i), lv, nil)
+		}
+	}
+
+	for j, rv := range right {
+		if !matched[j] {
+			d.add(Added, index(path, j), nil, rv)
+		}
+	}
+}
+
+func (d *differ) equal(field string, left, right any) bool {
+	sub := &differ{ignore: d.ignore, unordered: d.unordered}
+	sub.diff("", field, left, right)
+	return len(sub.changes) == 0
+}
+
+func (d *differ) add(kind ChangeKind, path string, left, right any) {
+	if path == "" {
+		path = "."
+	}
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Left: left, Right: right})
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// scalarEqual compares numbers by value, so 1.0 and 1 are equal
+func scalarEqual(left, right any) bool {
+	ln, lok := left.(json.Number)
+	rn, rok := right.(json.Number)
+	if lok && rok {
+		if ln == rn {
+			return true
+		}
+		lf, lerr := ln.Float64()
+		rf, rerr := rn.Float64()
+		return lerr == nil && rerr == nil && lf == rf
+	}
+
+	return left == right
+}
+
+func formatValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,104 @@
+package msgdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		name     string
+		left     string
+		right    string
+		opts     *Options
+		expected []string
+	}{
+		{
+			name:  "Equal",
+			left:  `{"id": "1", "score": 1.0, "user": {"name": "a"}}`,
+			right: `{"user": {"name": "a"}, "score": 1, "id": "1"}`,
+		},
+		{
+			name:  "Fields",
+			left:  `{"id": "1", "user": {"name": "a", "age": 3}, "old": true}`,
+			right: `{"id": "2", "user": {"name": "b", "age": 3}, "new": null}`,
+			expected: []string{
+				`~ id: "1" -> "2"`,
+				`+ new: null`,
+				`- old: true`,
+				`~ user.name: "a" -> "b"`,
+			},
+		},
+		{
+			name:  "Repeated",
+			left:  `{"tags": ["a", "b", "c"], "items": [{"id": 1}]}`,
+			right: `{"tags": ["b", "a"], "items": [{"id": 2}, {"id": 3}]}`,
+			expected: []string{
+				`~ items[0].id: 1 -> 2`,
+				`+ items[1]: {"id":3}`,
+				`~ tags[0]: "a" -> "b"`,
+				`~ tags[1]: "b" -> "a"`,
+				`- tags[2]: "c"`,
+			},
+		},
+		{
+			name:  "Unordered",
+			left:  `{"tags": ["a", "b", "c"], "items": [{"id": 1, "ts": "x"}, {"id": 2, "ts": "y"}]}`,
+			right: `{"tags": ["b", "a", "d"], "items": [{"id": 2, "ts": "z"}, {"id": 1, "ts": "w"}]}`,
+			opts:  &Options{Unordered: true, Ignore: []string{"items.ts"}},
+			expected: []string{
+				`- tags[2]: "c"`,
+				`+ tags[2]: "d"`,
+			},
+		},
+		{
+			name:  "Ignore",
+			left:  `{"id": "1", "created_at": "2024", "user": {"id": 1, "name": "a"}, "items": [{"id": 1, "name": "x"}]}`,
+			right: `{"id": "2", "created_at": "2025", "user": {"id": 2, "name": "a"}, "items": [{"id": 2, "name": "y"}]}`,
+			opts:  &Options{Ignore: []string{"id", "created_at", "user.id", "items.id"}},
+			expected: []string{
+				`~ items[0].name: "x" -> "y"`,
+			},
+		},
+		{
+			name:  "Streams",
+			left:  `[{"id": 1}, {"id": 2}]`,
+			right: `[{"id": 1}]`,
+			expected: []string{
+				`- [1]: {"id":2}`,
+			},
+		},
+		{
+			name:  "Types",
+			left:  `{"value": {"a": 1}}`,
+			right: `{"value": [1]}`,
+			expected: []string{
+				`~ value: {"a":1} -> [1]`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			changes, err := Diff([]byte(c.left), []byte(c.right), c.opts)
+			require.NoError(t, err)
+
+			actual := []string{}
+			for _, ch := range changes {
+				actual = append(actual, ch.String())
+			}
+
+			if c.expected == nil {
+				c.expected = []string{}
+			}
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestDiffInvalidJSON(t *testing.T) {
+	_, err := Diff([]byte(`{`), []byte(`{}`), nil)
+	assert.ErrorContains(t, err, "invalid left message")
+}
@@ -0,0 +1,294 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Proxy forwards all calls to the target connection and records them,
+// messages are decoded using descriptors registered in protoregistry.GlobalFiles
+type Proxy struct {
+	grpcServer *grpc.Server
+	conn       grpc.ClientConnInterface
+	recorder   *Recorder
+	log        io.Writer
+}
+
+type Option func(*Proxy)
+
+// WithLog writes a line per forwarded call to w
+func WithLog(w io.Writer) Option {
+	return func(p *Proxy) {
+		p.log = w
+	}
+}
+
+func NewProxy(conn grpc.ClientConnInterface, recorder *Recorder, opts ...Option) *Proxy {
+	p := &Proxy{
+		conn:     conn,
+		recorder: recorder,
+		log:      io.Discard,
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	p.grpcServer = grpc.NewServer(
+		grpc.UnknownServiceHandler(p.handleStream),
+		grpc.ForceServerCodec(rawCodec{}),
+	)
+	return p
+}
+
+// Serve accepts connections on the listener until Stop is called
+func (p *Proxy) Serve(lis net.Listener) error {
+	return p.grpcServer.Serve(lis)
+}
+
+// Stop stops the proxy waiting for active calls to finish
+func (p *Proxy) Stop() {
+	p.grpcServer.GracefulStop()
+}
+
+func (p *Proxy) handleStream(_ any, ss grpc.ServerStream) error {
+	fullMethod, _ := grpc.MethodFromServerStream(ss)
+	inMD, _ := metadata.FromIncomingContext(ss.Context())
+
+	c := &call{
+		md: findMethod(fullMethod),
+		rec: &Record{
+			Method:         fullMethod,
+			Time:           time.Now(),
+			RequestHeaders: forwardedMetadata(inMD),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(ss.Context(), c.rec.RequestHeaders))
+	defer cancel()
+
+	desc := &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}
+	cs, err := p.conn.NewStream(ctx, desc, fullMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return p.finish(c, err)
+	}
+
+	go func() {
+		for {
+			var msg []byte
+			if err := ss.RecvMsg(&msg); err == io.EOF {
+				cs.CloseSend()
+				return
+			} else if err != nil {
+				cancel()
+				return
+			}
+
+			c.addRequest(msg)
+			if err := cs.SendMsg(&msg); err != nil {
+				// the error is returned by RecvMsg of the client stream
+				return
+			}
+		}
+	}()
+
+	header, err := cs.Header()
+	if err == nil {
+		header = forwardedMetadata(header)
+		c.setResponseHeaders(header)
+		if err := ss.SendHeader(header); err != nil {
+			return err
+		}
+	}
+
+	for {
+		var msg []byte
+		if err = cs.RecvMsg(&msg); err != nil {
+			break
+		}
+
+		c.addResponse(msg)
+		if err := ss.SendMsg(&msg); err != nil {
+			return err
+		}
+	}
+
+	if err == io.EOF {
+		err = nil
+	}
+
+	trailer := forwardedMetadata(cs.Trailer())
+	c.setTrailers(trailer)
+	ss.SetTrailer(trailer)
+	return p.finish(c, err)
+}
+
+// finish records the call and returns the error to the client
+func (p *Proxy) finish(c *call, err error) error {
+	st := status.Convert(err)
+
+	c.mu.Lock()
+	c.rec.Duration = time.Since(c.rec.Time).String()
+	c.rec.Status = Status{Code: st.Code(), Message: st.Message()}
+	c.setMessages()
+	werr := p.recorder.Write(c.rec)
+	c.mu.Unlock()
+
+	fmt.Fprintf(p.log, "%s %s %s\n", c.rec.Method, st.Code(), c.rec.Duration)
+	if werr != nil {
+		fmt.Fprintf(p.log, "error recording %s: %v\n", c.rec.Method, werr)
+	}
+
+	return err
+}
+
+// call collects the record, requests are added concurrently with responses
+type call struct {
+	mu  sync.Mutex
+	md  protoreflect.MethodDescriptor
+	rec *Record
+	// requests and responses are binary messages in the order they are sent
+	requests  [][]byte
+	responses [][]byte
+}
+
+func (c *call) addRequest(b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, b)
+}
+
+func (c *call) addResponse(b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses = append(c.responses, b)
+}
+
+// setMessages sets messages of the record in JSON format,
+// the whole call is recorded in binary format if any of the messages can't be decoded
+func (c *call) setMessages() {
+	if c.md != nil {
+		requests, reqErr := messagesToJSON(c.md.Input(), c.requests)
+		responses, respErr := messagesToJSON(c.md.Output(), c.responses)
+		if reqErr == nil && respErr == nil {
+			c.rec.Requests = requests
+			c.rec.Responses = responses
+			return
+		}
+	}
+
+	c.rec.RawRequests = c.requests
+	c.rec.RawResponses = c.responses
+}
+
+func (c *call) setResponseHeaders(md metadata.MD) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rec.ResponseHeaders = md
+}
+
+func (c *call) setTrailers(md metadata.MD) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rec.Trailers = md
+}
+
+// findMethod returns the descriptor of /package.Service/Method, nil if it's not known
+func findMethod(fullMethod string) protoreflect.MethodDescriptor {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil
+	}
+
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil
+	}
+
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+
+	return sd.Methods().ByName(protoreflect.Name(method))
+}
+
+func toJSON(md protoreflect.MessageDescriptor, b []byte) (json.RawMessage, error) {
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	return protojson.MarshalOptions{
+		Resolver:      dynamicpb.NewTypes(protoregistry.GlobalFiles),
+		UseProtoNames: true,
+	}.Marshal(msg)
+}
+
+func messagesToJSON(md protoreflect.MessageDescriptor, messages [][]byte) ([]json.RawMessage, error) {
+	res := make([]json.RawMessage, 0, len(messages))
+	for _, b := range messages {
+		msg, err := toJSON(md, b)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, msg)
+	}
+	return res, nil
+}
+
+func fromJSON(md protoreflect.MessageDescriptor, b []byte) (proto.Message, error) {
+	msg := dynamicpb.NewMessage(md)
+	err := protojson.UnmarshalOptions{
+		Resolver: dynamicpb.NewTypes(protoregistry.GlobalFiles),
+	}.Unmarshal(b, msg)
+	return msg, err
+}
+
+// forwardedMetadata removes the headers set by grpc transport
+func forwardedMetadata(md metadata.MD) metadata.MD {
+	res := metadata.MD{}
+	for k, values := range md {
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+
+		switch k {
+		case "content-type", "user-agent", "te", "grpc-accept-encoding", "grpc-encoding", "grpc-timeout":
+			continue
+		}
+		res[k] = values
+	}
+	return res
+}
+
+// rawCodec passes serialized messages as is
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	*(v.(*[]byte)) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,238 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimi/grpc-client-cli/internal/mock"
+	"github.com/vadimi/grpc-client-cli/internal/testing/grpc_testing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var testStubs = []*mock.Stub{
+	{
+		Method:   "grpc_client_cli.testing.TestService/UnaryCall",
+		Match:    json.RawMessage(`{"user": {"id": 2}}`),
+		Status:   &mock.StubStatus{Code: codes.NotFound, Message: "no user"},
+		Trailers: map[string]string{"x-trailer": "done"},
+	},
+	{
+		Method:   "grpc_client_cli.testing.TestService/UnaryCall",
+		Response: json.RawMessage(`{"user": {"id": 1, "name": "backend"}}`),
+		Headers:  map[string]string{"x-backend": "mock"},
+	},
+	{
+		Method:    "grpc_client_cli.testing.TestService/StreamingOutputCall",
+		Responses: []json.RawMessage{[]byte(`{"user": {"id": 1}}`), []byte(`{"user": {"id": 2}}`)},
+	},
+}
+
+func startBackend(t *testing.T, stubs []*mock.Stub) *grpc.ClientConn {
+	t.Helper()
+
+	s, err := mock.NewServer([]protoreflect.FileDescriptor{grpc_testing.File_test_proto}, stubs)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return dial(t, lis.Addr().String())
+}
+
+func dial(t *testing.T, addr string) *grpc.ClientConn {
+	t.Helper()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestProxyRecord(t *testing.T) {
+	backend := startBackend(t, testStubs)
+
+	buf := &bytes.Buffer{}
+	p := NewProxy(backend, NewRecorder(buf))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go p.Serve(lis)
+
+	client := grpc_testing.NewTestServiceClient(dial(t, lis.Addr().String()))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request", "test")
+
+	var header metadata.MD
+	res, err := client.UnaryCall(ctx, &grpc_testing.SimpleRequest{User: &grpc_testing.User{Id: 1}}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "backend", res.GetUser().GetName())
+	assert.Equal(t, []string{"mock"}, header.Get("x-backend"))
+
+	var trailer metadata.MD
+	_, err = client.UnaryCall(ctx, &grpc_testing.SimpleRequest{User: &grpc_testing.User{Id: 2}}, grpc.Trailer(&trailer))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, []string{"done"}, trailer.Get("x-trailer"))
+
+	stream, err := client.StreamingOutputCall(ctx, &grpc_testing.StreamingOutputCallRequest{})
+	require.NoError(t, err)
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	p.Stop()
+
+	records := []*Record{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		rec := &Record{}
+		require.NoError(t, dec.Decode(rec))
+		records = append(records, rec)
+	}
+	require.Len(t, records, 3)
+
+	assert.Equal(t, "/grpc_client_cli.testing.TestService/UnaryCall", records[0].Method)
+	assert.Equal(t, []string{"test"}, records[0].RequestHeaders.Get("x-request"))
+	assert.Empty(t, records[0].RequestHeaders.Get(":authority"))
+	assert.Equal(t, []string{"mock"}, records[0].ResponseHeaders.Get("x-backend"))
+	require.Len(t, records[0].Requests, 1)
+	assert.JSONEq(t, `{"user": {"id": 1}}`, string(records[0].Requests[0]))
+	require.Len(t, records[0].Responses, 1)
+	assert.JSONEq(t, `{"user": {"id": 1, "name": "backend"}}`, string(records[0].Responses[0]))
+	assert.Equal(t, codes.OK, records[0].Status.Code)
+
+	assert.Equal(t, Status{Code: codes.NotFound, Message: "no user"}, records[1].Status)
+	assert.Equal(t, []string{"done"}, records[1].Trailers.Get("x-trailer"))
+	assert.Empty(t, records[1].Responses)
+
+	assert.Len(t, records[2].Responses, 2)
+
+	t.Run("replay", func(t *testing.T) {
+		for _, rec := range records {
+			res, err := Replay(context.Background(), backend, rec)
+			require.NoError(t, err)
+			assert.Empty(t, res.Diffs, rec.Method)
+		}
+	})
+
+	t.Run("replay changed", func(t *testing.T) {
+		changed := startBackend(t, []*mock.Stub{
+			{
+				Method:   "grpc_client_cli.testing.TestService/UnaryCall",
+				Response: json.RawMessage(`{"user": {"id": 1, "name": "changed"}}`),
+			},
+			{
+				Method:    "grpc_client_cli.testing.TestService/StreamingOutputCall",
+				Responses: []json.RawMessage{[]byte(`{"user": {"id": 1}}`)},
+			},
+		})
+
+		res, err := Replay(context.Background(), changed, records[0])
+		require.NoError(t, err)
+		assert.Equal(t, []string{`response #1: expected {"user":{"id":1,"name":"backend"}}, got {"user":{"id":1,"name":"changed"}}`}, res.Diffs)
+
+		res, err = Replay(context.Background(), changed, records[1])
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`status: expected NotFound "no user", got OK ""`,
+			"responses: expected 0 messages, got 1",
+		}, res.Diffs)
+
+		res, err = Replay(context.Background(), changed, records[2])
+		require.NoError(t, err)
+		assert.Equal(t, []string{"responses: expected 2 messages, got 1"}, res.Diffs)
+	})
+}
+
+func TestProxyUnknownMethod(t *testing.T) {
+	backend := startBackend(t, nil)
+
+	buf := &bytes.Buffer{}
+	p := NewProxy(backend, NewRecorder(buf))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go p.Serve(lis)
+
+	conn := dial(t, lis.Addr().String())
+	req := []byte{0x0a, 0x02, 0x08, 0x01}
+	var res []byte
+	err = conn.Invoke(context.Background(), "/unknown.Service/Method", &req, &res, grpc.ForceCodec(rawCodec{}))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	p.Stop()
+
+	rec := &Record{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), rec))
+	assert.Equal(t, [][]byte{req}, rec.RawRequests)
+	assert.Equal(t, codes.Unimplemented, rec.Status.Code)
+}
+
+func TestProxyRawCall(t *testing.T) {
+	echo := grpc.NewServer(
+		grpc.UnknownServiceHandler(func(_ any, ss grpc.ServerStream) error {
+			for {
+				var msg []byte
+				if err := ss.RecvMsg(&msg); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				if err := ss.SendMsg(&msg); err != nil {
+					return err
+				}
+			}
+		}),
+		grpc.ForceServerCodec(rawCodec{}),
+	)
+	backendLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go echo.Serve(backendLis)
+	t.Cleanup(echo.Stop)
+	backend := dial(t, backendLis.Addr().String())
+
+	buf := &bytes.Buffer{}
+	p := NewProxy(backend, NewRecorder(buf))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go p.Serve(lis)
+
+	// the second message can't be decoded, so the whole call is recorded in binary format
+	messages := [][]byte{{0x12, 0x02, 0x08, 0x01}, {0xff}, {0x12, 0x02, 0x08, 0x02}}
+	conn := dial(t, lis.Addr().String())
+	desc := &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}
+	cs, err := conn.NewStream(context.Background(), desc, "/grpc_client_cli.testing.TestService/FullDuplexCall", grpc.ForceCodec(rawCodec{}))
+	require.NoError(t, err)
+	for _, msg := range messages {
+		var res []byte
+		require.NoError(t, cs.SendMsg(&msg))
+		require.NoError(t, cs.RecvMsg(&res))
+	}
+	require.NoError(t, cs.CloseSend())
+	var res []byte
+	assert.Equal(t, io.EOF, cs.RecvMsg(&res))
+	p.Stop()
+
+	rec := &Record{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), rec))
+	assert.Empty(t, rec.Requests)
+	assert.Empty(t, rec.Responses)
+	assert.Equal(t, messages, rec.RawRequests)
+	assert.Equal(t, messages, rec.RawResponses)
+
+	replay, err := Replay(context.Background(), backend, rec)
+	require.NoError(t, err)
+	assert.Empty(t, replay.Diffs)
+}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// Record is a single call captured by the proxy
+type Record struct {
+	Method   string    `json:"method"`
+	Time     time.Time `json:"time"`
+	Duration string    `json:"duration"`
+
+	RequestHeaders  metadata.MD `json:"request_headers,omitempty"`
+	ResponseHeaders metadata.MD `json:"response_headers,omitempty"`
+	Trailers        metadata.MD `json:"trailers,omitempty"`
+
+	// Requests and Responses are messages in JSON format,
+	// they are set if the method descriptor is known and all messages of the call are decoded
+	Requests  []json.RawMessage `json:"requests,omitempty"`
+	Responses []json.RawMessage `json:"responses,omitempty"`
+	// RawRequests and RawResponses are binary messages of the calls that can't be decoded
+	RawRequests  [][]byte `json:"raw_requests,omitempty"`
+	RawResponses [][]byte `json:"raw_responses,omitempty"`
+
+	Status Status `json:"status"`
+}
+
+type Status struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+// Recorder writes records to the file, one JSON object per line
+type Recorder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+func (r *Recorder) Write(rec *Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadRecords reads all records written by Recorder from the file
+func ReadRecords(file string) ([]*Record, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []*Record{}
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		rec := &Record{}
+		if err := dec.Decode(rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid record #%d in %s: %w", len(records)+1, file, err)
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
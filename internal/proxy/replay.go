@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ReplayResult is the outcome of sending recorded requests again
+type ReplayResult struct {
+	Record *Record
+	Status Status
+	// Diffs describe differences between recorded and actual responses and status, empty if they match
+	Diffs []string
+}
+
+// Replay sends recorded requests using the connection and compares responses and status with the recorded ones,
+// headers, trailers and timing are not compared
+func Replay(ctx context.Context, conn grpc.ClientConnInterface, rec *Record) (*ReplayResult, error) {
+	md := findMethod(rec.Method)
+	requests, err := recordedRequests(md, rec)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(ctx, forwardedMetadata(rec.RequestHeaders)))
+	defer cancel()
+
+	responses := [][]byte{}
+	desc := &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}
+	cs, err := conn.NewStream(ctx, desc, rec.Method, grpc.ForceCodec(rawCodec{}))
+	if err == nil {
+		for _, req := range requests {
+			if err = cs.SendMsg(&req); err != nil {
+				break
+			}
+		}
+		// errors are returned by RecvMsg
+		cs.CloseSend()
+
+		for {
+			var msg []byte
+			if err = cs.RecvMsg(&msg); err != nil {
+				break
+			}
+			responses = append(responses, msg)
+		}
+	}
+
+	if err == io.EOF {
+		err = nil
+	}
+
+	st := status.Convert(err)
+	res := &ReplayResult{
+		Record: rec,
+		Status: Status{Code: st.Code(), Message: st.Message()},
+	}
+
+	if res.Status != rec.Status {
+		res.Diffs = append(res.Diffs, fmt.Sprintf("status: expected %s %q, got %s %q",
+			rec.Status.Code, rec.Status.Message, res.Status.Code, res.Status.Message))
+	}
+
+	res.Diffs = append(res.Diffs, compareResponses(md, rec, responses)...)
+	return res, nil
+}
+
+// recordedRequests returns binary requests of the record,
+// JSON messages are used only if the method descriptor is known
+func recordedRequests(md protoreflect.MethodDescriptor, rec *Record) ([][]byte, error) {
+	if len(rec.Requests) == 0 {
+		return rec.RawRequests, nil
+	}
+
+	if md == nil {
+		return nil, fmt.Errorf("%s: method descriptor is not found", rec.Method)
+	}
+
+	res := make([][]byte, len(rec.Requests))
+	for i, r := range rec.Requests {
+		msg, err := fromJSON(md.Input(), r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid request #%d: %w", rec.Method, i+1, err)
+		}
+
+		res[i], err = proto.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func compareResponses(md protoreflect.MethodDescriptor, rec *Record, actual [][]byte) []string {
+	expectedCount := len(rec.Responses) + len(rec.RawResponses)
+	if expectedCount != len(actual) {
+		return []string{fmt.Sprintf("responses: expected %d messages, got %d", expectedCount, len(actual))}
+	}
+
+	diffs := []string{}
+	if len(rec.Responses) == 0 {
+		for i, b := range rec.RawResponses {
+			if !bytes.Equal(b, actual[i]) {
+				diffs = append(diffs, fmt.Sprintf("response #%d: binary messages differ", i+1))
+			}
+		}
+		return diffs
+	}
+
+	if md == nil {
+		return []string{rec.Method + ": method descriptor is not found"}
+	}
+
+	for i, r := range rec.Responses {
+		diff := compareResponse(md.Output(), r, actual[i])
+		if diff != "" {
+			diffs = append(diffs, fmt.Sprintf("response #%d: %s", i+1, diff))
+		}
+	}
+
+	return diffs
+}
+
+// compareResponse returns empty string if the messages are equal
+func compareResponse(md protoreflect.MessageDescriptor, expected json.RawMessage, actual []byte) string {
+	expectedMsg, err := fromJSON(md, expected)
+	if err != nil {
+		return "invalid recorded message: " + err.Error()
+	}
+
+	actualMsg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(actual, actualMsg); err != nil {
+		return "invalid message: " + err.Error()
+	}
+
+	if proto.Equal(expectedMsg, actualMsg) {
+		return ""
+	}
+
+	actualJSON, err := toJSON(md, actual)
+	if err != nil {
+		return "invalid message: " + err.Error()
+	}
+
+	return fmt.Sprintf("expected %s, got %s", compactJSON(expected), compactJSON(actualJSON))
+}
+
+func compactJSON(b []byte) []byte {
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, b); err != nil {
+		return b
+	}
+	return buf.Bytes()
+}
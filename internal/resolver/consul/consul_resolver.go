@@ -0,0 +1,295 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	defaultAgent = "localhost:8500"
+
+	// consul blocking query wait time
+	watchWait = 5 * time.Minute
+	// consul adds up to wait/16 random jitter to blocking queries,
+	// the agent is considered hanging if it doesn't respond within the timeout
+	watchTimeout = watchWait + watchWait/16 + 30*time.Second
+	// delay between failed consul requests
+	retryDelay = 5 * time.Second
+)
+
+// NewConsulBuilder creates a consulBuilder which is used to factory consul resolvers.
+func NewConsulBuilder() resolver.Builder {
+	return &consulBuilder{client: newHTTPClient(watchTimeout)}
+}
+
+// newHTTPClient returns the client failing requests to the agents that accept connections and hang
+func newHTTPClient(responseTimeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: responseTimeout,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+type consulBuilder struct {
+	client *http.Client
+}
+
+// Build creates and starts a consul resolver that watches the instances of the target service.
+// Supported target format is consul://[agent-host:port]/service-name?tag=grpc&healthy=true
+func (b *consulBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	agent := target.URL.Host
+	serviceName := strings.Trim(target.Endpoint(), "/")
+
+	// consul://service-name uses local agent
+	if len(serviceName) == 0 {
+		serviceName = agent
+		agent = defaultAgent
+	}
+
+	if len(serviceName) == 0 {
+		return nil, fmt.Errorf("consul: service name is missing in target %q", target.URL.String())
+	}
+
+	if agent == "" {
+		agent = defaultAgent
+	}
+
+	if _, _, err := net.SplitHostPort(agent); err != nil {
+		agent = agent + ":8500"
+	}
+
+	query := target.URL.Query()
+	healthy := true
+	if val := query.Get("healthy"); val != "" {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("consul: invalid healthy parameter %q: %w", val, err)
+		}
+		healthy = b
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &consulResolver{
+		Agent:       agent,
+		ServiceName: serviceName,
+		Tags:        query["tag"],
+		Datacenter:  query.Get("dc"),
+		Healthy:     healthy,
+		ClientConn:  cc,
+		client:      b.client,
+		ctx:         ctx,
+		cancel:      cancel,
+		resolveNow:  make(chan struct{}, 1),
+	}
+
+	r.wg.Add(1)
+	go r.watch()
+
+	return r, nil
+}
+
+// Scheme returns the naming scheme of this resolver builder, which is "consul".
+func (b *consulBuilder) Scheme() string {
+	return "consul"
+}
+
+type consulResolver struct {
+	Agent       string
+	ServiceName string
+	Tags        []string
+	Datacenter  string
+	Healthy     bool
+	ClientConn  resolver.ClientConn
+
+	client     *http.Client
+	ctx        context.Context
+	cancel     context.CancelFunc
+	resolveNow chan struct{}
+	wg         sync.WaitGroup
+}
+
+// consulService is the subset of consul health/catalog service entries used by the resolver
+type consulService struct {
+	// catalog endpoint fields
+	Address        string
+	ServiceAddress string
+	ServicePort    int
+	ServiceMeta    map[string]string
+
+	// health endpoint fields
+	Node    *consulNode
+	Service *consulAgentService
+}
+
+type consulNode struct {
+	Address string
+}
+
+type consulAgentService struct {
+	Address string
+	Port    int
+	Meta    map[string]string
+}
+
+// address returns instance host:port, the port could be overridden by "grpc" or "grpc.port" metadata keys
+func (s *consulService) address() string {
+	host := s.ServiceAddress
+	if host == "" {
+		host = s.Address
+	}
+	port := strconv.Itoa(s.ServicePort)
+	meta := s.ServiceMeta
+
+	if s.Service != nil {
+		host = s.Service.Address
+		if host == "" && s.Node != nil {
+			host = s.Node.Address
+		}
+		port = strconv.Itoa(s.Service.Port)
+		meta = s.Service.Meta
+	}
+
+	if val, ok := meta["grpc"]; ok {
+		port = val
+	}
+
+	if val, ok := meta["grpc.port"]; ok {
+		port = val
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+// ResolveNow invokes an immediate resolution of the target that this consulResolver watches.
+func (r *consulResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *consulResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// watch uses consul blocking queries to get notified about service instances changes
+func (r *consulResolver) watch() {
+	defer r.wg.Done()
+
+	var index uint64
+	for {
+		newIndex, err := r.resolve(index)
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			r.ClientConn.ReportError(err)
+		}
+
+		// consul index could go backwards, reset it in that case,
+		// zero index means that blocking queries are not supported, so poll with a delay instead
+		if err != nil || newIndex == 0 || newIndex < index {
+			index = 0
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-r.resolveNow:
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		index = newIndex
+	}
+}
+
+func (r *consulResolver) resolve(index uint64) (uint64, error) {
+	services, newIndex, err := r.query(index)
+	if err != nil {
+		return 0, err
+	}
+
+	addrs := make([]resolver.Address, 0, len(services))
+	for _, s := range services {
+		addrs = append(addrs, resolver.Address{Addr: s.address()})
+	}
+
+	if len(addrs) == 0 {
+		r.ClientConn.ReportError(fmt.Errorf("no address for consul service %v", r.ServiceName))
+		return newIndex, nil
+	}
+
+	// errors could be ignored here as consul resolver is watch based
+	_ = r.ClientConn.UpdateState(resolver.State{Addresses: addrs})
+	return newIndex, nil
+}
+
+func (r *consulResolver) query(index uint64) ([]*consulService, uint64, error) {
+	endpoint := "/v1/catalog/service/"
+	if r.Healthy {
+		endpoint = "/v1/health/service/"
+	}
+
+	q := url.Values{}
+	for _, t := range r.Tags {
+		q.Add("tag", t)
+	}
+
+	if r.Healthy {
+		q.Set("passing", "true")
+	}
+
+	if r.Datacenter != "" {
+		q.Set("dc", r.Datacenter)
+	}
+
+	q.Set("index", strconv.FormatUint(index, 10))
+	q.Set("wait", watchWait.String())
+
+	u := url.URL{
+		Scheme:   "http",
+		Host:     r.Agent,
+		Path:     endpoint + url.PathEscape(r.ServiceName),
+		RawQuery: q.Encode(),
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("consul request %s failed: %s", u.Path, resp.Status)
+	}
+
+	var services []*consulService
+	if err := json.NewDecoder(resp.Body).Decode(&services); err != nil {
+		return nil, 0, fmt.Errorf("consul response decode error: %w", err)
+	}
+
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	return services, newIndex, nil
+}
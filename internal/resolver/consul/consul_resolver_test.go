@@ -0,0 +1,229 @@
+package consul
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/resolver"
+)
+
+type testClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+	errs   chan error
+}
+
+func newTestClientConn() *testClientConn {
+	return &testClientConn{
+		states: make(chan resolver.State, 10),
+		errs:   make(chan error, 10),
+	}
+}
+
+func (t *testClientConn) UpdateState(s resolver.State) error {
+	t.states <- s
+	return nil
+}
+
+func (t *testClientConn) ReportError(err error) {
+	t.errs <- err
+}
+
+func (t *testClientConn) waitState(tb testing.TB) []string {
+	select {
+	case s := <-t.states:
+		addrs := []string{}
+		for _, a := range s.Addresses {
+			addrs = append(addrs, a.Addr)
+		}
+		sort.Strings(addrs)
+		return addrs
+	case err := <-t.errs:
+		tb.Fatalf("unexpected resolver error: %v", err)
+	case <-time.After(5 * time.Second):
+		tb.Fatal("timeout waiting for resolver state")
+	}
+	return nil
+}
+
+func buildResolver(t *testing.T, srv *httptest.Server, target string, cc resolver.ClientConn) resolver.Resolver {
+	target = strings.ReplaceAll(target, "{agent}", strings.TrimPrefix(srv.URL, "http://"))
+	u, err := url.Parse(target)
+	require.NoError(t, err)
+
+	b := &consulBuilder{client: srv.Client()}
+	r, err := b.Build(resolver.Target{URL: *u}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestConsulResolverHealth(t *testing.T) {
+	var query atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/users" {
+			http.NotFound(w, r)
+			return
+		}
+		// block subsequent watch requests until the test completes
+		if r.URL.Query().Get("index") != "0" {
+			<-r.Context().Done()
+			return
+		}
+		query.Store(r.URL.Query())
+		w.Header().Set("X-Consul-Index", "10")
+		w.Write([]byte(`[
+			{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 8080, "Meta": {"grpc.port": "9090"}}},
+			{"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "10.0.1.2", "Port": 8080, "Meta": {"grpc": "5050"}}},
+			{"Node": {"Address": "10.0.0.3"}, "Service": {"Address": "10.0.1.3", "Port": 8080}}
+		]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cc := newTestClientConn()
+	buildResolver(t, srv, "consul://{agent}/users?tag=grpc&tag=v2", cc)
+
+	addrs := cc.waitState(t)
+	assert.Equal(t, []string{"10.0.0.1:9090", "10.0.1.2:5050", "10.0.1.3:8080"}, addrs)
+
+	q := query.Load().(url.Values)
+	assert.Equal(t, []string{"grpc", "v2"}, q["tag"])
+	assert.Equal(t, "true", q.Get("passing"))
+}
+
+func TestConsulResolverCatalog(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/catalog/service/users" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("index") != "0" {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("X-Consul-Index", "1")
+		w.Write([]byte(`[
+			{"Address": "10.0.0.1", "ServiceAddress": "", "ServicePort": 8080},
+			{"Address": "10.0.0.2", "ServiceAddress": "10.0.1.2", "ServicePort": 8080, "ServiceMeta": {"grpc": "5050"}}
+		]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cc := newTestClientConn()
+	buildResolver(t, srv, "consul://{agent}/users?healthy=false", cc)
+
+	addrs := cc.waitState(t)
+	assert.Equal(t, []string{"10.0.0.1:8080", "10.0.1.2:5050"}, addrs)
+}
+
+func TestConsulResolverWatch(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			assert.Equal(t, "0", r.URL.Query().Get("index"))
+			w.Header().Set("X-Consul-Index", "1")
+			w.Write([]byte(`[{"Service": {"Address": "10.0.0.1", "Port": 8080}}]`))
+		case 2:
+			assert.Equal(t, "1", r.URL.Query().Get("index"))
+			w.Header().Set("X-Consul-Index", "2")
+			w.Write([]byte(`[{"Service": {"Address": "10.0.0.1", "Port": 8080}}, {"Service": {"Address": "10.0.0.2", "Port": 8080}}]`))
+		default:
+			<-r.Context().Done()
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cc := newTestClientConn()
+	buildResolver(t, srv, "consul://{agent}/users", cc)
+
+	assert.Equal(t, []string{"10.0.0.1:8080"}, cc.waitState(t))
+	assert.Equal(t, []string{"10.0.0.1:8080", "10.0.0.2:8080"}, cc.waitState(t))
+}
+
+func TestConsulResolverNoInstances(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("index") != "0" {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("X-Consul-Index", "1")
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cc := newTestClientConn()
+	buildResolver(t, srv, "consul://{agent}/users", cc)
+
+	select {
+	case err := <-cc.errs:
+		assert.ErrorContains(t, err, "no address for consul service users")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for resolver error")
+	}
+}
+
+func TestConsulBuilderTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		agent   string
+		service string
+	}{
+		{"consul://users", defaultAgent, "users"},
+		{"consul:///users", defaultAgent, "users"},
+		{"consul://consul.local/users", "consul.local:8500", "users"},
+		{"consul://consul.local:9500/users", "consul.local:9500", "users"},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.target)
+		require.NoError(t, err)
+
+		// use unreachable client so the watcher fails fast
+		b := &consulBuilder{client: &http.Client{Transport: failingTransport{}}}
+		r, err := b.Build(resolver.Target{URL: *u}, newTestClientConn(), resolver.BuildOptions{})
+		require.NoError(t, err)
+
+		cr := r.(*consulResolver)
+		assert.Equal(t, test.agent, cr.Agent, test.target)
+		assert.Equal(t, test.service, cr.ServiceName, test.target)
+		r.Close()
+	}
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, http.ErrServerClosed
+}
+
+func TestConsulResolverHangingAgent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse("consul://" + strings.TrimPrefix(srv.URL, "http://") + "/users")
+	require.NoError(t, err)
+
+	cc := newTestClientConn()
+	b := &consulBuilder{client: newHTTPClient(100 * time.Millisecond)}
+	r, err := b.Build(resolver.Target{URL: *u}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+
+	select {
+	case err := <-cc.errs:
+		assert.ErrorContains(t, err, "timeout awaiting response headers")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for resolver error")
+	}
+}
@@ -1,23 +1,102 @@
 package eureka
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	ec "github.com/ArthurHlt/go-eureka-client/eureka"
 	"google.golang.org/grpc/resolver"
 )
 
-// NewEurekaBuilder creates a eurekaBuilder which is used to factory DNS resolvers.
-func NewEurekaBuilder() resolver.Builder {
-	return &eurekaBuilder{}
+const (
+	// default eureka registry fetch interval
+	defaultRefreshInterval = 30 * time.Second
+	// default timeout of eureka registry requests
+	defaultRequestTimeout = 10 * time.Second
+
+	statusUp = "UP"
+)
+
+// BuilderOption configures eureka resolvers created by the builder
+type BuilderOption func(*eurekaBuilder)
+
+// WithSecurePort makes resolvers prefer instances secure port over the insecure one
+func WithSecurePort(securePort bool) BuilderOption {
+	return func(b *eurekaBuilder) {
+		b.securePort = securePort
+	}
+}
+
+// WithRefreshInterval sets how often eureka registry is polled for instances changes
+func WithRefreshInterval(d time.Duration) BuilderOption {
+	return func(b *eurekaBuilder) {
+		if d > 0 {
+			b.refreshInterval = d
+		}
+	}
+}
+
+// WithRequestTimeout limits how long connecting to eureka registry and waiting for its response can take
+func WithRequestTimeout(d time.Duration) BuilderOption {
+	return func(b *eurekaBuilder) {
+		if d > 0 {
+			b.requestTimeout = d
+		}
+	}
+}
+
+// WithTLSConfig sets TLS configuration used to connect to eureka registry over https
+func WithTLSConfig(cfg *tls.Config) BuilderOption {
+	return func(b *eurekaBuilder) {
+		b.tlsConfig = cfg
+	}
+}
+
+// NewEurekaBuilder creates a eurekaBuilder which is used to factory eureka resolvers
+// connecting to eureka registry over http.
+func NewEurekaBuilder(opts ...BuilderOption) resolver.Builder {
+	return newEurekaBuilder("eureka", "http", opts...)
+}
+
+// NewSecureEurekaBuilder creates a eurekaBuilder which is used to factory eureka resolvers
+// connecting to eureka registry over https.
+func NewSecureEurekaBuilder(opts ...BuilderOption) resolver.Builder {
+	return newEurekaBuilder("eurekas", "https", opts...)
+}
+
+func newEurekaBuilder(scheme, registryScheme string, opts ...BuilderOption) *eurekaBuilder {
+	b := &eurekaBuilder{
+		scheme:          scheme,
+		registryScheme:  registryScheme,
+		refreshInterval: defaultRefreshInterval,
+		requestTimeout:  defaultRequestTimeout,
+	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	return b
 }
 
-type eurekaBuilder struct{}
+type eurekaBuilder struct {
+	scheme          string
+	registryScheme  string
+	securePort      bool
+	refreshInterval time.Duration
+	requestTimeout  time.Duration
+	tlsConfig       *tls.Config
+}
 
-// Build creates and starts a DNS resolver that watches the name resolution of the target.
+// Build creates and starts a eureka resolver that watches the name resolution of the target.
 func (b *eurekaBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
 	eurekaServer := target.URL.Host
 	serviceName := target.Endpoint()
@@ -29,6 +108,7 @@ func (b *eurekaBuilder) Build(target resolver.Target, cc resolver.ClientConn, op
 		eurekaPath = "eureka"
 	}
 
+	serviceName = strings.TrimSuffix(serviceName, "/")
 	serviceNameIndex := strings.LastIndex(serviceName, "/")
 
 	if serviceNameIndex != -1 {
@@ -42,58 +122,113 @@ func (b *eurekaBuilder) Build(target resolver.Target, cc resolver.ClientConn, op
 		eurekaServer = eurekaServer + ":8761"
 	}
 
-	d := &eurekaResolver{EurekaServer: eurekaServer, EurekaPath: eurekaPath, ServiceName: serviceName, ClientConn: cc}
+	eurekaURL := url.URL{Scheme: b.registryScheme, Host: eurekaServer, Path: eurekaPath, User: target.URL.User}
 
-	d.ResolveNow(resolver.ResolveNowOptions{})
+	eurekaClient := ec.NewClient([]string{
+		eurekaURL.String(),
+	})
+	// replace default transport as it skips certificates verification,
+	// the client doesn't support request timeouts so they are set on the transport
+	// to make sure hung registry doesn't block the resolver forever
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSClientConfig:       b.tlsConfig,
+		DialContext:           timeoutDialer(b.requestTimeout),
+		TLSHandshakeTimeout:   b.requestTimeout,
+		ResponseHeaderTimeout: b.requestTimeout,
+	}
+	eurekaClient.SetTransport(transport)
+
+	d := &eurekaResolver{
+		EurekaServer:    eurekaServer,
+		EurekaPath:      eurekaPath,
+		ServiceName:     serviceName,
+		ClientConn:      cc,
+		SecurePort:      b.securePort,
+		RefreshInterval: b.refreshInterval,
+		client:          eurekaClient,
+		transport:       transport,
+		resolveNow:      make(chan struct{}, 1),
+		done:            make(chan struct{}),
+	}
+
+	d.wg.Add(1)
+	go d.watch()
 
 	return d, nil
 }
 
-// Scheme returns the naming scheme of this resolver builder, which is "eureka".
+// Scheme returns the naming scheme of this resolver builder, which is "eureka" or "eurekas".
 func (b *eurekaBuilder) Scheme() string {
-	return "eureka"
+	return b.scheme
 }
 
 type eurekaResolver struct {
-	EurekaServer string
-	EurekaPath   string
-	ServiceName  string
-	ClientConn   resolver.ClientConn
+	EurekaServer    string
+	EurekaPath      string
+	ServiceName     string
+	ClientConn      resolver.ClientConn
+	SecurePort      bool
+	RefreshInterval time.Duration
+
+	client     *ec.Client
+	transport  *http.Transport
+	resolveNow chan struct{}
+	done       chan struct{}
+	wg         sync.WaitGroup
 }
 
-// ResolveNow invoke an immediate resolution of the target that this dnsResolver watches.
+// ResolveNow invoke an immediate resolution of the target that this eurekaResolver watches.
 func (d *eurekaResolver) ResolveNow(resolver.ResolveNowOptions) {
-	eurekaURL := url.URL{Scheme: "http", Host: d.EurekaServer, Path: d.EurekaPath}
+	select {
+	case d.resolveNow <- struct{}{}:
+	default:
+	}
+}
 
-	eurekaClient := ec.NewClient([]string{
-		eurekaURL.String(),
-	})
+func (d *eurekaResolver) Close() {
+	close(d.done)
+	d.wg.Wait()
+	d.transport.CloseIdleConnections()
+}
 
-	application, err := eurekaClient.GetApplication(d.ServiceName)
+// watch periodically polls eureka registry for instances of the application
+func (d *eurekaResolver) watch() {
+	defer d.wg.Done()
 
-	if err == nil {
+	ticker := time.NewTicker(d.RefreshInterval)
+	defer ticker.Stop()
 
-		var newAddrs []resolver.Address = make([]resolver.Address, 0, len(application.Instances))
+	for {
+		d.resolve()
 
-		for _, instance := range application.Instances {
+		select {
+		case <-d.done:
+			return
+		case <-d.resolveNow:
+		case <-ticker.C:
+		}
+	}
+}
 
-			port := strconv.Itoa(instance.Port.Port)
+func (d *eurekaResolver) resolve() {
+	application, err := d.client.GetApplication(d.ServiceName)
 
-			if val, ok := instance.Metadata.Map["grpc"]; ok {
-				port = val
-			}
+	if err == nil {
+
+		var newAddrs []resolver.Address = make([]resolver.Address, 0, len(application.Instances))
 
-			if val, ok := instance.Metadata.Map["grpc.port"]; ok {
-				port = val
+		for _, instance := range application.Instances {
+			if !strings.EqualFold(instance.Status, statusUp) {
+				continue
 			}
 
-			addr := instance.IpAddr + ":" + port
+			addr := instance.IpAddr + ":" + d.instancePort(&instance)
 			newAddrs = append(newAddrs, resolver.Address{Addr: addr})
 		}
 
 		if len(newAddrs) == 0 {
 			err = fmt.Errorf("No address for application %v", d.ServiceName)
-			d.ClientConn.ReportError(err)
 		} else {
 			state := &resolver.State{
 				Addresses: newAddrs,
@@ -108,4 +243,60 @@ func (d *eurekaResolver) ResolveNow(resolver.ResolveNowOptions) {
 	}
 }
 
-func (d *eurekaResolver) Close() {}
+// instancePort returns instance port in the following order:
+// "grpc" metadata key, "grpc.port" metadata key, secure port if preferred and enabled, insecure port
+func (d *eurekaResolver) instancePort(instance *ec.InstanceInfo) string {
+	port := ""
+
+	if instance.Port != nil {
+		port = strconv.Itoa(instance.Port.Port)
+	}
+
+	if d.SecurePort && instance.SecurePort != nil && instance.SecurePort.Enabled {
+		port = strconv.Itoa(instance.SecurePort.Port)
+	}
+
+	if instance.Metadata != nil {
+		if val, ok := instance.Metadata.Map["grpc"]; ok {
+			port = val
+		}
+
+		if val, ok := instance.Metadata.Map["grpc.port"]; ok {
+			port = val
+		}
+	}
+
+	return port
+}
+
+// timeoutDialer returns dial func creating connections that fail if no data is read
+// or written within the timeout, so reading a response body can't hang as well
+func timeoutDialer(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &timeoutConn{Conn: conn, timeout: timeout}, nil
+	}
+}
+
+type timeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *timeoutConn) Write(b []byte) (int, error) {
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,229 @@
+package eureka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/resolver"
+)
+
+const appXML = `<application>
+  <name>USERS</name>
+  <instance>
+    <ipAddr>10.0.0.1</ipAddr>
+    <status>UP</status>
+    <port enabled="true">8080</port>
+    <securePort enabled="true">8443</securePort>
+  </instance>
+  <instance>
+    <ipAddr>10.0.0.2</ipAddr>
+    <status>UP</status>
+    <port enabled="true">8080</port>
+    <securePort enabled="false">8443</securePort>
+    <metadata>
+      <grpc.port>9090</grpc.port>
+    </metadata>
+  </instance>
+  <instance>
+    <ipAddr>10.0.0.3</ipAddr>
+    <status>DOWN</status>
+    <port enabled="true">8080</port>
+  </instance>
+  <instance>
+    <ipAddr>10.0.0.4</ipAddr>
+    <status>OUT_OF_SERVICE</status>
+    <port enabled="true">8080</port>
+  </instance>
+</application>`
+
+type testClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+	errs   chan error
+}
+
+func newTestClientConn() *testClientConn {
+	return &testClientConn{
+		states: make(chan resolver.State, 10),
+		errs:   make(chan error, 10),
+	}
+}
+
+func (t *testClientConn) UpdateState(s resolver.State) error {
+	t.states <- s
+	return nil
+}
+
+func (t *testClientConn) ReportError(err error) {
+	t.errs <- err
+}
+
+func (t *testClientConn) waitState(tb testing.TB) []string {
+	select {
+	case s := <-t.states:
+		addrs := []string{}
+		for _, a := range s.Addresses {
+			addrs = append(addrs, a.Addr)
+		}
+		sort.Strings(addrs)
+		return addrs
+	case err := <-t.errs:
+		tb.Fatalf("unexpected resolver error: %v", err)
+	case <-time.After(5 * time.Second):
+		tb.Fatal("timeout waiting for resolver state")
+	}
+	return nil
+}
+
+func newEurekaServer(t *testing.T, tlsSrv bool, handler http.HandlerFunc) *httptest.Server {
+	var srv *httptest.Server
+	if tlsSrv {
+		srv = httptest.NewTLSServer(handler)
+	} else {
+		srv = httptest.NewServer(handler)
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func buildResolver(t *testing.T, b resolver.Builder, target string, cc resolver.ClientConn) {
+	u, err := url.Parse(target)
+	require.NoError(t, err)
+
+	r, err := b.Build(resolver.Target{URL: *u}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+}
+
+func TestEurekaResolver(t *testing.T) {
+	srv := newEurekaServer(t, false, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/eureka/apps/users" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(appXML))
+	})
+
+	cc := newTestClientConn()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	buildResolver(t, NewEurekaBuilder(), "eureka://"+host+"/eureka/users/", cc)
+
+	// DOWN and OUT_OF_SERVICE instances are filtered out
+	assert.Equal(t, []string{"10.0.0.1:8080", "10.0.0.2:9090"}, cc.waitState(t))
+}
+
+func TestEurekaResolverSecurePort(t *testing.T) {
+	srv := newEurekaServer(t, false, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(appXML))
+	})
+
+	cc := newTestClientConn()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	buildResolver(t, NewEurekaBuilder(WithSecurePort(true)), "eureka://"+host+"/eureka/users", cc)
+
+	// metadata port takes precedence over the secure one, disabled secure port is ignored
+	assert.Equal(t, []string{"10.0.0.1:8443", "10.0.0.2:9090"}, cc.waitState(t))
+}
+
+func TestEurekaResolverHTTPSBasicAuth(t *testing.T) {
+	srv := newEurekaServer(t, true, func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(appXML))
+	})
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(srv.Certificate())
+
+	cc := newTestClientConn()
+	host := strings.TrimPrefix(srv.URL, "https://")
+	b := NewSecureEurekaBuilder(WithTLSConfig(&tls.Config{RootCAs: certPool}))
+	assert.Equal(t, "eurekas", b.Scheme())
+	buildResolver(t, b, "eurekas://admin:secret@"+host+"/eureka/users", cc)
+
+	assert.Equal(t, []string{"10.0.0.1:8080", "10.0.0.2:9090"}, cc.waitState(t))
+}
+
+func TestEurekaResolverRefresh(t *testing.T) {
+	var calls atomic.Int32
+	srv := newEurekaServer(t, false, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Write([]byte(`<application><name>USERS</name><instance><ipAddr>10.0.0.1</ipAddr><status>UP</status><port>8080</port></instance></application>`))
+			return
+		}
+		w.Write([]byte(`<application><name>USERS</name><instance><ipAddr>10.0.0.5</ipAddr><status>UP</status><port>8080</port></instance></application>`))
+	})
+
+	cc := newTestClientConn()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	buildResolver(t, NewEurekaBuilder(WithRefreshInterval(50*time.Millisecond)), "eureka://"+host+"/eureka/users", cc)
+
+	assert.Equal(t, []string{"10.0.0.1:8080"}, cc.waitState(t))
+	assert.Equal(t, []string{"10.0.0.5:8080"}, cc.waitState(t))
+}
+
+func TestEurekaResolverNoInstancesUp(t *testing.T) {
+	srv := newEurekaServer(t, false, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<application><name>USERS</name><instance><ipAddr>10.0.0.1</ipAddr><status>DOWN</status><port>8080</port></instance></application>`))
+	})
+
+	cc := newTestClientConn()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	buildResolver(t, NewEurekaBuilder(), "eureka://"+host+"/eureka/users", cc)
+
+	select {
+	case err := <-cc.errs:
+		assert.ErrorContains(t, err, "No address for application users")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for resolver error")
+	}
+}
+
+func TestEurekaResolverRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := newEurekaServer(t, false, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	// unblock the handler before the server is closed, otherwise srv.Close waits for it
+	t.Cleanup(func() { close(release) })
+
+	cc := newTestClientConn()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	u, err := url.Parse("eureka://" + host + "/eureka/users")
+	require.NoError(t, err)
+
+	r, err := NewEurekaBuilder(WithRequestTimeout(100*time.Millisecond)).Build(resolver.Target{URL: *u}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+
+	select {
+	case err := <-cc.errs:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for resolver error")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		r.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resolver close is blocked by hung registry")
+	}
+}
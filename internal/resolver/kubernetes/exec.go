@@ -0,0 +1,144 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+	// the token is refreshed a bit before it expires
+	execTokenExpiryDelta = 10 * time.Second
+	execTimeout          = time.Minute
+)
+
+// execConfig is exec credential plugin configuration of kubeconfig user
+type execConfig struct {
+	Command    string   `yaml:"command"`
+	Args       []string `yaml:"args"`
+	APIVersion string   `yaml:"apiVersion"`
+	Env        []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+// execCredential is the output of exec credential plugin, only token credentials are supported
+type execCredential struct {
+	Status *struct {
+		Token               string     `json:"token"`
+		ExpirationTimestamp *time.Time `json:"expirationTimestamp"`
+	} `json:"status"`
+}
+
+// execPlugin runs exec credential plugin, the token is cached until it expires
+type execPlugin struct {
+	command    string
+	args       []string
+	env        []string
+	apiVersion string
+	now        func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func newExecPlugin(cfg *execConfig, dir string) (*execPlugin, error) {
+	if cfg.Command == "" {
+		return nil, errors.New("exec command is empty")
+	}
+
+	p := &execPlugin{
+		command:    cfg.Command,
+		args:       cfg.Args,
+		apiVersion: cfg.APIVersion,
+		now:        time.Now,
+	}
+
+	// the same as kubectl: commands with path separators are relative to kubeconfig directory,
+	// other commands are looked up in PATH
+	if strings.ContainsRune(p.command, filepath.Separator) {
+		p.command = resolvePath(dir, p.command)
+	}
+
+	if p.apiVersion == "" {
+		p.apiVersion = defaultExecAPIVersion
+	}
+
+	for _, e := range cfg.Env {
+		p.env = append(p.env, e.Name+"="+e.Value)
+	}
+
+	return p, nil
+}
+
+func (p *execPlugin) getToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && (p.expiry.IsZero() || p.now().Add(execTokenExpiryDelta).Before(p.expiry)) {
+		return p.token, nil
+	}
+
+	token, expiry, err := p.run()
+	if err != nil {
+		return "", fmt.Errorf("kubeconfig exec credential plugin: %w", err)
+	}
+
+	p.token = token
+	p.expiry = expiry
+	return token, nil
+}
+
+func (p *execPlugin) run() (string, time.Time, error) {
+	info, err := json.Marshal(map[string]any{
+		"apiVersion": p.apiVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]any{"interactive": false},
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command, p.args...)
+	cmd.Env = append(os.Environ(), p.env...)
+	cmd.Env = append(cmd.Env, "KUBERNETES_EXEC_INFO="+string(info))
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// don't wait for output of child processes that keep running after the command is killed
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		return "", time.Time{}, fmt.Errorf("%s failed: %w", p.command, err)
+	}
+
+	var cred execCredential
+	if err := json.Unmarshal(stdout.Bytes(), &cred); err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid %s output: %w", p.command, err)
+	}
+
+	if cred.Status == nil || cred.Status.Token == "" {
+		return "", time.Time{}, fmt.Errorf("%s returned no token", p.command)
+	}
+
+	var expiry time.Time
+	if cred.Status.ExpirationTimestamp != nil {
+		expiry = *cred.Status.ExpirationTimestamp
+	}
+
+	return cred.Status.Token, expiry, nil
+}
@@ -0,0 +1,367 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	defaultNamespace  = "default"
+)
+
+// restConfig contains everything needed to talk to kubernetes API server
+type restConfig struct {
+	Host      string
+	Namespace string
+	TLSConfig *tls.Config
+	Token     string
+	Username  string
+	Password  string
+	// Exec gets the token from a credential plugin if it's set
+	Exec *execPlugin
+}
+
+// authorize adds credentials to the API server request
+func (c *restConfig) authorize(h http.Header) error {
+	token := c.Token
+	if c.Exec != nil {
+		var err error
+		if token, err = c.Exec.getToken(); err != nil {
+			return err
+		}
+	}
+
+	if token != "" {
+		h.Set("Authorization", "Bearer "+token)
+	} else if c.Username != "" {
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password)))
+	}
+	return nil
+}
+
+func (c *restConfig) httpClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: c.TLSConfig,
+		},
+	}
+}
+
+// kubeConfig is the subset of kubeconfig file format supported by the resolver
+type kubeConfig struct {
+	CurrentContext string        `yaml:"current-context"`
+	Clusters       []kubeCluster `yaml:"clusters"`
+	Contexts       []kubeContext `yaml:"contexts"`
+	Users          []kubeUser    `yaml:"users"`
+}
+
+type kubeCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthority     string `yaml:"certificate-authority"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		TLSServerName            string `yaml:"tls-server-name"`
+	} `yaml:"cluster"`
+	// dir is the directory of the file the cluster is defined in, relative paths are resolved against it
+	dir string
+}
+
+type kubeContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster   string `yaml:"cluster"`
+		User      string `yaml:"user"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"context"`
+}
+
+type kubeUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		Token                 string      `yaml:"token"`
+		TokenFile             string      `yaml:"tokenFile"`
+		ClientCertificate     string      `yaml:"client-certificate"`
+		ClientCertificateData string      `yaml:"client-certificate-data"`
+		ClientKey             string      `yaml:"client-key"`
+		ClientKeyData         string      `yaml:"client-key-data"`
+		Username              string      `yaml:"username"`
+		Password              string      `yaml:"password"`
+		Exec                  *execConfig `yaml:"exec"`
+		AuthProvider          any         `yaml:"auth-provider"`
+	} `yaml:"user"`
+	// dir is the directory of the file the user is defined in, relative paths are resolved against it
+	dir string
+}
+
+// loadRestConfig reads kubeconfig from KUBECONFIG env variable or ~/.kube/config,
+// in-cluster service account configuration is used if no kubeconfig is found.
+// kubeContext overrides kubeconfig current-context if not empty.
+func loadRestConfig(kubeContext string) (*restConfig, error) {
+	paths := kubeconfigPaths()
+	if len(paths) == 0 {
+		if cfg, err := inClusterConfig(); err == nil {
+			return cfg, nil
+		}
+		return nil, errors.New("kubeconfig not found")
+	}
+
+	return loadKubeconfig(paths, kubeContext)
+}
+
+// kubeconfigPaths returns the files listed in KUBECONFIG or ~/.kube/config if it exists
+func kubeconfigPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		paths := []string{}
+		for p := range strings.SplitSeq(env, string(os.PathListSeparator)) {
+			if p != "" && !slices.Contains(paths, p) {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	p := filepath.Join(home, ".kube", "config")
+	if _, err := os.Stat(p); err != nil {
+		return nil
+	}
+
+	return []string{p}
+}
+
+// mergeKubeconfig reads the files the same way as kubectl does: missing files are skipped,
+// the first file setting current-context or defining a cluster, context or user with the same name wins
+func mergeKubeconfig(paths []string) (*kubeConfig, error) {
+	res := &kubeConfig{}
+	found := false
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+		found = true
+
+		var kc kubeConfig
+		if err := yaml.Unmarshal(b, &kc); err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+		}
+
+		if res.CurrentContext == "" {
+			res.CurrentContext = kc.CurrentContext
+		}
+
+		dir := filepath.Dir(path)
+		for _, c := range kc.Clusters {
+			if !slices.ContainsFunc(res.Clusters, func(e kubeCluster) bool { return e.Name == c.Name }) {
+				c.dir = dir
+				res.Clusters = append(res.Clusters, c)
+			}
+		}
+
+		for _, c := range kc.Contexts {
+			if !slices.ContainsFunc(res.Contexts, func(e kubeContext) bool { return e.Name == c.Name }) {
+				res.Contexts = append(res.Contexts, c)
+			}
+		}
+
+		for _, u := range kc.Users {
+			if !slices.ContainsFunc(res.Users, func(e kubeUser) bool { return e.Name == u.Name }) {
+				u.dir = dir
+				res.Users = append(res.Users, u)
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("kubeconfig not found: %s", strings.Join(paths, string(os.PathListSeparator)))
+	}
+
+	return res, nil
+}
+
+func loadKubeconfig(paths []string, kubeContext string) (*restConfig, error) {
+	kc, err := mergeKubeconfig(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	if kubeContext == "" {
+		kubeContext = kc.CurrentContext
+	}
+
+	cfg := &restConfig{Namespace: defaultNamespace}
+	tlsCfg := &tls.Config{}
+	cfg.TLSConfig = tlsCfg
+
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name != kubeContext {
+			continue
+		}
+		found = true
+
+		if c.Context.Namespace != "" {
+			cfg.Namespace = c.Context.Namespace
+		}
+
+		for _, cl := range kc.Clusters {
+			if cl.Name != c.Context.Cluster {
+				continue
+			}
+
+			cfg.Host = cl.Cluster.Server
+			tlsCfg.InsecureSkipVerify = cl.Cluster.InsecureSkipTLSVerify
+			tlsCfg.ServerName = cl.Cluster.TLSServerName
+
+			ca, err := readDataOrFile(cl.Cluster.CertificateAuthorityData, resolvePath(cl.dir, cl.Cluster.CertificateAuthority))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read cluster certificate authority: %w", err)
+			}
+
+			if len(ca) > 0 {
+				pool := x509.NewCertPool()
+				if !pool.AppendCertsFromPEM(ca) {
+					return nil, errors.New("failed to append cluster certificate authority")
+				}
+				tlsCfg.RootCAs = pool
+			}
+		}
+
+		for _, u := range kc.Users {
+			if u.Name != c.Context.User {
+				continue
+			}
+
+			if u.User.AuthProvider != nil {
+				return nil, fmt.Errorf("kubeconfig user %s: auth-provider credentials are not supported, use exec credential plugin instead", u.Name)
+			}
+
+			if u.User.Exec != nil {
+				cfg.Exec, err = newExecPlugin(u.User.Exec, u.dir)
+				if err != nil {
+					return nil, fmt.Errorf("kubeconfig user %s: %w", u.Name, err)
+				}
+			}
+
+			cfg.Token = u.User.Token
+			if cfg.Token == "" && u.User.TokenFile != "" {
+				token, err := os.ReadFile(resolvePath(u.dir, u.User.TokenFile))
+				if err != nil {
+					return nil, fmt.Errorf("failed to read token file: %w", err)
+				}
+				cfg.Token = strings.TrimSpace(string(token))
+			}
+
+			cfg.Username = u.User.Username
+			cfg.Password = u.User.Password
+
+			cert, err := readDataOrFile(u.User.ClientCertificateData, resolvePath(u.dir, u.User.ClientCertificate))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read client certificate: %w", err)
+			}
+
+			key, err := readDataOrFile(u.User.ClientKeyData, resolvePath(u.dir, u.User.ClientKey))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read client key: %w", err)
+			}
+
+			if len(cert) > 0 && len(key) > 0 {
+				certificate, err := tls.X509KeyPair(cert, key)
+				if err != nil {
+					return nil, fmt.Errorf("failed to load client certificate: %w", err)
+				}
+				tlsCfg.Certificates = append(tlsCfg.Certificates, certificate)
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("kubeconfig context %q not found", kubeContext)
+	}
+
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("kubeconfig context %q has no cluster server", kubeContext)
+	}
+
+	if _, err := url.Parse(cfg.Host); err != nil {
+		return nil, fmt.Errorf("invalid cluster server: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// resolvePath resolves relative file paths against kubeconfig directory
+func resolvePath(dir, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+func inClusterConfig() (*restConfig, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running inside kubernetes cluster")
+	}
+
+	token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	cfg := &restConfig{
+		Host:      "https://" + net.JoinHostPort(host, port),
+		Namespace: defaultNamespace,
+		TLSConfig: &tls.Config{RootCAs: pool},
+		Token:     strings.TrimSpace(string(token)),
+	}
+
+	if ns, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		cfg.Namespace = strings.TrimSpace(string(ns))
+	}
+
+	return cfg, nil
+}
+
+func readDataOrFile(data, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	if file != "" {
+		return os.ReadFile(file)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,432 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// delay between failed kubernetes API requests
+const retryDelay = 5 * time.Second
+
+// NewKubernetesBuilder creates a kubernetesBuilder which is used to factory kubernetes endpoints resolvers.
+func NewKubernetesBuilder() resolver.Builder {
+	return &kubernetesBuilder{loadConfig: loadRestConfig}
+}
+
+type kubernetesBuilder struct {
+	loadConfig func(kubeContext string) (*restConfig, error)
+}
+
+// Build creates and starts a kubernetes resolver that watches EndpointSlices of the target service.
+// Supported target format is k8s://[namespace]/service[:port]?context=kube-context&portforward=true
+func (b *kubernetesBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	query := target.URL.Query()
+
+	cfg, err := b.loadConfig(query.Get("context"))
+	if err != nil {
+		return nil, fmt.Errorf("k8s: %w", err)
+	}
+
+	namespace := target.URL.Host
+	if namespace == "" {
+		namespace = cfg.Namespace
+	}
+
+	serviceName, port, _ := strings.Cut(strings.Trim(target.Endpoint(), "/"), ":")
+	if serviceName == "" {
+		return nil, fmt.Errorf("k8s: service name is missing in target %q", target.URL.String())
+	}
+
+	portForward := false
+	if val := query.Get("portforward"); val != "" {
+		portForward, err = strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("k8s: invalid portforward parameter %q: %w", val, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &kubernetesResolver{
+		Namespace:   namespace,
+		ServiceName: serviceName,
+		Port:        port,
+		ClientConn:  cc,
+		cfg:         cfg,
+		client:      cfg.httpClient(),
+		ctx:         ctx,
+		cancel:      cancel,
+		resolveNow:  make(chan struct{}, 1),
+		slices:      map[string]*endpointSlice{},
+	}
+
+	if portForward {
+		r.forwarder = newPortForwarder(cfg, namespace)
+	}
+
+	r.wg.Add(1)
+	go r.watch()
+
+	return r, nil
+}
+
+// Scheme returns the naming scheme of this resolver builder, which is "k8s".
+func (b *kubernetesBuilder) Scheme() string {
+	return "k8s"
+}
+
+type kubernetesResolver struct {
+	Namespace   string
+	ServiceName string
+	Port        string
+	ClientConn  resolver.ClientConn
+
+	cfg        *restConfig
+	client     *http.Client
+	forwarder  *portForwarder
+	ctx        context.Context
+	cancel     context.CancelFunc
+	resolveNow chan struct{}
+	wg         sync.WaitGroup
+
+	// target port selector, either port name or port number,
+	// the only EndpointSlice port is used if both are empty
+	portName     string
+	portNumber   int
+	portResolved bool
+	slices       map[string]*endpointSlice
+}
+
+type objectMeta struct {
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+type endpointSlice struct {
+	Metadata  objectMeta `json:"metadata"`
+	Endpoints []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready *bool `json:"ready"`
+		} `json:"conditions"`
+		TargetRef *struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"targetRef"`
+	} `json:"endpoints"`
+	Ports []struct {
+		Name *string `json:"name"`
+		Port *int    `json:"port"`
+	} `json:"ports"`
+}
+
+type endpointSliceList struct {
+	Metadata objectMeta       `json:"metadata"`
+	Items    []*endpointSlice `json:"items"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+type service struct {
+	Spec struct {
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"spec"`
+}
+
+// ResolveNow invokes an immediate resolution of the target that this kubernetesResolver watches.
+func (r *kubernetesResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *kubernetesResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+	if r.forwarder != nil {
+		r.forwarder.Close()
+	}
+}
+
+// watch lists service EndpointSlices and then watches them for changes,
+// the list is repeated every time the watch fails
+func (r *kubernetesResolver) watch() {
+	defer r.wg.Done()
+
+	for {
+		err := r.resolvePort()
+		if err == nil {
+			var rv string
+			rv, err = r.list()
+			if err == nil {
+				err = r.watchSlices(rv)
+			}
+		}
+
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			r.ClientConn.ReportError(err)
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.resolveNow:
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// resolvePort converts service port to EndpointSlice port selector.
+// Numeric ports are looked up in the Service spec to find the port name,
+// if the service cannot be read the number is used as pod port.
+func (r *kubernetesResolver) resolvePort() error {
+	if r.portResolved || r.Port == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(r.Port)
+	if err != nil {
+		r.portName = r.Port
+		r.portResolved = true
+		return nil
+	}
+
+	var svc service
+	if err := r.get(r.apiPath("/api/v1", "services/"+url.PathEscape(r.ServiceName)), nil, &svc); err == nil {
+		for _, p := range svc.Spec.Ports {
+			if p.Port == n {
+				// unnamed port is possible only if the service has one port,
+				// so EndpointSlices have the only port which is the target port
+				r.portName = p.Name
+				r.portResolved = true
+				return nil
+			}
+		}
+	}
+
+	r.portNumber = n
+	r.portResolved = true
+	return nil
+}
+
+func (r *kubernetesResolver) list() (string, error) {
+	var list endpointSliceList
+	q := url.Values{"labelSelector": {"kubernetes.io/service-name=" + r.ServiceName}}
+	if err := r.get(r.apiPath("/apis/discovery.k8s.io/v1", "endpointslices"), q, &list); err != nil {
+		return "", err
+	}
+
+	r.slices = map[string]*endpointSlice{}
+	for _, s := range list.Items {
+		r.slices[s.Metadata.Name] = s
+	}
+
+	r.updateState()
+	return list.Metadata.ResourceVersion, nil
+}
+
+func (r *kubernetesResolver) watchSlices(resourceVersion string) error {
+	q := url.Values{
+		"labelSelector":   {"kubernetes.io/service-name=" + r.ServiceName},
+		"watch":           {"true"},
+		"resourceVersion": {resourceVersion},
+	}
+
+	resp, err := r.do(r.apiPath("/apis/discovery.k8s.io/v1", "endpointslices"), q)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev watchEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return fmt.Errorf("k8s watch event decode error: %w", err)
+		}
+
+		switch ev.Type {
+		case "ADDED", "MODIFIED", "DELETED":
+			s := &endpointSlice{}
+			if err := json.Unmarshal(ev.Object, s); err != nil {
+				return fmt.Errorf("k8s watch event decode error: %w", err)
+			}
+
+			if ev.Type == "DELETED" {
+				delete(r.slices, s.Metadata.Name)
+			} else {
+				r.slices[s.Metadata.Name] = s
+			}
+			r.updateState()
+		case "ERROR":
+			return fmt.Errorf("k8s watch error: %s", ev.Object)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// updateState sends ready endpoints addresses to grpc
+func (r *kubernetesResolver) updateState() {
+	type podAddr struct {
+		addr string
+		pod  string
+		port int
+	}
+
+	var pods []podAddr
+	var err error
+	for _, s := range r.slices {
+		port, perr := r.slicePort(s)
+		if perr != nil {
+			err = perr
+			continue
+		}
+
+		for _, e := range s.Endpoints {
+			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+				continue
+			}
+
+			pod := ""
+			if e.TargetRef != nil && e.TargetRef.Kind == "Pod" {
+				pod = e.TargetRef.Name
+			}
+
+			for _, a := range e.Addresses {
+				pods = append(pods, podAddr{addr: net.JoinHostPort(a, strconv.Itoa(port)), pod: pod, port: port})
+			}
+		}
+	}
+
+	if len(pods) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no ready endpoints for k8s service %s/%s", r.Namespace, r.ServiceName)
+		}
+		r.ClientConn.ReportError(err)
+		return
+	}
+
+	slices.SortFunc(pods, func(a, b podAddr) int { return strings.Compare(a.addr, b.addr) })
+
+	addrs := make([]resolver.Address, 0, len(pods))
+	if r.forwarder != nil {
+		targets := make([]forwardTarget, 0, len(pods))
+		for _, p := range pods {
+			if p.pod != "" {
+				targets = append(targets, forwardTarget{pod: p.pod, port: p.port})
+			}
+		}
+
+		localAddrs, ferr := r.forwarder.Update(targets)
+		if ferr != nil {
+			r.ClientConn.ReportError(ferr)
+			return
+		}
+
+		for _, a := range localAddrs {
+			addrs = append(addrs, resolver.Address{Addr: a})
+		}
+	} else {
+		for _, p := range pods {
+			addrs = append(addrs, resolver.Address{Addr: p.addr})
+		}
+	}
+
+	// errors could be ignored here as kubernetes resolver is watch based
+	_ = r.ClientConn.UpdateState(resolver.State{Addresses: addrs})
+}
+
+func (r *kubernetesResolver) slicePort(s *endpointSlice) (int, error) {
+	if r.portNumber != 0 {
+		return r.portNumber, nil
+	}
+
+	if r.portName == "" && len(s.Ports) > 1 {
+		return 0, fmt.Errorf("k8s service %s/%s has multiple ports, please specify one", r.Namespace, r.ServiceName)
+	}
+
+	for _, p := range s.Ports {
+		name := ""
+		if p.Name != nil {
+			name = *p.Name
+		}
+
+		if (r.portName == "" || r.portName == name) && p.Port != nil {
+			return *p.Port, nil
+		}
+	}
+
+	return 0, fmt.Errorf("port %q not found in k8s service %s/%s endpoints", r.Port, r.Namespace, r.ServiceName)
+}
+
+func (r *kubernetesResolver) apiPath(prefix, resource string) string {
+	return prefix + "/namespaces/" + url.PathEscape(r.Namespace) + "/" + resource
+}
+
+func (r *kubernetesResolver) get(path string, q url.Values, v any) error {
+	resp, err := r.do(path, q)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("k8s response decode error: %w", err)
+	}
+	return nil
+}
+
+func (r *kubernetesResolver) do(path string, q url.Values) (*http.Response, error) {
+	u, err := url.Parse(r.cfg.Host)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if err := r.cfg.authorize(req.Header); err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New("k8s request " + u.Path + " failed: " + resp.Status)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,475 @@
+package kubernetes
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/resolver"
+)
+
+const testToken = "test-token"
+
+type testClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+	errs   chan error
+}
+
+func newTestClientConn() *testClientConn {
+	return &testClientConn{
+		states: make(chan resolver.State, 10),
+		errs:   make(chan error, 10),
+	}
+}
+
+func (t *testClientConn) UpdateState(s resolver.State) error {
+	t.states <- s
+	return nil
+}
+
+func (t *testClientConn) ReportError(err error) {
+	t.errs <- err
+}
+
+func (t *testClientConn) waitState(tb testing.TB) []string {
+	select {
+	case s := <-t.states:
+		addrs := []string{}
+		for _, a := range s.Addresses {
+			addrs = append(addrs, a.Addr)
+		}
+		sort.Strings(addrs)
+		return addrs
+	case err := <-t.errs:
+		tb.Fatalf("unexpected resolver error: %v", err)
+	case <-time.After(5 * time.Second):
+		tb.Fatal("timeout waiting for resolver state")
+	}
+	return nil
+}
+
+func endpointSliceJSON(name string, port int, ips ...string) string {
+	endpoints := []string{}
+	for i, ip := range ips {
+		endpoints = append(endpoints, fmt.Sprintf(`{"addresses": ["%s"], "conditions": {"ready": true}, "targetRef": {"kind": "Pod", "name": "pod-%d"}}`, ip, i))
+	}
+	endpoints = append(endpoints, `{"addresses": ["10.9.9.9"], "conditions": {"ready": false}, "targetRef": {"kind": "Pod", "name": "not-ready"}}`)
+
+	return fmt.Sprintf(`{"metadata": {"name": "%s"}, "endpoints": [%s], "ports": [{"name": "http", "port": 8080}, {"name": "grpc", "port": %d}]}`,
+		name, strings.Join(endpoints, ","), port)
+}
+
+// fakeAPIServer serves users service endpoints and sends watch events from the channel
+func fakeAPIServer(t *testing.T, events chan string, mux *http.ServeMux) *httptest.Server {
+	if mux == nil {
+		mux = http.NewServeMux()
+	}
+
+	mux.HandleFunc("/api/v1/namespaces/test/services/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"spec": {"ports": [{"name": "http", "port": 80}, {"name": "grpc", "port": 5050}]}}`))
+	})
+
+	mux.HandleFunc("/apis/discovery.k8s.io/v1/namespaces/test/endpointslices", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Query().Get("labelSelector") != "kubernetes.io/service-name=users" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Query().Get("watch") != "true" {
+			fmt.Fprintf(w, `{"metadata": {"resourceVersion": "1"}, "items": [%s]}`, endpointSliceJSON("users-1", 9090, "10.0.0.1", "10.0.0.2"))
+			return
+		}
+
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case ev := <-events:
+				fmt.Fprintln(w, ev)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testRestConfig(srv *httptest.Server) *restConfig {
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return &restConfig{
+		Host:      srv.URL,
+		Namespace: "test",
+		TLSConfig: &tls.Config{RootCAs: pool},
+		Token:     testToken,
+	}
+}
+
+func buildResolver(t *testing.T, cfg *restConfig, target string, cc resolver.ClientConn) {
+	u, err := url.Parse(target)
+	require.NoError(t, err)
+
+	b := &kubernetesBuilder{loadConfig: func(string) (*restConfig, error) { return cfg, nil }}
+	r, err := b.Build(resolver.Target{URL: *u}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+}
+
+func TestKubernetesResolverWatch(t *testing.T) {
+	events := make(chan string, 1)
+	srv := fakeAPIServer(t, events, nil)
+
+	cc := newTestClientConn()
+	buildResolver(t, testRestConfig(srv), "k8s://test/users:grpc", cc)
+
+	assert.Equal(t, []string{"10.0.0.1:9090", "10.0.0.2:9090"}, cc.waitState(t))
+
+	events <- `{"type": "ADDED", "object": ` + endpointSliceJSON("users-2", 9090, "10.0.0.3") + `}`
+	assert.Equal(t, []string{"10.0.0.1:9090", "10.0.0.2:9090", "10.0.0.3:9090"}, cc.waitState(t))
+
+	events <- `{"type": "DELETED", "object": {"metadata": {"name": "users-1"}}}`
+	assert.Equal(t, []string{"10.0.0.3:9090"}, cc.waitState(t))
+}
+
+func TestKubernetesResolverServicePort(t *testing.T) {
+	srv := fakeAPIServer(t, nil, nil)
+
+	cc := newTestClientConn()
+	// default namespace comes from the config, service port 5050 is named "grpc"
+	buildResolver(t, testRestConfig(srv), "k8s:///users:5050", cc)
+
+	assert.Equal(t, []string{"10.0.0.1:9090", "10.0.0.2:9090"}, cc.waitState(t))
+}
+
+func TestKubernetesResolverUnnamedServicePort(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/namespaces/test/services/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"spec": {"ports": [{"port": 80, "targetPort": 9090}]}}`))
+	})
+	mux.HandleFunc("/apis/discovery.k8s.io/v1/namespaces/test/endpointslices", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "true" {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte(`{"metadata": {"resourceVersion": "1"}, "items": [{"metadata": {"name": "orders-1"}, ` +
+			`"endpoints": [{"addresses": ["10.0.0.1"], "conditions": {"ready": true}}], "ports": [{"name": "", "port": 9090}]}]}`))
+	})
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+
+	cc := newTestClientConn()
+	// service port differs from the pods port
+	buildResolver(t, testRestConfig(srv), "k8s://test/orders:80", cc)
+
+	assert.Equal(t, []string{"10.0.0.1:9090"}, cc.waitState(t))
+}
+
+func TestKubernetesResolverMultiplePorts(t *testing.T) {
+	srv := fakeAPIServer(t, nil, nil)
+
+	cc := newTestClientConn()
+	buildResolver(t, testRestConfig(srv), "k8s://test/users", cc)
+
+	select {
+	case err := <-cc.errs:
+		assert.ErrorContains(t, err, "has multiple ports")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for resolver error")
+	}
+}
+
+func TestKubernetesResolverPortForward(t *testing.T) {
+	// pod stand-in that echoes everything back
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { echo.Close() })
+	go func() {
+		for {
+			c, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				io.Copy(c, c)
+			}()
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/namespaces/test/pods/{pod}/portforward", websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			if r.Header.Get("Authorization") != "Bearer "+testToken || r.URL.Query().Get("ports") != "9090" {
+				return fmt.Errorf("invalid portforward request")
+			}
+			config.Protocol = []string{portForwardProtocol}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.BinaryFrame
+			pod, err := net.Dial("tcp", echo.Addr().String())
+			if err != nil {
+				return
+			}
+			defer pod.Close()
+
+			// port prefixes for data and error channels, 9090 little endian
+			websocket.Message.Send(ws, []byte{dataChannel, 0x82, 0x23})
+			websocket.Message.Send(ws, []byte{errorChannel, 0x82, 0x23})
+
+			go func() {
+				buf := make([]byte, 1024)
+				for {
+					n, err := pod.Read(buf)
+					if err != nil {
+						return
+					}
+					websocket.Message.Send(ws, append([]byte{dataChannel}, buf[:n]...))
+				}
+			}()
+
+			for {
+				var frame []byte
+				if err := websocket.Message.Receive(ws, &frame); err != nil {
+					return
+				}
+				if len(frame) > 1 && frame[0] == dataChannel {
+					pod.Write(frame[1:])
+				}
+			}
+		},
+	})
+
+	srv := fakeAPIServer(t, nil, mux)
+
+	cc := newTestClientConn()
+	buildResolver(t, testRestConfig(srv), "k8s://test/users:grpc?portforward=true", cc)
+
+	addrs := cc.waitState(t)
+	require.Len(t, addrs, 2)
+	for _, a := range addrs {
+		assert.True(t, strings.HasPrefix(a, "127.0.0.1:"), a)
+
+		conn, err := net.Dial("tcp", a)
+		require.NoError(t, err)
+
+		_, err = conn.Write([]byte("ping\n"))
+		require.NoError(t, err)
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "ping\n", line)
+		conn.Close()
+	}
+}
+
+func TestLoadKubeconfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte(testToken+"\n"), 0o600))
+
+	kubeconfig := fmt.Sprintf(`
+apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+    insecure-skip-tls-verify: true
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+    namespace: team
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: prod-user
+users:
+- name: dev-user
+  user:
+    tokenFile: token
+- name: prod-user
+  user:
+    username: admin
+    password: secret
+`, srv.URL, base64.StdEncoding.EncodeToString(caPEM))
+
+	path := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(path, []byte(kubeconfig), 0o600))
+
+	cfg, err := loadKubeconfig([]string{path}, "")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL, cfg.Host)
+	assert.Equal(t, "team", cfg.Namespace)
+	assert.Equal(t, testToken, cfg.Token)
+	assert.NotNil(t, cfg.TLSConfig.RootCAs)
+
+	// the loaded CA must be able to verify API server certificate
+	resp, err := cfg.httpClient().Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	cfg, err = loadKubeconfig([]string{path}, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "https://prod.example.com", cfg.Host)
+	assert.Equal(t, defaultNamespace, cfg.Namespace)
+	assert.True(t, cfg.TLSConfig.InsecureSkipVerify)
+
+	h := http.Header{}
+	require.NoError(t, cfg.authorize(h))
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("admin:secret")), h.Get("Authorization"))
+
+	_, err = loadKubeconfig([]string{path}, "unknown")
+	assert.ErrorContains(t, err, `context "unknown" not found`)
+}
+
+func TestLoadKubeconfigMerge(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "users"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users", "token"), []byte(testToken), 0o600))
+
+	clusters := filepath.Join(dir, "clusters")
+	require.NoError(t, os.WriteFile(clusters, []byte(`
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+`), 0o600))
+
+	users := filepath.Join(dir, "users", "config")
+	require.NoError(t, os.WriteFile(users, []byte(`
+current-context: other
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://other.example.com
+users:
+- name: dev-user
+  user:
+    tokenFile: token
+`), 0o600))
+
+	missing := filepath.Join(dir, "missing")
+	t.Setenv("KUBECONFIG", strings.Join([]string{clusters, missing, users}, string(os.PathListSeparator)))
+
+	cfg, err := loadRestConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "https://dev.example.com", cfg.Host)
+	// relative paths are resolved against the file defining the user
+	assert.Equal(t, testToken, cfg.Token)
+
+	_, err = loadKubeconfig([]string{missing}, "")
+	assert.ErrorContains(t, err, "kubeconfig not found")
+}
+
+func TestExecPlugin(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "counter")
+	script := `echo run >> "$COUNTER"
+echo "$KUBERNETES_EXEC_INFO" > "$COUNTER.info"
+echo "{\"apiVersion\": \"client.authentication.k8s.io/v1\", \"kind\": \"ExecCredential\",
+  \"status\": {\"token\": \"$TOKEN\", \"expirationTimestamp\": \"2030-01-01T00:00:00Z\"}}"`
+
+	kubeconfig := fmt.Sprintf(`
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+users:
+- name: dev-user
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: sh
+      args: ["-c", %q]
+      env:
+      - name: TOKEN
+        value: %s
+      - name: COUNTER
+        value: %s
+`, script, testToken, counter)
+
+	path := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(path, []byte(kubeconfig), 0o600))
+
+	cfg, err := loadKubeconfig([]string{path}, "")
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Exec)
+
+	now := time.Date(2029, 12, 31, 23, 0, 0, 0, time.UTC)
+	cfg.Exec.now = func() time.Time { return now }
+
+	h := http.Header{}
+	require.NoError(t, cfg.authorize(h))
+	assert.Equal(t, "Bearer "+testToken, h.Get("Authorization"))
+
+	info, err := os.ReadFile(counter + ".info")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"apiVersion": "client.authentication.k8s.io/v1", "kind": "ExecCredential", "spec": {"interactive": false}}`, string(info))
+
+	// the token is cached until it expires
+	require.NoError(t, cfg.authorize(http.Header{}))
+	runs, err := os.ReadFile(counter)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(runs), "run"))
+
+	now = now.Add(time.Hour)
+	require.NoError(t, cfg.authorize(http.Header{}))
+	runs, err = os.ReadFile(counter)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(runs), "run"))
+
+	cfg.Exec.token = ""
+	cfg.Exec.command = "false"
+	assert.ErrorContains(t, cfg.authorize(http.Header{}), "exec credential plugin: false failed")
+}
@@ -0,0 +1,204 @@
+package kubernetes
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// kubernetes websocket streaming protocol, every frame is prefixed with channel number
+	portForwardProtocol = "v4.channel.k8s.io"
+
+	dataChannel  = 0
+	errorChannel = 1
+)
+
+type forwardTarget struct {
+	pod  string
+	port int
+}
+
+// portForwarder opens local listeners for pods and tunnels accepted connections
+// through kubernetes API server portforward subresource
+type portForwarder struct {
+	cfg       *restConfig
+	namespace string
+
+	mu        sync.Mutex
+	listeners map[forwardTarget]net.Listener
+}
+
+func newPortForwarder(cfg *restConfig, namespace string) *portForwarder {
+	return &portForwarder{
+		cfg:       cfg,
+		namespace: namespace,
+		listeners: map[forwardTarget]net.Listener{},
+	}
+}
+
+// Update makes sure every target has a local listener and closes listeners of targets that are gone,
+// it returns local listeners addresses in targets order
+func (f *portForwarder) Update(targets []forwardTarget) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	active := map[forwardTarget]struct{}{}
+	addrs := make([]string, 0, len(targets))
+	for _, t := range targets {
+		active[t] = struct{}{}
+
+		l, ok := f.listeners[t]
+		if !ok {
+			var err error
+			l, err = net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				return nil, err
+			}
+			f.listeners[t] = l
+			go f.serve(l, t)
+		}
+
+		addrs = append(addrs, l.Addr().String())
+	}
+
+	for t, l := range f.listeners {
+		if _, ok := active[t]; !ok {
+			l.Close()
+			delete(f.listeners, t)
+		}
+	}
+
+	return addrs, nil
+}
+
+func (f *portForwarder) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for t, l := range f.listeners {
+		l.Close()
+		delete(f.listeners, t)
+	}
+}
+
+func (f *portForwarder) serve(l net.Listener, t forwardTarget) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go f.forward(conn, t)
+	}
+}
+
+func (f *portForwarder) forward(local net.Conn, t forwardTarget) {
+	defer local.Close()
+
+	ws, err := f.dial(t)
+	if err != nil {
+		log.Printf("k8s port-forward to pod %s/%s failed: %v", f.namespace, t.pod, err)
+		return
+	}
+	defer ws.Close()
+
+	go func() {
+		defer local.Close()
+
+		// the first frame of every channel starts with the port number
+		prefixRead := map[byte]bool{}
+		for {
+			var frame []byte
+			if err := websocket.Message.Receive(ws, &frame); err != nil {
+				return
+			}
+
+			if len(frame) == 0 {
+				continue
+			}
+
+			ch, data := frame[0], frame[1:]
+			if !prefixRead[ch] {
+				prefixRead[ch] = true
+				if len(data) < 2 {
+					continue
+				}
+				data = data[2:]
+			}
+
+			switch ch {
+			case dataChannel:
+				if _, err := local.Write(data); err != nil {
+					return
+				}
+			case errorChannel:
+				if len(data) > 0 {
+					log.Printf("k8s port-forward to pod %s/%s error: %s", f.namespace, t.pod, data)
+					return
+				}
+			}
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := local.Read(buf)
+		if n > 0 {
+			frame := append([]byte{dataChannel}, buf[:n]...)
+			if serr := websocket.Message.Send(ws, frame); serr != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (f *portForwarder) dial(t forwardTarget) (*websocket.Conn, error) {
+	u, err := url.Parse(f.cfg.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	origin := *u
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") +
+		"/api/v1/namespaces/" + url.PathEscape(f.namespace) + "/pods/" + url.PathEscape(t.pod) + "/portforward"
+	u.RawQuery = url.Values{"ports": {strconv.Itoa(t.port)}}.Encode()
+
+	config, err := websocket.NewConfig(u.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+
+	config.Protocol = []string{portForwardProtocol}
+	config.TlsConfig = f.cfg.TLSConfig
+	config.Header = http.Header{}
+	config.Dialer = &net.Dialer{Timeout: 30 * time.Second}
+	if err := f.cfg.authorize(config.Header); err != nil {
+		return nil, err
+	}
+
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// execCredential is the output expected from auth exec command, for example:
+//
+//	{"headers": {"authorization": "Bearer token", "x-tenant": ["a", "b"]}, "expirationTimestamp": "2025-01-01T00:00:00Z"}
+//
+// kubectl credential plugins output with status.token is supported as well.
+type execCredential struct {
+	Headers             map[string]execHeaderValue `json:"headers"`
+	ExpirationTimestamp *time.Time                 `json:"expirationTimestamp"`
+	Status              *struct {
+		Token               string     `json:"token"`
+		ExpirationTimestamp *time.Time `json:"expirationTimestamp"`
+	} `json:"status"`
+}
+
+// execHeaderValue is either a single string or an array of strings
+type execHeaderValue []string
+
+func (v *execHeaderValue) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = []string{s}
+		return nil
+	}
+
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return errors.New("header value must be a string or an array of strings")
+	}
+
+	*v = arr
+	return nil
+}
+
+// default time limit of auth exec command, long enough for browser based SSO logins
+const defaultAuthExecTimeout = 5 * time.Minute
+
+// authExec runs external command to get request headers,
+// the result is cached until it expires
+type authExec struct {
+	command []string
+	timeout time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	cached  bool
+	headers map[string][]string
+	expiry  time.Time
+}
+
+func newAuthExec(command []string) *authExec {
+	return &authExec{
+		command: command,
+		timeout: defaultAuthExecTimeout,
+		now:     time.Now,
+	}
+}
+
+func (a *authExec) getHeaders(ctx context.Context) (map[string][]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cached && (a.expiry.IsZero() || a.now().Add(tokenExpiryDelta).Before(a.expiry)) {
+		return a.headers, nil
+	}
+
+	// the command has its own timeout, otherwise a short call deadline
+	// would kill it, e.g. while the user logs in
+	runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.timeout)
+	defer cancel()
+
+	headers, expiry, err := a.run(runCtx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "auth exec: %v", err)
+	}
+
+	a.headers = headers
+	a.expiry = expiry
+	a.cached = true
+	return headers, nil
+}
+
+func (a *authExec) run(ctx context.Context) (map[string][]string, time.Time, error) {
+	if len(a.command) == 0 {
+		return nil, time.Time{}, errors.New("command is empty")
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, a.command[0], a.command[1:]...)
+	cmd.Stdout = &stdout
+	// stdin is not passed, in interactive mode the command would compete with the prompt for the input,
+	// stderr is shown so the command can print instructions, e.g. SSO login URL
+	cmd.Stderr = os.Stderr
+	// don't wait for output of child processes that keep running after the command is killed
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s failed: %w", a.command[0], err)
+	}
+
+	var cred execCredential
+	if err := json.Unmarshal(stdout.Bytes(), &cred); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid %s output: %w", a.command[0], err)
+	}
+
+	headers := map[string][]string{}
+	for k, v := range cred.Headers {
+		k = strings.ToLower(k)
+		headers[k] = append(headers[k], v...)
+	}
+
+	var expiry time.Time
+	if cred.ExpirationTimestamp != nil {
+		expiry = *cred.ExpirationTimestamp
+	}
+
+	if cred.Status != nil {
+		if cred.Status.Token != "" {
+			headers["authorization"] = append(headers["authorization"], "Bearer "+cred.Status.Token)
+		}
+
+		if cred.Status.ExpirationTimestamp != nil {
+			expiry = *cred.Status.ExpirationTimestamp
+		}
+	}
+
+	if len(headers) == 0 {
+		return nil, time.Time{}, fmt.Errorf("%s returned no headers", a.command[0])
+	}
+
+	return headers, expiry, nil
+}
+
+func authExecUnaryInterceptor(a *authExec) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		md, err := a.getHeaders(ctx)
+		if err != nil {
+			return err
+		}
+		newCtx := appendMetadata(ctx, md)
+		return invoker(newCtx, method, req, reply, cc, opts...)
+	}
+}
+
+func authExecStreamInterceptor(a *authExec) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		md, err := a.getHeaders(ctx)
+		if err != nil {
+			return nil, err
+		}
+		newCtx := appendMetadata(ctx, md)
+		return streamer(newCtx, desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// shellCommand returns a command that prints output and appends a line to the counter file on every run
+func shellCommand(t *testing.T, output string) ([]string, func() int) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	script := fmt.Sprintf("echo run >> %s; echo '%s'", counter, output)
+	return []string{"sh", "-c", script}, func() int {
+		b, _ := os.ReadFile(counter)
+		return strings.Count(string(b), "run")
+	}
+}
+
+func TestAuthExecHeaders(t *testing.T) {
+	command, runs := shellCommand(t, `{"headers": {"Authorization": "Bearer token1", "x-tenant": ["a", "b"]}}`)
+	a := newAuthExec(command)
+
+	headers, err := a.getHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token1"}, headers["authorization"])
+	assert.Equal(t, []string{"a", "b"}, headers["x-tenant"])
+
+	// no expiration, the headers are cached for the whole session
+	_, err = a.getHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, runs())
+}
+
+func TestAuthExecExpiration(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	command, runs := shellCommand(t, `{"headers": {"authorization": "Bearer token1"}, "expirationTimestamp": "2025-01-01T01:00:00Z"}`)
+	a := newAuthExec(command)
+	a.now = func() time.Time { return now }
+
+	_, err := a.getHeaders(context.Background())
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Minute)
+	_, err = a.getHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, runs())
+
+	// expired, the command is invoked again
+	now = now.Add(30 * time.Minute)
+	_, err = a.getHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs())
+}
+
+func TestAuthExecKubectlFormat(t *testing.T) {
+	command, _ := shellCommand(t, `{"kind": "ExecCredential", "status": {"token": "token1", "expirationTimestamp": "2099-01-01T00:00:00Z"}}`)
+	a := newAuthExec(command)
+
+	headers, err := a.getHeaders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token1"}, headers["authorization"])
+}
+
+func TestAuthExecErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		err     string
+	}{
+		{"Failed", []string{"sh", "-c", "exit 1"}, "sh failed"},
+		{"InvalidJSON", []string{"sh", "-c", "echo not-json"}, "invalid sh output"},
+		{"NoHeaders", []string{"sh", "-c", "echo '{}'"}, "sh returned no headers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAuthExec(tt.command)
+			_, err := a.getHeaders(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestAuthExecIgnoresCallDeadline(t *testing.T) {
+	a := newAuthExec([]string{"sh", "-c", `sleep 0.2; echo '{"headers": {"authorization": "Bearer token1"}}'`})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	headers, err := a.getHeaders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token1"}, headers["authorization"])
+}
+
+func TestAuthExecTimeout(t *testing.T) {
+	a := newAuthExec([]string{"sh", "-c", "exec sleep 5"})
+	a.timeout = 50 * time.Millisecond
+
+	_, err := a.getHeaders(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // register gzip compressor
+)
+
+// Compressors contains the names of supported compressors, none disables compression
+var Compressors = []string{"none", "gzip", "zstd", "snappy"}
+
+func init() {
+	encoding.RegisterCompressor(&zstdCompressor{})
+	encoding.RegisterCompressor(&snappyCompressor{})
+}
+
+type zstdCompressor struct{}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	// single threaded decoder doesn't start background goroutines, so it's safe not to close it
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+func (c *zstdCompressor) Name() string {
+	return "zstd"
+}
+
+type snappyCompressor struct{}
+
+func (c *snappyCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+func (c *snappyCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return snappy.NewReader(r), nil
+}
+
+func (c *snappyCompressor) Name() string {
+	return "snappy"
+}
@@ -0,0 +1,37 @@
+package rpc
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCompressors(t *testing.T) {
+	data := []byte(strings.Repeat("grpc-client-cli ", 100))
+
+	for _, name := range Compressors[1:] {
+		t.Run(name, func(t *testing.T) {
+			c := encoding.GetCompressor(name)
+			require.NotNil(t, c, "compressor is not registered")
+
+			buf := &bytes.Buffer{}
+			w, err := c.Compress(buf)
+			require.NoError(t, err)
+			_, err = w.Write(data)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			assert.Less(t, buf.Len(), len(data))
+
+			r, err := c.Decompress(buf)
+			require.NoError(t, err)
+			res, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, data, res)
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+// Connect protocol is described in https://connectrpc.com/docs/protocol
+const (
+	ConnectCodecProto = "proto"
+	ConnectCodecJSON  = "json"
+
+	connectProtocolVersion = "1"
+	endStreamFlag          = 0x02
+)
+
+// ConnectCodecs lists supported message encodings of the Connect protocol
+var ConnectCodecs = []string{ConnectCodecProto, ConnectCodecJSON}
+
+var connectCodes = map[string]codes.Code{
+	"canceled":            codes.Canceled,
+	"unknown":             codes.Unknown,
+	"invalid_argument":    codes.InvalidArgument,
+	"deadline_exceeded":   codes.DeadlineExceeded,
+	"not_found":           codes.NotFound,
+	"already_exists":      codes.AlreadyExists,
+	"permission_denied":   codes.PermissionDenied,
+	"resource_exhausted":  codes.ResourceExhausted,
+	"failed_precondition": codes.FailedPrecondition,
+	"aborted":             codes.Aborted,
+	"out_of_range":        codes.OutOfRange,
+	"unimplemented":       codes.Unimplemented,
+	"internal":            codes.Internal,
+	"unavailable":         codes.Unavailable,
+	"data_loss":           codes.DataLoss,
+	"unauthenticated":     codes.Unauthenticated,
+}
+
+type connectCodec struct {
+	name      string
+	marshal   func(proto.Message) ([]byte, error)
+	unmarshal func([]byte, proto.Message) error
+}
+
+func newConnectCodec(name string) *connectCodec {
+	if name == ConnectCodecJSON {
+		// messages from reflection are registered in global files, Any fields are resolved using them
+		types := dynamicpb.NewTypes(protoregistry.GlobalFiles)
+		return &connectCodec{
+			name:      ConnectCodecJSON,
+			marshal:   protojson.MarshalOptions{Resolver: types}.Marshal,
+			unmarshal: protojson.UnmarshalOptions{Resolver: types, DiscardUnknown: true}.Unmarshal,
+		}
+	}
+
+	return &connectCodec{
+		name:      ConnectCodecProto,
+		marshal:   proto.Marshal,
+		unmarshal: proto.Unmarshal,
+	}
+}
+
+func newConnectStream(codecName string) httpStreamFunc {
+	return func(ctx context.Context, c *httpConn, desc *grpc.StreamDesc, method string) (grpc.ClientStream, error) {
+		s := &connectStream{
+			httpStream: newHTTPStream(ctx, c, method, ProtocolConnect),
+			unary:      !desc.ClientStreams && !desc.ServerStreams,
+			single:     !desc.ClientStreams,
+			codec:      newConnectCodec(codecName),
+		}
+		s.send = s.do
+
+		return s, nil
+	}
+}
+
+// connectStream sends the request when CloseSend is called, so client and bidi streaming
+// calls are half-duplex: all request messages are sent before responses are read
+type connectStream struct {
+	*httpStream
+	unary bool
+	// single is true if the method accepts a single request message
+	single bool
+	codec  *connectCodec
+
+	req  []byte
+	sent int
+	// trailer of unary calls is sent in headers
+	respTrailer metadata.MD
+	// unaryResp is the response message of unary calls
+	unaryResp []byte
+	body      *bufio.Reader
+
+	// unaryRead is used by RecvMsg only
+	unaryRead bool
+}
+
+func (s *connectStream) SendMsg(m any) error {
+	if s.single && s.sent > 0 {
+		return status.Error(codes.Internal, "connect: only a single request message is supported")
+	}
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "connect: unsupported message type %T", m)
+	}
+
+	b, err := s.codec.marshal(msg)
+	if err != nil {
+		return status.Errorf(codes.Internal, "connect: failed to marshal request: %v", err)
+	}
+
+	wireLength := len(b)
+	if s.unary {
+		s.req = b
+	} else {
+		s.req = appendFrame(s.req, 0, b)
+		wireLength += frameHeaderSize
+	}
+	s.sent++
+
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.OutPayload{
+			Client:           true,
+			Length:           len(b),
+			CompressedLength: len(b),
+			WireLength:       wireLength,
+			SentTime:         time.Now(),
+		})
+	})
+
+	return nil
+}
+
+func (s *connectStream) RecvMsg(m any) error {
+	if err := s.waitResponse(); err != nil {
+		return err
+	}
+
+	if s.err != nil {
+		return s.finish(s.respTrailer, status.Convert(s.err))
+	}
+
+	var payload []byte
+	if s.unary {
+		if s.unaryRead {
+			return s.finish(s.respTrailer, status.New(codes.OK, ""))
+		}
+		s.unaryRead = true
+		payload = s.unaryResp
+	} else {
+		flags, p, err := readFrame(s.body, s.conn.maxRecvMsgSize)
+		if err != nil {
+			if err == io.EOF {
+				return s.finish(nil, status.New(codes.Internal, "connect: server closed the stream without end of stream message"))
+			}
+			return s.finish(nil, s.readError(err))
+		}
+
+		if flags&endStreamFlag != 0 {
+			trailer, st := parseEndStream(p)
+			return s.finish(trailer, st)
+		}
+
+		if flags&compressedFrameFlag != 0 {
+			return s.finish(nil, status.New(codes.Internal, "connect: compressed messages are not supported"))
+		}
+		payload = p
+	}
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return s.finish(nil, status.Newf(codes.Internal, "connect: unsupported message type %T", m))
+	}
+
+	if err := s.codec.unmarshal(payload, msg); err != nil {
+		return s.finish(nil, status.Newf(codes.Internal, "connect: failed to unmarshal response: %v", err))
+	}
+
+	wireLength := len(payload)
+	if !s.unary {
+		wireLength += frameHeaderSize
+	}
+
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.InPayload{
+			Client:           true,
+			Length:           len(payload),
+			CompressedLength: len(payload),
+			WireLength:       wireLength,
+			RecvTime:         time.Now(),
+		})
+	})
+
+	return nil
+}
+
+func (s *connectStream) do() {
+	contentType := "application/" + s.codec.name
+	if !s.unary {
+		contentType = "application/connect+" + s.codec.name
+	}
+
+	req, err := s.conn.newRequest(s.ctx, s.method, s.req)
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Connect-Protocol-Version", connectProtocolVersion)
+	if deadline, ok := s.ctx.Deadline(); ok {
+		req.Header.Set("Connect-Timeout-Ms", strconv.FormatInt(max(time.Until(deadline).Milliseconds(), 1), 10))
+	}
+
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.OutHeader{Client: true, Header: headersToMetadata(req.Header), FullMethod: s.method})
+	})
+
+	resp, err := s.conn.client.Do(req)
+	if err != nil {
+		s.err = s.readError(err).Err()
+		return
+	}
+
+	if s.unary {
+		s.header, s.respTrailer = splitTrailers(resp.Header, "Trailer-")
+	} else {
+		s.header = headersToMetadata(resp.Header)
+	}
+	recordHTTPResponse(s.ctx, s.method, s.header, resp)
+
+	if s.unary {
+		defer resp.Body.Close()
+	} else {
+		s.closer = resp.Body
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		s.err = s.errorFromResponse(resp).Err()
+		return
+	}
+
+	respType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(respType, contentType) {
+		s.err = status.Errorf(codes.Unknown, "connect: unexpected content type %q", respType)
+		return
+	}
+
+	if !s.unary {
+		s.body = bufio.NewReader(resp.Body)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(s.conn.maxRecvMsgSize)+1))
+	if err != nil {
+		s.err = s.readError(err).Err()
+		return
+	}
+
+	if len(body) > s.conn.maxRecvMsgSize {
+		s.err = status.Errorf(codes.ResourceExhausted, "received message larger than max (%d)", s.conn.maxRecvMsgSize)
+		return
+	}
+	s.unaryResp = body
+}
+
+// errorFromResponse decodes Connect error JSON, the code is derived from HTTP status
+// if the response is not a Connect error, e.g. it's returned by a proxy
+func (s *connectStream) errorFromResponse(resp *http.Response) *status.Status {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(s.conn.maxRecvMsgSize)))
+	if err == nil && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		cerr := &connectError{}
+		if err := json.Unmarshal(body, cerr); err == nil && cerr.Code != "" {
+			return cerr.status()
+		}
+	}
+
+	return status.Newf(httpStatusToCode(resp.StatusCode), "connect: unexpected HTTP status %s", resp.Status)
+}
+
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message"`
+	Details []connectErrorDetail `json:"details"`
+}
+
+type connectErrorDetail struct {
+	// Type is fully qualified message name without type.googleapis.com/ prefix
+	Type string `json:"type"`
+	// Value is base64 encoded message, padding is optional
+	Value string `json:"value"`
+}
+
+// status converts Connect error to grpc status, details are kept as Any messages
+func (e *connectError) status() *status.Status {
+	code, ok := connectCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	st := &spb.Status{Code: int32(code), Message: e.Message}
+	for _, d := range e.Details {
+		value, err := decodeBinHeader(d.Value)
+		if err != nil {
+			continue
+		}
+		st.Details = append(st.Details, &anypb.Any{TypeUrl: "type.googleapis.com/" + d.Type, Value: value})
+	}
+
+	return status.FromProto(st)
+}
+
+type connectEndStream struct {
+	Error    *connectError       `json:"error"`
+	Metadata map[string][]string `json:"metadata"`
+}
+
+// parseEndStream parses end of stream message that contains the call status and trailers
+func parseEndStream(b []byte) (metadata.MD, *status.Status) {
+	end := &connectEndStream{}
+	if err := json.Unmarshal(b, end); err != nil {
+		return nil, status.Newf(codes.Internal, "connect: invalid end of stream message: %v", err)
+	}
+
+	h := http.Header{}
+	for k, values := range end.Metadata {
+		for _, v := range values {
+			h.Add(k, v)
+		}
+	}
+	trailer := headersToMetadata(h)
+
+	if end.Error != nil {
+		return trailer, end.Error.status()
+	}
+	return trailer, status.New(codes.OK, "")
+}
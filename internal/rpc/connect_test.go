@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseEndStream(t *testing.T) {
+	info, err := proto.Marshal(&errdetails.ErrorInfo{Reason: "QUOTA", Domain: "example.com"})
+	require.NoError(t, err)
+
+	// connect encodes details without padding
+	end := `{
+  "error": {
+    "code": "resource_exhausted",
+    "message": "quota exceeded",
+    "details": [{"type": "google.rpc.ErrorInfo", "value": "` + base64.RawStdEncoding.EncodeToString(info) + `"}]
+  },
+  "metadata": {"x-request-id": ["1"], "x-trace-bin": ["` + base64.StdEncoding.EncodeToString([]byte("trace")) + `"]}
+}`
+
+	trailer, st := parseEndStream([]byte(end))
+	assert.Equal(t, []string{"1"}, trailer.Get("x-request-id"))
+	assert.Equal(t, []string{"trace"}, trailer.Get("x-trace-bin"))
+
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Equal(t, "quota exceeded", st.Message())
+	require.Len(t, st.Details(), 1)
+	detail, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "QUOTA", detail.Reason)
+}
+
+func TestParseEndStreamOK(t *testing.T) {
+	trailer, st := parseEndStream([]byte(`{}`))
+	assert.Empty(t, trailer)
+	assert.Equal(t, codes.OK, st.Code())
+
+	_, st = parseEndStream([]byte(`{"error": {"code": "new_code"}}`))
+	assert.Equal(t, codes.Unknown, st.Code())
+}
+
+func TestSplitTrailers(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "application/proto")
+	h.Set("X-Request-Id", "1")
+	h.Set("Trailer-X-Checksum", "abc")
+
+	header, trailer := splitTrailers(h, "Trailer-")
+	assert.Equal(t, []string{"1"}, header.Get("x-request-id"))
+	assert.Empty(t, header.Get("trailer-x-checksum"))
+	assert.Equal(t, []string{"abc"}, trailer.Get("x-checksum"))
+}
@@ -33,22 +33,24 @@ func NewConnectionOpts(target string) (*ConnectionOptions, error) {
 	tokens := strings.SplitSeq(target, ",")
 	for token := range tokens {
 		opt := strings.TrimSpace(token)
-		elements := strings.SplitN(opt, "=", 2)
-		if len(elements) > 1 {
-			key := strings.TrimSpace(elements[0])
-			value := strings.TrimSpace(elements[1])
-
-			switch key {
-			case hostOpt:
-				opts.Host = value
-			case authorityOpt:
-				opts.Authority = value
-			case metadataOpt:
-				k, v := parseMetadata(value)
-				opts.addMetadata(k, v)
-			}
-		} else {
+		key, value, found := strings.Cut(opt, "=")
+		key = strings.TrimSpace(key)
+		// only known options are parsed, the rest is the host
+		// as targets can have query parameters, e.g. consul://localhost:8500/app?tag=grpc
+		if !found || (key != hostOpt && key != authorityOpt && key != metadataOpt) {
 			opts.Host = opt
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		switch key {
+		case hostOpt:
+			opts.Host = value
+		case authorityOpt:
+			opts.Authority = value
+		case metadataOpt:
+			k, v := parseMetadata(value)
+			opts.addMetadata(k, v)
 		}
 	}
 
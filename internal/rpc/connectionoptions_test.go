@@ -54,3 +54,23 @@ func TestConnectionOptionsParseMetadata(t *testing.T) {
 		assert.Equal(t, test.expected, val)
 	}
 }
+
+func TestConnectionOptionsParseTargetQuery(t *testing.T) {
+	tests := []struct {
+		input     string
+		host      string
+		authority string
+	}{
+		{"consul://consul.example.com:8500/service-name?tag=grpc", "consul://consul.example.com:8500/service-name?tag=grpc", ""},
+		{"k8s://default/user-service:grpc?context=staging&portforward=true,authority=users", "k8s://default/user-service:grpc?context=staging&portforward=true", "users"},
+		{"host=consul://localhost/users?dc=dc1", "consul://localhost/users?dc=dc1", ""},
+	}
+
+	for _, test := range tests {
+		opts, err := NewConnectionOpts(test.input)
+		require.NoError(t, err)
+
+		assert.Equal(t, test.host, opts.Host)
+		assert.Equal(t, test.authority, opts.Authority)
+	}
+}
@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/resolver"
+)
+
+// ConnTiming describes how long it took to establish the connection used by the call
+type ConnTiming struct {
+	// Resolve is the time of the first name resolution of the target,
+	// it's nil if the target is not resolved with dns resolver
+	Resolve *time.Duration
+	// Connect is TCP connect time, it's nil if the connection goes through HTTP proxy
+	Connect *time.Duration
+	// TLSHandshake is 0 for insecure connections
+	TLSHandshake time.Duration
+}
+
+// connTimer collects timings of the connection phases, round robin connections
+// are established to every resolved address, the latest one is reported
+type connTimer struct {
+	mu     sync.Mutex
+	timing ConnTiming
+	ok     bool
+}
+
+func (t *connTimer) record(f func(*ConnTiming)) {
+	t.mu.Lock()
+	f(&t.timing)
+	t.ok = true
+	t.mu.Unlock()
+}
+
+// take returns timing of the connection established since the previous call,
+// so calls reusing the connection don't report it again
+func (t *connTimer) take() *ConnTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.ok {
+		return nil
+	}
+
+	timing := t.timing
+	t.timing = ConnTiming{}
+	t.ok = false
+	return &timing
+}
+
+// dialOptions returns dial options measuring name resolution and TCP connect time,
+// custom dialer doesn't support HTTP CONNECT proxy, so connect time is not measured if a proxy is configured
+func (t *connTimer) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithResolvers(&timingResolverBuilder{Builder: resolver.Get("dns"), timer: t}),
+	}
+
+	if httpproxy.FromEnvironment().HTTPSProxy == "" {
+		opts = append(opts, grpc.WithContextDialer(t.dial))
+	}
+
+	return opts
+}
+
+// wrapCreds measures TLS handshake time of the transport credentials
+func (t *connTimer) wrapCreds(creds credentials.TransportCredentials) credentials.TransportCredentials {
+	return &timingCreds{TransportCredentials: creds, timer: t}
+}
+
+func (t *connTimer) dial(ctx context.Context, addr string) (net.Conn, error) {
+	// unix targets are passed to custom dialers with the scheme, abstract unix sockets start with \x00
+	network := "tcp"
+	if rest, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network = "unix"
+		addr = strings.TrimPrefix(rest, "//")
+	} else if strings.HasPrefix(addr, "\x00") {
+		network = "unix"
+	}
+
+	start := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	connect := time.Since(start)
+	t.record(func(ct *ConnTiming) {
+		ct.Connect = &connect
+	})
+	return conn, nil
+}
+
+type timingCreds struct {
+	credentials.TransportCredentials
+	timer *connTimer
+}
+
+func (c *timingCreds) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	start := time.Now()
+	conn, authInfo, err := c.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.timer.record(func(ct *ConnTiming) {
+		ct.TLSHandshake = time.Since(start)
+	})
+	return conn, authInfo, nil
+}
+
+func (c *timingCreds) Clone() credentials.TransportCredentials {
+	return &timingCreds{TransportCredentials: c.TransportCredentials.Clone(), timer: c.timer}
+}
+
+// timingResolverBuilder measures the time from the resolver start to the first resolved state
+type timingResolverBuilder struct {
+	resolver.Builder
+	timer *connTimer
+}
+
+func (b *timingResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	return b.Builder.Build(target, &timingClientConn{ClientConn: cc, start: time.Now(), timer: b.timer}, opts)
+}
+
+type timingClientConn struct {
+	resolver.ClientConn
+	start time.Time
+	timer *connTimer
+	once  sync.Once
+}
+
+func (c *timingClientConn) UpdateState(s resolver.State) error {
+	c.once.Do(func() {
+		resolve := time.Since(c.start)
+		c.timer.record(func(ct *ConnTiming) {
+			ct.Resolve = &resolve
+		})
+	})
+	return c.ClientConn.UpdateState(s)
+}
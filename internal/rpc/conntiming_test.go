@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func connectWithTiming(t *testing.T, target string, timer *connTimer) {
+	opts := append(timer.dialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(target, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn.Connect()
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		require.True(t, conn.WaitForStateChange(ctx, state))
+	}
+}
+
+func TestConnTiming(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	go s.Serve(lis)
+	defer s.Stop()
+
+	t.Run("DNS", func(t *testing.T) {
+		timer := &connTimer{}
+		require.Nil(t, timer.take())
+
+		connectWithTiming(t, lis.Addr().String(), timer)
+
+		timing := timer.take()
+		require.NotNil(t, timing)
+		require.NotNil(t, timing.Resolve)
+		assert.Positive(t, *timing.Resolve)
+		require.NotNil(t, timing.Connect)
+		assert.Positive(t, *timing.Connect)
+		assert.Zero(t, timing.TLSHandshake)
+
+		// calls reusing the connection don't get its timing
+		assert.Nil(t, timer.take())
+	})
+
+	t.Run("Passthrough", func(t *testing.T) {
+		timer := &connTimer{}
+		connectWithTiming(t, "passthrough:///"+lis.Addr().String(), timer)
+
+		timing := timer.take()
+		require.NotNil(t, timing)
+		assert.Nil(t, timing.Resolve)
+		assert.NotNil(t, timing.Connect)
+	})
+
+	t.Run("Proxy", func(t *testing.T) {
+		t.Setenv("HTTPS_PROXY", "http://proxy.invalid:3128")
+
+		// custom dialer would bypass the proxy, so it's not used
+		timer := &connTimer{}
+		assert.Len(t, timer.dialOptions(), 1)
+	})
+}
@@ -1,21 +1,19 @@
 package rpc
 
 import (
-	"crypto/tls"
-	"crypto/x509"
+	"context"
 	"errors"
-	"fmt"
 	"log"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/vadimi/grpc-client-cli/internal/resolver/consul"
 	"github.com/vadimi/grpc-client-cli/internal/resolver/eureka"
+	"github.com/vadimi/grpc-client-cli/internal/resolver/kubernetes"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	_ "google.golang.org/grpc/encoding/gzip" // register gzip compressor
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/resolver"
@@ -29,6 +27,9 @@ func init() {
 	// TODO: remove that line when dns is default resolver
 	resolver.SetDefaultScheme("dns")
 	resolver.Register(eureka.NewEurekaBuilder())
+	resolver.Register(eureka.NewSecureEurekaBuilder())
+	resolver.Register(consul.NewConsulBuilder())
+	resolver.Register(kubernetes.NewKubernetesBuilder())
 }
 
 type connMeta struct {
@@ -48,13 +49,29 @@ type GrpcConnFactorySettings struct {
 	keepalive      bool
 	keepaliveTime  time.Duration
 	maxRecvMsgSize int
+	perRPCCreds    []credentials.PerRPCCredentials
+	authExec       *authExec
+	jwt            *JWTConfig
+	tlsOpts        *TLSOptions
+	compressor     string
+	retryPolicy    *RetryPolicy
+	connTiming     bool
+	tracing        *TracingConfig
+	protocol       string
+	connectCodec   string
 }
 
 type GrpcConnFactory struct {
 	settings *GrpcConnFactorySettings
 	conns    struct {
 		sync.Mutex
-		cache map[string]*connMeta
+		cache     map[string]*connMeta
+		httpCache map[string]*httpConnMeta
+	}
+	tracing struct {
+		sync.Once
+		t   *tracing
+		err error
 	}
 }
 
@@ -98,6 +115,78 @@ func WithMaxRecvMsgSize(messageSize int) ConnFactoryOption {
 	}
 }
 
+// WithCompressor compresses requests using the registered compressor, e.g. gzip, zstd or snappy
+func WithCompressor(name string) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		if name != "none" {
+			s.compressor = name
+		}
+	}
+}
+
+// WithRetry retries failed calls according to the policy, it's disabled if MaxAttempts is less than 2
+func WithRetry(p *RetryPolicy) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		if p.MaxAttempts > 1 {
+			s.retryPolicy = p
+		}
+	}
+}
+
+// WithConnTiming measures name resolution, TCP connect and TLS handshake time of connections,
+// the timing is available in call stats
+func WithConnTiming() ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		s.connTiming = true
+	}
+}
+
+// WithTracing creates OpenTelemetry client span for every call and propagates
+// W3C trace context and baggage to the server
+func WithTracing(cfg *TracingConfig) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		s.tracing = cfg
+	}
+}
+
+// WithProtocol sets the protocol used for service calls, reflection calls always use gRPC
+func WithProtocol(protocol string) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		if protocol != ProtocolGRPC {
+			s.protocol = protocol
+		}
+	}
+}
+
+// WithConnectCodec sets message encoding of the Connect protocol, proto or json
+func WithConnectCodec(codec string) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		s.connectCodec = codec
+	}
+}
+
+// WithOAuth2 adds authorization header with the token obtained from OAuth2 token endpoint to every call
+func WithOAuth2(cfg *OAuth2Config) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		s.perRPCCreds = append(s.perRPCCreds, newOAuth2Creds(cfg))
+	}
+}
+
+// WithAuthExec runs the command to get headers for every call, the headers are cached until they expire
+func WithAuthExec(command []string) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		s.authExec = newAuthExec(command)
+	}
+}
+
+// WithJWT adds authorization header with self-signed JWT token to every call,
+// the key is loaded and tokens are minted when the connection is created
+func WithJWT(cfg *JWTConfig) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		s.jwt = cfg
+	}
+}
+
 func NewGrpcConnFactory(opts ...ConnFactoryOption) *GrpcConnFactory {
 	settings := &GrpcConnFactorySettings{}
 
@@ -109,6 +198,7 @@ func NewGrpcConnFactory(opts ...ConnFactoryOption) *GrpcConnFactory {
 		settings: settings,
 	}
 	f.conns.cache = map[string]*connMeta{}
+	f.conns.httpCache = map[string]*httpConnMeta{}
 	return f
 }
 
@@ -118,6 +208,26 @@ func (f *GrpcConnFactory) GetConn(target string, opts ...grpc.DialOption) (*grpc
 	})
 }
 
+// GetCallConn returns connection for service calls, it's HTTP based connection
+// if the protocol other than gRPC is configured
+func (f *GrpcConnFactory) GetCallConn(target string) (grpc.ClientConnInterface, error) {
+	if f.settings.protocol == "" {
+		return f.GetConn(target)
+	}
+
+	f.conns.Lock()
+	defer f.conns.Unlock()
+
+	conn, ok := f.conns.httpCache[target]
+	if !ok {
+		conn = &httpConnMeta{}
+		conn.conn, conn.err = f.newHTTPConn(target)
+		f.conns.httpCache[target] = conn
+	}
+
+	return conn.conn, conn.err
+}
+
 func (f *GrpcConnFactory) getConn(target string, dial dialFunc, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	connOpts, err := NewConnectionOpts(target)
 	if err != nil {
@@ -132,10 +242,15 @@ func (f *GrpcConnFactory) getConn(target string, dial dialFunc, opts ...grpc.Dia
 	f.conns.Unlock()
 
 	conn.Do(func() {
+		var timer *connTimer
+		if f.settings.connTiming {
+			timer = &connTimer{}
+		}
+
 		opts := append(opts,
 			grpc.WithDisableServiceConfig(),
 			grpc.WithDefaultServiceConfig(loadBalancer),
-			grpc.WithStatsHandler(newStatsHanler()),
+			grpc.WithStatsHandler(newStatsHanler(timer)),
 		)
 
 		authority := connOpts.Authority
@@ -152,14 +267,36 @@ func (f *GrpcConnFactory) getConn(target string, dial dialFunc, opts ...grpc.Dia
 
 		creds := insecure.NewCredentials()
 		if f.settings.tls {
-			creds, err = getCredentials(f.settings.insecure, f.settings.caCert, f.settings.cert, f.settings.certKey)
+			creds, err = getCredentials(f.settings)
 			if err != nil {
 				conn.dialErr = err
 				return
 			}
+
+			// eureka instances expose TLS endpoints on the secure port
+			opts = append(opts, grpc.WithResolvers(
+				eureka.NewEurekaBuilder(eureka.WithSecurePort(true)),
+				eureka.NewSecureEurekaBuilder(eureka.WithSecurePort(true)),
+			))
+		}
+
+		if timer != nil {
+			opts = append(opts, timer.dialOptions()...)
+			if f.settings.tls {
+				creds = timer.wrapCreds(creds)
+			}
 		}
 		opts = append(opts, grpc.WithTransportCredentials(creds))
 
+		callCreds, err := f.callCredentials(connOpts.Metadata)
+		if err != nil {
+			conn.dialErr = err
+			return
+		}
+		for _, c := range callCreds {
+			opts = append(opts, grpc.WithPerRPCCredentials(c))
+		}
+
 		if f.settings.keepalive {
 			ka := keepalive.ClientParameters{
 				PermitWithoutStream: true,
@@ -176,19 +313,14 @@ func (f *GrpcConnFactory) getConn(target string, dial dialFunc, opts ...grpc.Dia
 			opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(f.settings.maxRecvMsgSize)))
 		}
 
-		unaryInterceptors := []grpc.UnaryClientInterceptor{}
-		streamInterceptors := []grpc.StreamClientInterceptor{}
-
-		md := f.metadata(connOpts.Metadata)
-
-		if len(md) > 0 {
-			unaryInterceptors = append(unaryInterceptors,
-				MetadataUnaryInterceptor(md),
-			)
+		if f.settings.compressor != "" {
+			opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(f.settings.compressor)))
+		}
 
-			streamInterceptors = append(streamInterceptors,
-				MetadataStreamInterceptor(md),
-			)
+		unaryInterceptors, streamInterceptors, err := f.interceptors(connOpts.Metadata)
+		if err != nil {
+			conn.dialErr = err
+			return
 		}
 
 		opts = append(opts,
@@ -204,18 +336,119 @@ func (f *GrpcConnFactory) getConn(target string, dial dialFunc, opts ...grpc.Dia
 	return conn.conn, conn.dialErr
 }
 
+// callCredentials returns per-RPC credentials added to every call,
+// connMd is metadata from the target connection string
+func (f *GrpcConnFactory) callCredentials(connMd map[string][]string) ([]credentials.PerRPCCredentials, error) {
+	creds := append([]credentials.PerRPCCredentials{}, f.settings.perRPCCreds...)
+	if f.settings.jwt != nil {
+		jwtCreds, err := newJWTCreds(f.settings.jwt)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, jwtCreds)
+	}
+
+	// OAuth2 and JWT credentials set authorization header,
+	// server would get two values if it's passed explicitly as well
+	if len(creds) > 0 {
+		for k := range f.metadata(connMd) {
+			if strings.EqualFold(k, "authorization") {
+				return nil, errors.New("authorization header cannot be combined with OAuth2 or JWT credentials")
+			}
+		}
+	}
+
+	return creds, nil
+}
+
+// interceptors returns client interceptors chain, connMd is metadata from the target connection string
+func (f *GrpcConnFactory) interceptors(connMd map[string][]string) ([]grpc.UnaryClientInterceptor, []grpc.StreamClientInterceptor, error) {
+	unaryInterceptors := []grpc.UnaryClientInterceptor{}
+	streamInterceptors := []grpc.StreamClientInterceptor{}
+
+	if f.settings.tracing != nil {
+		t, err := f.getTracing()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the span covers all retry attempts
+		unaryInterceptors = append(unaryInterceptors, tracingUnaryInterceptor(t))
+		streamInterceptors = append(streamInterceptors, tracingStreamInterceptor(t))
+	}
+
+	md := f.metadata(connMd)
+
+	if len(md) > 0 {
+		unaryInterceptors = append(unaryInterceptors,
+			MetadataUnaryInterceptor(md),
+		)
+
+		streamInterceptors = append(streamInterceptors,
+			MetadataStreamInterceptor(md),
+		)
+	}
+
+	if f.settings.authExec != nil {
+		unaryInterceptors = append(unaryInterceptors,
+			authExecUnaryInterceptor(f.settings.authExec),
+		)
+
+		streamInterceptors = append(streamInterceptors,
+			authExecStreamInterceptor(f.settings.authExec),
+		)
+	}
+
+	if f.settings.retryPolicy != nil {
+		streamInterceptors = append(streamInterceptors,
+			retryStreamInterceptor(f.settings.retryPolicy),
+		)
+	}
+
+	return unaryInterceptors, streamInterceptors, nil
+}
+
+// getTracing creates tracer provider shared by all connections
+func (f *GrpcConnFactory) getTracing() (*tracing, error) {
+	f.tracing.Do(func() {
+		f.tracing.t, f.tracing.err = newTracing(context.Background(), f.settings.tracing)
+	})
+	return f.tracing.t, f.tracing.err
+}
+
 func (f *GrpcConnFactory) Close() error {
 	f.conns.Lock()
 	defer f.conns.Unlock()
 
 	resultErr := []string{}
 	for _, connMeta := range f.conns.cache {
+		// the connection is nil if it couldn't be created
+		if connMeta.conn == nil {
+			continue
+		}
+
 		err := connMeta.conn.Close()
 		if err != nil {
 			resultErr = append(resultErr, err.Error())
 		}
 	}
 
+	for _, connMeta := range f.conns.httpCache {
+		if connMeta.conn != nil {
+			connMeta.conn.close()
+		}
+	}
+
+	// flush spans after all calls are finished
+	if f.tracing.t != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		err := f.tracing.t.shutdown(ctx)
+		cancel()
+		if err != nil {
+			resultErr = append(resultErr, err.Error())
+		}
+	}
+
 	if len(resultErr) > 0 {
 		msg := strings.Join(resultErr, ": ")
 		return errors.New(msg)
@@ -233,10 +466,10 @@ func (f *GrpcConnFactory) CloseConn(target string) error {
 		return err
 	}
 	conn, ok := f.conns.cache[connOpts.Host]
-	if ok {
+	if ok && conn.conn != nil {
 		err = conn.conn.Close()
-		delete(f.conns.cache, connOpts.Host)
 	}
+	delete(f.conns.cache, connOpts.Host)
 	return err
 }
 
@@ -252,32 +485,3 @@ func (f *GrpcConnFactory) metadata(connOptsMd map[string][]string) map[string][]
 
 	return metadata.Join(mds...)
 }
-
-func getCredentials(insecure bool, caCert, cert, certKey string) (credentials.TransportCredentials, error) {
-	var tlsCfg tls.Config
-	if insecure {
-		tlsCfg.InsecureSkipVerify = true
-	} else if caCert != "" {
-		b, err := os.ReadFile(caCert)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read the CA certificate: %w", err)
-		}
-		cp := x509.NewCertPool()
-		if !cp.AppendCertsFromPEM(b) {
-			return nil, errors.New("failed to append the client certificate")
-		}
-		tlsCfg.RootCAs = cp
-	}
-
-	if cert != "" && certKey != "" {
-		certificate, err := tls.LoadX509KeyPair(cert, certKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read the client certificate: %w", err)
-		}
-		tlsCfg.Certificates = append(tlsCfg.Certificates, certificate)
-	} else if cert != "" || certKey != "" {
-		return nil, errors.New("both cert and certKey need to be specified")
-	}
-
-	return credentials.NewTLS(&tlsCfg), nil
-}
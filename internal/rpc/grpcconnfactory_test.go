@@ -1,10 +1,20 @@
 package rpc
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestWithAuthority(t *testing.T) {
@@ -38,3 +48,56 @@ func TestWithKeepalive(t *testing.T) {
 	assert.Equal(t, keepalive, grpcConnFact.settings.keepalive)
 	assert.Equal(t, keepaliveTime, grpcConnFact.settings.keepaliveTime)
 }
+
+func TestHTTPConnCompression(t *testing.T) {
+	f := NewGrpcConnFactory(WithProtocol(ProtocolConnect), WithCompressor("gzip"))
+	defer f.Close()
+
+	_, err := f.GetCallConn("localhost:8080")
+	assert.ErrorContains(t, err, "compression is not supported by connect protocol")
+}
+
+func TestConsulTargetWithQuery(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	host, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/users" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("index") != "0" {
+			<-r.Context().Done()
+			return
+		}
+
+		// the instance is returned only if query parameters of the target reach consul
+		w.Header().Set("X-Consul-Index", "1")
+		if r.URL.Query().Get("tag") != "grpc" || r.URL.Query().Get("dc") != "dc1" {
+			w.Write([]byte("[]"))
+			return
+		}
+		fmt.Fprintf(w, `[{"Node": {"Address": %q}, "Service": {"Port": %s}}]`, host, port)
+	}))
+	t.Cleanup(consul.Close)
+
+	f := NewGrpcConnFactory()
+	t.Cleanup(func() { f.Close() })
+
+	target := "consul://" + strings.TrimPrefix(consul.URL, "http://") + "/users?tag=grpc&dc=dc1"
+	conn, err := f.GetConn(target)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
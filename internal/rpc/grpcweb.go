@@ -0,0 +1,290 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// gRPC-Web protocol is described in https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+const (
+	grpcWebContentType     = "application/grpc-web+proto"
+	grpcWebTextContentType = "application/grpc-web-text+proto"
+
+	frameHeaderSize     = 5
+	compressedFrameFlag = 0x01
+	trailerFrameFlag    = 0x80
+)
+
+func newWebStream(text bool) httpStreamFunc {
+	return func(ctx context.Context, c *httpConn, desc *grpc.StreamDesc, method string) (grpc.ClientStream, error) {
+		if desc.ClientStreams {
+			return nil, status.Error(codes.Unimplemented, "grpc-web: "+errClientStreaming.Error())
+		}
+
+		s := &webStream{
+			httpStream: newHTTPStream(ctx, c, method, ProtocolGRPCWeb),
+			text:       text,
+		}
+		s.send = s.do
+
+		return s, nil
+	}
+}
+
+// webStream sends the request when CloseSend is called,
+// gRPC-Web supports unary and server streaming calls only
+type webStream struct {
+	*httpStream
+	text bool
+
+	req  []byte
+	body *bufio.Reader
+}
+
+func (s *webStream) SendMsg(m any) error {
+	if s.req != nil {
+		return status.Error(codes.Internal, "grpc-web: only a single request message is supported")
+	}
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "grpc-web: unsupported message type %T", m)
+	}
+
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return status.Errorf(codes.Internal, "grpc-web: failed to marshal request: %v", err)
+	}
+
+	s.req = appendFrame(nil, 0, b)
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.OutPayload{
+			Client:           true,
+			Length:           len(b),
+			CompressedLength: len(b),
+			WireLength:       len(s.req),
+			SentTime:         time.Now(),
+		})
+	})
+
+	return nil
+}
+
+func (s *webStream) RecvMsg(m any) error {
+	if err := s.waitResponse(); err != nil {
+		return err
+	}
+
+	if s.err != nil {
+		return s.finish(nil, status.Convert(s.err))
+	}
+
+	// trailers-only response has status in headers
+	if st, ok := statusFromMetadata(s.header); ok {
+		return s.finish(s.header, st)
+	}
+
+	flags, payload, err := readFrame(s.body, s.conn.maxRecvMsgSize)
+	if err != nil {
+		if err == io.EOF {
+			return s.finish(nil, status.New(codes.Internal, "grpc-web: server closed the stream without sending trailers"))
+		}
+		return s.finish(nil, s.readError(err))
+	}
+
+	if flags&trailerFrameFlag != 0 {
+		trailer := parseWebTrailers(payload)
+		st, ok := statusFromMetadata(trailer)
+		if !ok {
+			st = status.New(codes.Internal, "grpc-web: grpc-status is missing in trailers")
+		}
+		return s.finish(trailer, st)
+	}
+
+	if flags&compressedFrameFlag != 0 {
+		return s.finish(nil, status.New(codes.Internal, "grpc-web: compressed messages are not supported"))
+	}
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return s.finish(nil, status.Newf(codes.Internal, "grpc-web: unsupported message type %T", m))
+	}
+
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return s.finish(nil, status.Newf(codes.Internal, "grpc-web: failed to unmarshal response: %v", err))
+	}
+
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.InPayload{
+			Client:           true,
+			Length:           len(payload),
+			CompressedLength: len(payload),
+			WireLength:       len(payload) + frameHeaderSize,
+			RecvTime:         time.Now(),
+		})
+	})
+
+	return nil
+}
+
+func (s *webStream) do() {
+	body := s.req
+	contentType := grpcWebContentType
+	if s.text {
+		body = []byte(base64.StdEncoding.EncodeToString(body))
+		contentType = grpcWebTextContentType
+	}
+
+	req, err := s.conn.newRequest(s.ctx, s.method, body)
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+	req.Header.Set("X-Grpc-Web", "1")
+	req.Header.Set("X-User-Agent", httpUserAgent)
+	if deadline, ok := s.ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", encodeTimeout(time.Until(deadline)))
+	}
+
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.OutHeader{Client: true, Header: headersToMetadata(req.Header), FullMethod: s.method})
+	})
+
+	resp, err := s.conn.client.Do(req)
+	if err != nil {
+		s.err = s.readError(err).Err()
+		return
+	}
+
+	s.header = headersToMetadata(resp.Header)
+	s.closer = resp.Body
+	recordHTTPResponse(s.ctx, s.method, s.header, resp)
+
+	if _, ok := statusFromMetadata(s.header); ok {
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		s.err = status.Errorf(httpStatusToCode(resp.StatusCode), "grpc-web: unexpected HTTP status %s", resp.Status)
+		return
+	}
+
+	respType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(respType, "application/grpc-web") {
+		s.err = status.Errorf(codes.Unknown, "grpc-web: unexpected content type %q", respType)
+		return
+	}
+
+	var r io.Reader = resp.Body
+	if strings.HasPrefix(respType, "application/grpc-web-text") {
+		r = &base64Reader{r: bufio.NewReader(resp.Body)}
+	}
+	s.body = bufio.NewReader(r)
+}
+
+func appendFrame(b []byte, flags byte, payload []byte) []byte {
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+func readFrame(r io.Reader, maxSize int) (byte, []byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if header[0]&trailerFrameFlag == 0 && int64(size) > int64(maxSize) {
+		return 0, nil, status.Errorf(codes.ResourceExhausted, "received message larger than max (%d vs. %d)", size, maxSize)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
+
+// parseWebTrailers parses trailers frame, trailers are encoded as HTTP/1 headers
+func parseWebTrailers(b []byte) metadata.MD {
+	md := metadata.MD{}
+	for line := range bytes.SplitSeq(b, []byte("\r\n")) {
+		k, v, ok := strings.Cut(string(line), ":")
+		if !ok {
+			continue
+		}
+
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+		if strings.HasSuffix(k, "-bin") {
+			if decoded, err := decodeBinHeader(v); err == nil {
+				v = string(decoded)
+			}
+		}
+		md.Append(k, v)
+	}
+	return md
+}
+
+// base64Reader decodes grpc-web-text response, every frame can be encoded separately
+// so padding can be found in the middle of the stream
+type base64Reader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func (b *base64Reader) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 {
+		var quantum [4]byte
+		n := 0
+		for n < len(quantum) {
+			c, err := b.r.ReadByte()
+			if err != nil {
+				if err == io.EOF && n > 0 {
+					return 0, io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+
+			if c == '\r' || c == '\n' || c == ' ' || c == '\t' {
+				continue
+			}
+			quantum[n] = c
+			n++
+		}
+
+		decoded := make([]byte, 3)
+		m, err := base64.StdEncoding.Decode(decoded, quantum[:])
+		if err != nil {
+			return 0, fmt.Errorf("invalid grpc-web-text response: %w", err)
+		}
+		b.buf = decoded[:m]
+	}
+
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,62 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestBase64ReaderPaddedChunks(t *testing.T) {
+	first := appendFrame(nil, 0, []byte("hello"))
+	second := appendFrame(nil, trailerFrameFlag, []byte("grpc-status: 0\r\n"))
+
+	// every frame is encoded separately, so padding appears in the middle
+	encoded := base64.StdEncoding.EncodeToString(first) + "\r\n" + base64.StdEncoding.EncodeToString(second)
+	r := bufio.NewReader(&base64Reader{r: bufio.NewReader(strings.NewReader(encoded))})
+
+	flags, payload, err := readFrame(r, defaultMaxRecvMsgSize)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), flags)
+	assert.Equal(t, "hello", string(payload))
+
+	flags, payload, err = readFrame(r, defaultMaxRecvMsgSize)
+	require.NoError(t, err)
+	assert.Equal(t, byte(trailerFrameFlag), flags)
+	assert.Equal(t, "grpc-status: 0\r\n", string(payload))
+
+	_, _, err = readFrame(r, defaultMaxRecvMsgSize)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadFrameMaxSize(t *testing.T) {
+	frame := appendFrame(nil, 0, bytes.Repeat([]byte{1}, 10))
+	_, _, err := readFrame(bytes.NewReader(frame), 5)
+	assert.ErrorContains(t, err, "larger than max")
+}
+
+func TestParseWebTrailers(t *testing.T) {
+	details := base64.StdEncoding.EncodeToString([]byte("details"))
+	md := parseWebTrailers([]byte("Grpc-Status: 5\r\ngrpc-message: not%20found\r\nx-trace-bin: " + details + "\r\n"))
+
+	assert.Equal(t, []string{"details"}, md.Get("x-trace-bin"))
+
+	st, ok := statusFromMetadata(md)
+	require.True(t, ok)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "not found", st.Message())
+}
+
+func TestEncodeTimeout(t *testing.T) {
+	assert.Equal(t, "1500m", encodeTimeout(1500*time.Millisecond))
+	assert.Equal(t, "1m", encodeTimeout(100*time.Microsecond))
+	assert.Equal(t, "1n", encodeTimeout(-time.Second))
+	assert.Equal(t, "100000S", encodeTimeout(100000*time.Second))
+}
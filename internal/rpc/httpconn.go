@@ -0,0 +1,504 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+const (
+	ProtocolGRPC        = "grpc"
+	ProtocolGRPCWeb     = "grpc-web"
+	ProtocolGRPCWebText = "grpc-web-text"
+	ProtocolConnect     = "connect"
+	ProtocolHTTP        = "http"
+)
+
+// Protocols lists protocols supported for service calls, reflection always uses gRPC
+var Protocols = []string{ProtocolGRPC, ProtocolGRPCWeb, ProtocolGRPCWebText, ProtocolConnect, ProtocolHTTP}
+
+const (
+	httpUserAgent = "grpc-client-cli"
+	// the same as grpc default max receive message size
+	defaultMaxRecvMsgSize = 4 * 1024 * 1024
+)
+
+type httpStreamFunc func(ctx context.Context, c *httpConn, desc *grpc.StreamDesc, method string) (grpc.ClientStream, error)
+
+// httpConn implements grpc.ClientConnInterface for protocols running on top of plain HTTP,
+// calls go through the same interceptors as regular gRPC connections
+type httpConn struct {
+	baseURL        string
+	authority      string
+	client         *http.Client
+	callCreds      []credentials.PerRPCCredentials
+	maxRecvMsgSize int
+	streamer       grpc.Streamer
+}
+
+type httpConnMeta struct {
+	conn *httpConn
+	err  error
+}
+
+func (f *GrpcConnFactory) newHTTPConn(target string) (*httpConn, error) {
+	if f.settings.compressor != "" {
+		return nil, fmt.Errorf("compression is not supported by %s protocol", f.settings.protocol)
+	}
+
+	connOpts, err := NewConnectionOpts(target)
+	if err != nil {
+		return nil, err
+	}
+
+	host := connOpts.Host
+	if scheme, rest, ok := strings.Cut(host, "://"); ok {
+		if scheme != "dns" {
+			return nil, fmt.Errorf("only host:port or dns:///host:port targets are supported by %s protocol", f.settings.protocol)
+		}
+		host = strings.TrimPrefix(rest, "/")
+	}
+
+	authority := connOpts.Authority
+	if f.settings.authority != "" {
+		authority = f.settings.authority
+	}
+
+	transport := &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		ForceAttemptHTTP2: true,
+	}
+
+	scheme := "http"
+	if f.settings.tls {
+		scheme = "https"
+		cfg, err := newTLSConfig(f.settings)
+		if err != nil {
+			return nil, err
+		}
+
+		// the same as grpc, :authority is used as TLS server name
+		serverName := host
+		if authority != "" {
+			serverName = authority
+		}
+		if h, _, err := net.SplitHostPort(serverName); err == nil {
+			serverName = h
+		}
+
+		// connections through a proxy use the config created here, direct connections
+		// get a new one on every dial to pick up rotated CA certificate
+		transport.TLSClientConfig = cfg.forServer(serverName)
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			tlsCfg := cfg.forServer(serverName)
+			tlsCfg.NextProtos = []string{"h2", "http/1.1"}
+			d := &tls.Dialer{Config: tlsCfg}
+			return d.DialContext(ctx, network, addr)
+		}
+	}
+
+	callCreds, err := f.callCredentials(connOpts.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	_, streamInterceptors, err := f.interceptors(connOpts.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &httpConn{
+		baseURL:        scheme + "://" + host,
+		authority:      authority,
+		client:         &http.Client{Transport: transport},
+		callCreds:      callCreds,
+		maxRecvMsgSize: f.settings.maxRecvMsgSize,
+	}
+	if c.maxRecvMsgSize <= 0 {
+		c.maxRecvMsgSize = defaultMaxRecvMsgSize
+	}
+
+	var newStream httpStreamFunc
+	switch f.settings.protocol {
+	case ProtocolGRPCWeb:
+		newStream = newWebStream(false)
+	case ProtocolGRPCWebText:
+		newStream = newWebStream(true)
+	case ProtocolConnect:
+		newStream = newConnectStream(f.settings.connectCodec)
+	case ProtocolHTTP:
+		newStream = newTranscodingStream
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", f.settings.protocol)
+	}
+
+	c.streamer = chainStreamer(streamInterceptors, func(ctx context.Context, desc *grpc.StreamDesc, _ *grpc.ClientConn, method string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		return newStream(ctx, c, desc, method)
+	})
+
+	return c, nil
+}
+
+// Invoke calls unary method through the stream interceptors, unary interceptors are not used
+func (c *httpConn) Invoke(ctx context.Context, method string, args any, reply any, opts ...grpc.CallOption) error {
+	cs, err := c.NewStream(ctx, &grpc.StreamDesc{}, method, opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := cs.SendMsg(args); err != nil {
+		return err
+	}
+
+	if err := cs.CloseSend(); err != nil {
+		return err
+	}
+
+	return cs.RecvMsg(reply)
+}
+
+func (c *httpConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return c.streamer(ctx, desc, nil, method, opts...)
+}
+
+func (c *httpConn) close() {
+	c.client.CloseIdleConnections()
+}
+
+// newRequest creates POST request to the method with headers from outgoing metadata and call credentials
+func (c *httpConn) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	return c.newHTTPRequest(ctx, http.MethodPost, method, method, body)
+}
+
+// newHTTPRequest creates request to the path, call credentials are requested for the service of the method
+func (c *httpConn) newHTTPRequest(ctx context.Context, httpMethod, path, method string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, httpMethod, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if c.authority != "" {
+		req.Host = c.authority
+	}
+
+	req.Header.Set("User-Agent", httpUserAgent)
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+
+	service, _, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	for _, creds := range c.callCreds {
+		if creds.RequireTransportSecurity() && req.URL.Scheme != "https" {
+			return nil, status.Error(codes.Unauthenticated, "credentials require transport level security (use --tls option)")
+		}
+
+		credsMd, err := creds.GetRequestMetadata(ctx, c.baseURL+"/"+service)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		for k, v := range credsMd {
+			md.Append(k, v)
+		}
+	}
+
+	for k, values := range md {
+		for _, v := range values {
+			if strings.HasSuffix(k, "-bin") {
+				v = base64.RawStdEncoding.EncodeToString([]byte(v))
+			}
+			req.Header.Add(k, v)
+		}
+	}
+
+	return req, nil
+}
+
+// httpStream contains the state shared by streams of HTTP based protocols,
+// the request is sent by send func when CloseSend is called
+type httpStream struct {
+	ctx    context.Context
+	conn   *httpConn
+	method string
+	// protocol is used as errors prefix
+	protocol string
+	begin    time.Time
+	send     func()
+
+	closeOnce sync.Once
+	// ready is closed when response headers are received or the request fails
+	ready  chan struct{}
+	header metadata.MD
+	closer io.Closer
+	err    error
+
+	// the fields below are used by RecvMsg only
+	trailer  metadata.MD
+	finished bool
+	finalErr error
+}
+
+func newHTTPStream(ctx context.Context, c *httpConn, method, protocol string) *httpStream {
+	s := &httpStream{
+		ctx:      ctx,
+		conn:     c,
+		method:   method,
+		protocol: protocol,
+		begin:    time.Now(),
+		ready:    make(chan struct{}),
+	}
+
+	recordHTTPStats(ctx, func(st *Stats) {
+		st.record(&stats.Begin{Client: true, BeginTime: s.begin})
+	})
+
+	return s
+}
+
+func (s *httpStream) Header() (metadata.MD, error) {
+	select {
+	case <-s.ready:
+		return s.header, s.err
+	case <-s.ctx.Done():
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	}
+}
+
+// Trailer returns trailers, it should be called after RecvMsg returns an error
+func (s *httpStream) Trailer() metadata.MD {
+	return s.trailer
+}
+
+func (s *httpStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *httpStream) CloseSend() error {
+	s.closeOnce.Do(func() {
+		defer close(s.ready)
+		s.send()
+	})
+	return nil
+}
+
+// waitResponse waits for the response headers, non-nil error means the stream is finished
+// and the error should be returned by RecvMsg
+func (s *httpStream) waitResponse() error {
+	select {
+	case <-s.ready:
+	case <-s.ctx.Done():
+		return s.finish(nil, status.FromContextError(s.ctx.Err()))
+	}
+
+	if s.finished {
+		return s.finalErr
+	}
+	return nil
+}
+
+func (s *httpStream) readError(err error) *status.Status {
+	if s.ctx.Err() != nil {
+		return status.FromContextError(s.ctx.Err())
+	}
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	return status.Newf(codes.Unavailable, "%s: %v", s.protocol, err)
+}
+
+// finish records the call end and returns io.EOF for OK status
+func (s *httpStream) finish(trailer metadata.MD, st *status.Status) error {
+	if s.finished {
+		return s.finalErr
+	}
+
+	s.finished = true
+	s.trailer = trailer
+
+	// the request can still be in progress if the context is cancelled
+	select {
+	case <-s.ready:
+		if s.closer != nil {
+			s.closer.Close()
+		}
+	default:
+	}
+
+	s.finalErr = io.EOF
+	if st.Code() != codes.OK {
+		s.finalErr = st.Err()
+	}
+
+	recordHTTPEnd(s.ctx, s.begin, trailer, st)
+	return s.finalErr
+}
+
+// recordHTTPStats records call stats, grpc stats handler is not used for HTTP connections
+func recordHTTPStats(ctx context.Context, event func(s *Stats)) {
+	if s := ExtractRpcStats(ctx); s != nil {
+		event(s)
+	}
+}
+
+// recordHTTPResponse records response headers and TLS state of the connection
+func recordHTTPResponse(ctx context.Context, method string, header metadata.MD, resp *http.Response) {
+	recordHTTPStats(ctx, func(st *Stats) {
+		st.record(&stats.InHeader{Client: true, Header: header, FullMethod: method})
+		if resp.TLS != nil {
+			st.Lock()
+			st.tlsState = resp.TLS
+			st.Unlock()
+		}
+	})
+}
+
+// recordHTTPEnd records trailers and the call end
+func recordHTTPEnd(ctx context.Context, begin time.Time, trailer metadata.MD, st *status.Status) {
+	recordHTTPStats(ctx, func(s *Stats) {
+		if trailer != nil {
+			s.record(&stats.InTrailer{Client: true, Trailer: trailer})
+		}
+		s.record(&stats.End{Client: true, BeginTime: begin, EndTime: time.Now(), Error: st.Err()})
+	})
+}
+
+// splitTrailers separates response headers and trailers sent as headers with the prefix
+func splitTrailers(h http.Header, prefix string) (metadata.MD, metadata.MD) {
+	header, trailer := http.Header{}, http.Header{}
+	for k, values := range h {
+		if name, ok := strings.CutPrefix(k, prefix); ok {
+			trailer[name] = values
+			continue
+		}
+		header[k] = values
+	}
+	return headersToMetadata(header), headersToMetadata(trailer)
+}
+
+// encodeTimeout formats the deadline as grpc-timeout header value
+func encodeTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "1n"
+	}
+
+	// the value should have at most 8 digits
+	if ms := d.Milliseconds(); ms < 1e8 {
+		return strconv.FormatInt(max(ms, 1), 10) + "m"
+	}
+
+	return strconv.FormatInt(int64(d.Seconds()), 10) + "S"
+}
+
+// headersToMetadata converts HTTP headers to metadata decoding binary values
+func headersToMetadata(h http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, values := range h {
+		k = strings.ToLower(k)
+		for _, v := range values {
+			if strings.HasSuffix(k, "-bin") {
+				if b, err := decodeBinHeader(v); err == nil {
+					v = string(b)
+				}
+			}
+			md.Append(k, v)
+		}
+	}
+	return md
+}
+
+func decodeBinHeader(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}
+
+// statusFromMetadata extracts grpc status from trailers, ok is false if grpc-status is missing
+func statusFromMetadata(md metadata.MD) (*status.Status, bool) {
+	codeVal := md.Get("grpc-status")
+	if len(codeVal) == 0 {
+		return nil, false
+	}
+
+	code, err := strconv.Atoi(codeVal[0])
+	if err != nil {
+		return status.Newf(codes.Internal, "invalid grpc-status %q", codeVal[0]), true
+	}
+
+	if details := md.Get("grpc-status-details-bin"); len(details) > 0 {
+		if st, err := statusFromDetails([]byte(details[0])); err == nil {
+			return st, true
+		}
+	}
+
+	var msg string
+	if m := md.Get("grpc-message"); len(m) > 0 {
+		msg = m[0]
+		if unescaped, err := url.PathUnescape(msg); err == nil {
+			msg = unescaped
+		}
+	}
+
+	return status.New(codes.Code(code), msg), true
+}
+
+func statusFromDetails(b []byte) (*status.Status, error) {
+	st := &spb.Status{}
+	if err := proto.Unmarshal(b, st); err != nil {
+		return nil, err
+	}
+	return status.FromProto(st), nil
+}
+
+// httpStatusToCode maps HTTP status to grpc code when the response doesn't have grpc-status,
+// see https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func httpStatusToCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.Internal
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// chainStreamer wraps the streamer with interceptors, the first interceptor is the outermost one
+func chainStreamer(interceptors []grpc.StreamClientInterceptor, streamer grpc.Streamer) grpc.Streamer {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next, interceptor := streamer, interceptors[i]
+		streamer = func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return interceptor(ctx, desc, cc, method, next, opts...)
+		}
+	}
+	return streamer
+}
+
+var errClientStreaming = errors.New("client and bidi streaming calls are not supported")
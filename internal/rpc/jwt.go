@@ -0,0 +1,293 @@
+package rpc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultJWTLifetime = 15 * time.Minute
+
+// JWTConfig contains settings to mint self-signed JWT tokens
+type JWTConfig struct {
+	// KeyFile is either PEM encoded private key (RSA, ECDSA P-256 or Ed25519)
+	// or service account JSON key file with private_key, private_key_id and client_email fields
+	KeyFile string
+	Issuer  string
+	Subject string
+	// Audience defaults to fully qualified name of the called service
+	Audience string
+	Claims   map[string]any
+	Lifetime time.Duration
+}
+
+// serviceAccountKey is the subset of service account JSON key file
+type serviceAccountKey struct {
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	ClientEmail  string `json:"client_email"`
+}
+
+type jwtToken struct {
+	value  string
+	expiry time.Time
+}
+
+// jwtCreds implements credentials.PerRPCCredentials,
+// it mints a signed token per audience and refreshes it before expiration
+type jwtCreds struct {
+	cfg    JWTConfig
+	key    crypto.Signer
+	alg    string
+	keyID  string
+	now    func() time.Time
+	mu     sync.Mutex
+	tokens map[string]*jwtToken
+}
+
+func newJWTCreds(cfg *JWTConfig) (*jwtCreds, error) {
+	b, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt key: %w", err)
+	}
+
+	c := &jwtCreds{
+		cfg:    *cfg,
+		now:    time.Now,
+		tokens: map[string]*jwtToken{},
+	}
+
+	keyPEM := b
+	var sa serviceAccountKey
+	if json.Unmarshal(b, &sa) == nil && sa.PrivateKey != "" {
+		keyPEM = []byte(sa.PrivateKey)
+		c.keyID = sa.PrivateKeyID
+		if c.cfg.Issuer == "" {
+			c.cfg.Issuer = sa.ClientEmail
+		}
+	}
+
+	c.key, c.alg, err = parseSigningKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cfg.Subject == "" {
+		c.cfg.Subject = c.cfg.Issuer
+	}
+
+	if c.cfg.Lifetime <= 0 {
+		c.cfg.Lifetime = defaultJWTLifetime
+	}
+
+	return c, nil
+}
+
+func parseSigningKey(b []byte) (crypto.Signer, string, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, "", errors.New("jwt key: no PEM data found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, "", fmt.Errorf("jwt key: unsupported PEM block type %q", block.Type)
+	}
+
+	if err != nil {
+		return nil, "", fmt.Errorf("jwt key: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, "RS256", nil
+	case *ecdsa.PrivateKey:
+		if k.Curve.Params().BitSize != 256 {
+			return nil, "", errors.New("jwt key: only P-256 ECDSA keys are supported")
+		}
+		return k, "ES256", nil
+	case ed25519.PrivateKey:
+		return k, "EdDSA", nil
+	default:
+		return nil, "", fmt.Errorf("jwt key: unsupported key type %T", key)
+	}
+}
+
+func (c *jwtCreds) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	audience := c.cfg.Audience
+	if audience == "" && len(uri) > 0 {
+		audience = serviceFromURI(uri[0])
+	}
+
+	token, err := c.getToken(audience)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "jwt: %v", err)
+	}
+
+	return map[string]string{
+		"authorization": "Bearer " + token,
+	}, nil
+}
+
+func (c *jwtCreds) RequireTransportSecurity() bool {
+	return false
+}
+
+// serviceFromURI extracts fully qualified service name from the uri grpc passes to per-RPC credentials,
+// it has https://host/package.Service format
+func serviceFromURI(uri string) string {
+	if i := strings.LastIndex(uri, "/"); i >= 0 {
+		return uri[i+1:]
+	}
+	return uri
+}
+
+func (c *jwtCreds) getToken(audience string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if t, ok := c.tokens[audience]; ok && now.Add(c.refreshDelta()).Before(t.expiry) {
+		return t.value, nil
+	}
+
+	t, err := c.sign(audience, now)
+	if err != nil {
+		return "", err
+	}
+
+	c.tokens[audience] = t
+	return t.value, nil
+}
+
+// refreshDelta returns how long before expiration the token should be refreshed
+func (c *jwtCreds) refreshDelta() time.Duration {
+	return min(tokenExpiryDelta, c.cfg.Lifetime/4)
+}
+
+func (c *jwtCreds) sign(audience string, now time.Time) (*jwtToken, error) {
+	header := map[string]string{
+		"alg": c.alg,
+		"typ": "JWT",
+	}
+
+	if c.keyID != "" {
+		header["kid"] = c.keyID
+	}
+
+	expiry := now.Add(c.cfg.Lifetime)
+	claims := map[string]any{}
+	for k, v := range c.cfg.Claims {
+		claims[k] = v
+	}
+
+	if c.cfg.Issuer != "" {
+		claims["iss"] = c.cfg.Issuer
+	}
+
+	if c.cfg.Subject != "" {
+		claims["sub"] = c.cfg.Subject
+	}
+
+	if audience != "" {
+		claims["aud"] = audience
+	}
+
+	claims["iat"] = now.Unix()
+	claims["exp"] = expiry.Unix()
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	sig, err := c.signature([]byte(signingInput))
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtToken{
+		value:  signingInput + "." + base64.RawURLEncoding.EncodeToString(sig),
+		expiry: expiry,
+	}, nil
+}
+
+func (c *jwtCreds) signature(data []byte) ([]byte, error) {
+	switch key := c.key.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, data), nil
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses fixed size r||s signature format instead of ASN.1
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	default:
+		digest := sha256.Sum256(data)
+		return c.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+}
+
+// ParseJWTClaims converts "key: value" pairs to JWT claims,
+// values that are valid JSON (numbers, booleans, objects, arrays) are used as is, other values are strings
+func ParseJWTClaims(m map[string][]string) map[string]any {
+	claims := map[string]any{}
+	for k, values := range m {
+		for _, v := range values {
+			var val any = v
+			var js any
+			if json.Unmarshal([]byte(v), &js) == nil {
+				val = js
+			}
+
+			if existing, ok := claims[k]; ok {
+				if arr, ok := existing.([]any); ok {
+					claims[k] = append(arr, val)
+				} else {
+					claims[k] = []any{existing, val}
+				}
+				continue
+			}
+			claims[k] = val
+		}
+	}
+
+	return claims
+}
@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, key crypto.Signer) string {
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}), 0o600))
+	return file
+}
+
+// parseJWT verifies token signature and returns its header and claims
+func parseJWT(t *testing.T, token string, pub crypto.PublicKey) (map[string]any, map[string]any) {
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+
+	signed := []byte(parts[0] + "." + parts[1])
+	digest := sha256.Sum256(signed)
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		require.NoError(t, rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig))
+	case *ecdsa.PublicKey:
+		require.Len(t, sig, 64)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		require.True(t, ecdsa.Verify(k, digest[:], r, s))
+	case ed25519.PublicKey:
+		require.True(t, ed25519.Verify(k, signed, sig))
+	}
+
+	decode := func(s string) map[string]any {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		require.NoError(t, err)
+		m := map[string]any{}
+		require.NoError(t, json.Unmarshal(b, &m))
+		return m
+	}
+
+	return decode(parts[0]), decode(parts[1])
+}
+
+func bearer(t *testing.T, md map[string]string) string {
+	token, ok := strings.CutPrefix(md["authorization"], "Bearer ")
+	require.True(t, ok)
+	return token
+}
+
+func TestJWTSigningKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{"RSA", rsaKey, "RS256"},
+		{"ECDSA", ecKey, "ES256"},
+		{"Ed25519", edKey, "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := newJWTCreds(&JWTConfig{
+				KeyFile: writeKey(t, tt.key),
+				Issuer:  "svc@example.com",
+			})
+			require.NoError(t, err)
+
+			md, err := creds.GetRequestMetadata(context.Background(), "https://localhost:5050/grpc.testing.TestService")
+			require.NoError(t, err)
+
+			header, claims := parseJWT(t, bearer(t, md), tt.key.Public())
+			assert.Equal(t, tt.alg, header["alg"])
+			assert.Equal(t, "svc@example.com", claims["iss"])
+			assert.Equal(t, "svc@example.com", claims["sub"])
+			assert.Equal(t, "grpc.testing.TestService", claims["aud"])
+			assert.Equal(t, defaultJWTLifetime.Seconds(), claims["exp"].(float64)-claims["iat"].(float64))
+		})
+	}
+}
+
+func TestJWTServiceAccountKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	sa, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"private_key":    string(keyPEM),
+		"private_key_id": "key1",
+		"client_email":   "svc@project.iam.gserviceaccount.com",
+	})
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "sa.json")
+	require.NoError(t, os.WriteFile(file, sa, 0o600))
+
+	creds, err := newJWTCreds(&JWTConfig{
+		KeyFile:  file,
+		Audience: "https://api.example.com",
+		Claims:   ParseJWTClaims(map[string][]string{"role": {"admin"}, "level": {"3"}}),
+	})
+	require.NoError(t, err)
+
+	md, err := creds.GetRequestMetadata(context.Background(), "https://localhost:5050/grpc.testing.TestService")
+	require.NoError(t, err)
+
+	header, claims := parseJWT(t, bearer(t, md), key.Public())
+	assert.Equal(t, "key1", header["kid"])
+	assert.Equal(t, "svc@project.iam.gserviceaccount.com", claims["iss"])
+	assert.Equal(t, "https://api.example.com", claims["aud"])
+	assert.Equal(t, "admin", claims["role"])
+	assert.Equal(t, float64(3), claims["level"])
+}
+
+func TestJWTRefresh(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	creds, err := newJWTCreds(&JWTConfig{KeyFile: writeKey(t, key), Lifetime: time.Hour})
+	require.NoError(t, err)
+
+	now := time.Now()
+	creds.now = func() time.Time { return now }
+
+	uri := "https://localhost:5050/grpc.testing.TestService"
+	md1, err := creds.GetRequestMetadata(context.Background(), uri)
+	require.NoError(t, err)
+
+	// token is still valid
+	now = now.Add(time.Hour - 2*tokenExpiryDelta)
+	md2, err := creds.GetRequestMetadata(context.Background(), uri)
+	require.NoError(t, err)
+	assert.Equal(t, md1, md2)
+
+	// each service gets its own token
+	md3, err := creds.GetRequestMetadata(context.Background(), "https://localhost:5050/grpc.testing.OtherService")
+	require.NoError(t, err)
+	assert.NotEqual(t, md1, md3)
+
+	// token is about to expire and should be minted again
+	now = now.Add(tokenExpiryDelta + time.Second)
+	md4, err := creds.GetRequestMetadata(context.Background(), uri)
+	require.NoError(t, err)
+	assert.NotEqual(t, md1, md4)
+}
+
+func TestJWTInvalidKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(file, []byte("not a key"), 0o600))
+
+	_, err := newJWTCreds(&JWTConfig{KeyFile: file})
+	assert.ErrorContains(t, err, "no PEM data found")
+
+	_, err = newJWTCreds(&JWTConfig{KeyFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorContains(t, err, "failed to read jwt key")
+}
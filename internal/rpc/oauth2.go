@@ -0,0 +1,195 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// default subject token type for token exchange, see RFC 8693
+	tokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	// tokens are refreshed this amount of time before they expire
+	tokenExpiryDelta = 30 * time.Second
+)
+
+// OAuth2Config contains settings to fetch access tokens from OAuth2 token endpoint
+// using client credentials or token exchange (if SubjectToken is set) grants
+type OAuth2Config struct {
+	TokenURL         string
+	ClientID         string
+	ClientSecret     string
+	Scopes           []string
+	Audience         string
+	SubjectToken     string
+	SubjectTokenType string
+}
+
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+
+	expiry time.Time
+}
+
+func (t *oauth2Token) valid(now time.Time) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+
+	return t.expiry.IsZero() || now.Add(tokenExpiryDelta).Before(t.expiry)
+}
+
+func (t *oauth2Token) authorization() string {
+	tokenType := t.TokenType
+	// some providers return "bearer" which is not accepted by all servers
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+
+	return tokenType + " " + t.AccessToken
+}
+
+type oauth2TokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// oauth2Creds implements credentials.PerRPCCredentials,
+// it caches fetched token and refreshes it before expiration
+type oauth2Creds struct {
+	cfg    *OAuth2Config
+	client *http.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	token *oauth2Token
+}
+
+func newOAuth2Creds(cfg *OAuth2Config) *oauth2Creds {
+	return &oauth2Creds{
+		cfg:    cfg,
+		client: http.DefaultClient,
+		now:    time.Now,
+	}
+}
+
+func (c *oauth2Creds) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := c.getToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"authorization": token.authorization(),
+	}, nil
+}
+
+func (c *oauth2Creds) RequireTransportSecurity() bool {
+	return false
+}
+
+func (c *oauth2Creds) getToken(ctx context.Context) (*oauth2Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token.valid(c.now()) {
+		return c.token, nil
+	}
+
+	token, err := c.fetchToken(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "oauth2: cannot fetch token: %v", err)
+	}
+
+	c.token = token
+	return token, nil
+}
+
+func (c *oauth2Creds) fetchToken(ctx context.Context) (*oauth2Token, error) {
+	form := url.Values{}
+	if c.cfg.SubjectToken != "" {
+		form.Set("grant_type", grantTypeTokenExchange)
+		form.Set("subject_token", c.cfg.SubjectToken)
+
+		tokenType := c.cfg.SubjectTokenType
+		if tokenType == "" {
+			tokenType = tokenTypeAccessToken
+		}
+		form.Set("subject_token_type", tokenType)
+	} else {
+		form.Set("grant_type", grantTypeClientCredentials)
+	}
+
+	if len(c.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	}
+
+	if c.cfg.Audience != "" {
+		form.Set("audience", c.cfg.Audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	issuedAt := c.now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var tokenErr oauth2TokenError
+		if json.Unmarshal(body, &tokenErr) == nil && tokenErr.Error != "" {
+			if tokenErr.ErrorDescription != "" {
+				return nil, fmt.Errorf("%s: %s: %s", resp.Status, tokenErr.Error, tokenErr.ErrorDescription)
+			}
+			return nil, fmt.Errorf("%s: %s", resp.Status, tokenErr.Error)
+		}
+		return nil, errors.New(resp.Status)
+	}
+
+	token := &oauth2Token{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+
+	if token.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+
+	if token.ExpiresIn > 0 {
+		token.expiry = issuedAt.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
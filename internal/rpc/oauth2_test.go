@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTokenServer(t *testing.T, check func(r *http.Request)) (*httptest.Server, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		require.NoError(t, r.ParseForm())
+		if check != nil {
+			check(r)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token%d", "token_type": "bearer", "expires_in": 3600}`, n)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, calls
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	srv, calls := newTokenServer(t, func(r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "client", id)
+		assert.Equal(t, "secret", secret)
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "read write", r.PostForm.Get("scope"))
+		assert.Equal(t, "api://users", r.PostForm.Get("audience"))
+	})
+
+	creds := newOAuth2Creds(&OAuth2Config{
+		TokenURL:     srv.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+		Audience:     "api://users",
+	})
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token1", md["authorization"])
+
+	// cached token is reused
+	md, err = creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token1", md["authorization"])
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestOAuth2Refresh(t *testing.T) {
+	srv, calls := newTokenServer(t, nil)
+
+	now := time.Now()
+	creds := newOAuth2Creds(&OAuth2Config{TokenURL: srv.URL})
+	creds.now = func() time.Time { return now }
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token1", md["authorization"])
+
+	// token is still valid
+	now = now.Add(time.Hour - 2*tokenExpiryDelta)
+	md, err = creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token1", md["authorization"])
+
+	// token is about to expire and should be refreshed
+	now = now.Add(tokenExpiryDelta + time.Second)
+	md, err = creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token2", md["authorization"])
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestOAuth2TokenExchange(t *testing.T) {
+	srv, _ := newTokenServer(t, func(r *http.Request) {
+		assert.Equal(t, grantTypeTokenExchange, r.PostForm.Get("grant_type"))
+		assert.Equal(t, "subject", r.PostForm.Get("subject_token"))
+		assert.Equal(t, tokenTypeAccessToken, r.PostForm.Get("subject_token_type"))
+	})
+
+	creds := newOAuth2Creds(&OAuth2Config{
+		TokenURL:     srv.URL,
+		SubjectToken: "subject",
+	})
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token1", md["authorization"])
+}
+
+func TestOAuth2Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "invalid_client", "error_description": "unknown client"}`))
+	}))
+	defer srv.Close()
+
+	creds := newOAuth2Creds(&OAuth2Config{TokenURL: srv.URL})
+
+	_, err := creds.GetRequestMetadata(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Contains(t, err.Error(), "invalid_client: unknown client")
+}
+
+func TestOAuth2AuthorizationHeaderConflict(t *testing.T) {
+	cfg := &OAuth2Config{TokenURL: "http://localhost/token"}
+
+	tests := []struct {
+		name    string
+		headers map[string][]string
+		target  string
+	}{
+		{"Header", map[string][]string{"Authorization": {"Bearer token"}}, "localhost:5050"},
+		{"TargetMetadata", nil, "localhost:5050,metadata=authorization:Bearer token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := NewGrpcConnFactory(WithOAuth2(cfg), WithHeaders(test.headers))
+			t.Cleanup(func() { f.Close() })
+
+			_, err := f.GetConn(test.target)
+			assert.EqualError(t, err, "authorization header cannot be combined with OAuth2 or JWT credentials")
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy describes how failed calls are retried,
+// calls are retried only until the first response message is received, the same way gRPC retry policy works
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the original one
+	MaxAttempts    int
+	Codes          []codes.Code
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomizes backoff by up to this fraction in both directions, it should be in [0, 1] range
+	Jitter float64
+}
+
+// RetryAttempt describes a failed call attempt that was retried
+type RetryAttempt struct {
+	Err     error
+	Backoff time.Duration
+}
+
+// ParseRetryCodes converts status code names like UNAVAILABLE or resource_exhausted to codes
+func ParseRetryCodes(names []string) ([]codes.Code, error) {
+	result := make([]codes.Code, 0, len(names))
+	for _, name := range names {
+		var c codes.Code
+		quoted := `"` + strings.ToUpper(strings.TrimSpace(name)) + `"`
+		if err := json.Unmarshal([]byte(quoted), &c); err != nil {
+			return nil, fmt.Errorf("invalid status code %q", name)
+		}
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	code := status.Code(err)
+	for _, c := range p.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns delay before the retry number n starting from 0
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	b := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(n))
+	if p.Jitter > 0 {
+		b *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+
+	// clamped after jitter, so the delay never exceeds max backoff
+	if p.MaxBackoff > 0 && b > float64(p.MaxBackoff) {
+		b = float64(p.MaxBackoff)
+	}
+
+	return time.Duration(max(b, 0))
+}
+
+func retryStreamInterceptor(p *RetryPolicy) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		rs := &retryStream{
+			ctx:    ctx,
+			policy: p,
+			newAttempt: func() (grpc.ClientStream, error) {
+				return streamer(ctx, desc, cc, method, opts...)
+			},
+		}
+
+		for {
+			cs, err := rs.newAttempt()
+			if err == nil {
+				rs.cs = cs
+				return rs, nil
+			}
+
+			if !rs.shouldRetry(err) {
+				return nil, err
+			}
+		}
+	}
+}
+
+// retryStream buffers sent messages to be able to replay them on a new stream
+// if the call fails with retryable status code before the first response message is received
+type retryStream struct {
+	ctx        context.Context
+	policy     *RetryPolicy
+	newAttempt func() (grpc.ClientStream, error)
+
+	mu        sync.Mutex
+	cs        grpc.ClientStream
+	sent      []any
+	closed    bool
+	committed bool
+	attempt   int
+}
+
+// shouldRetry waits for the backoff and returns true if the failed attempt can be retried
+func (rs *retryStream) shouldRetry(err error) bool {
+	rs.attempt++
+	if rs.attempt >= rs.policy.MaxAttempts || !rs.policy.retryable(err) {
+		return false
+	}
+
+	backoff := rs.policy.backoff(rs.attempt - 1)
+	if s := ExtractRpcStats(rs.ctx); s != nil {
+		s.recordAttempt(RetryAttempt{Err: err, Backoff: backoff})
+	}
+
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	select {
+	case <-rs.ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (rs *retryStream) current() grpc.ClientStream {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.cs
+}
+
+func (rs *retryStream) Header() (metadata.MD, error) {
+	return rs.current().Header()
+}
+
+func (rs *retryStream) Trailer() metadata.MD {
+	return rs.current().Trailer()
+}
+
+func (rs *retryStream) Context() context.Context {
+	return rs.current().Context()
+}
+
+func (rs *retryStream) CloseSend() error {
+	rs.mu.Lock()
+	rs.closed = true
+	cs := rs.cs
+	rs.mu.Unlock()
+	return cs.CloseSend()
+}
+
+func (rs *retryStream) SendMsg(m any) error {
+	rs.mu.Lock()
+	if !rs.committed {
+		rs.sent = append(rs.sent, m)
+	}
+	cs := rs.cs
+	rs.mu.Unlock()
+
+	// io.EOF means the stream failed and the actual error is returned by RecvMsg, where the call is retried
+	return cs.SendMsg(m)
+}
+
+func (rs *retryStream) RecvMsg(m any) error {
+	for {
+		rs.mu.Lock()
+		cs, committed := rs.cs, rs.committed
+		rs.mu.Unlock()
+
+		err := cs.RecvMsg(m)
+		if committed || err == io.EOF {
+			return err
+		}
+
+		if err == nil {
+			rs.mu.Lock()
+			rs.committed = true
+			rs.sent = nil
+			rs.mu.Unlock()
+			return nil
+		}
+
+		if !rs.shouldRetry(err) {
+			return err
+		}
+
+		if err := rs.replay(); err != nil {
+			return err
+		}
+	}
+}
+
+// replay starts a new attempt and sends all buffered messages again,
+// the lock is only taken once the attempt is started so SendMsg isn't blocked by backoff waits
+func (rs *retryStream) replay() error {
+	var cs grpc.ClientStream
+	for {
+		var err error
+		cs, err = rs.newAttempt()
+		if err == nil {
+			break
+		}
+		if !rs.shouldRetry(err) {
+			return err
+		}
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.cs = cs
+	for _, m := range rs.sent {
+		// send error means the new attempt failed too, the actual error is returned by RecvMsg
+		if err := rs.cs.SendMsg(m); err != nil {
+			return nil
+		}
+	}
+
+	if rs.closed {
+		return rs.cs.CloseSend()
+	}
+
+	return nil
+}
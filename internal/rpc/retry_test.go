@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseRetryCodes(t *testing.T) {
+	res, err := ParseRetryCodes([]string{"UNAVAILABLE", "resource_exhausted", " ABORTED "})
+	require.NoError(t, err)
+	assert.Equal(t, []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.Aborted}, res)
+
+	_, err = ParseRetryCodes([]string{"UNKNOWN_CODE"})
+	assert.ErrorContains(t, err, "invalid status code")
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, p.backoff(0))
+	assert.Equal(t, 400*time.Millisecond, p.backoff(2))
+	assert.Equal(t, time.Second, p.backoff(10))
+
+	p.Jitter = 0.5
+	for range 100 {
+		b := p.backoff(0)
+		assert.GreaterOrEqual(t, b, 50*time.Millisecond)
+		assert.LessOrEqual(t, b, 150*time.Millisecond)
+
+		assert.LessOrEqual(t, p.backoff(10), time.Second)
+	}
+}
+
+// failingStream fails every RecvMsg with the status code
+type failingStream struct {
+	grpc.ClientStream
+	code codes.Code
+}
+
+func (s *failingStream) SendMsg(any) error        { return nil }
+func (s *failingStream) RecvMsg(any) error        { return status.Error(s.code, "") }
+func (s *failingStream) CloseSend() error         { return nil }
+func (s *failingStream) Context() context.Context { return context.Background() }
+
+func TestRetryStreamBackoffDoesNotHoldLock(t *testing.T) {
+	attempts := 0
+	rs := &retryStream{
+		ctx: context.Background(),
+		policy: &RetryPolicy{
+			MaxAttempts:    3,
+			Codes:          []codes.Code{codes.Unavailable},
+			InitialBackoff: 200 * time.Millisecond,
+			Multiplier:     1,
+		},
+		newAttempt: func() (grpc.ClientStream, error) {
+			attempts++
+			if attempts == 1 {
+				return nil, status.Error(codes.Unavailable, "")
+			}
+			return &failingStream{code: codes.Internal}, nil
+		},
+	}
+	rs.cs = &failingStream{code: codes.Unavailable}
+	rs.attempt = 1
+
+	done := make(chan error)
+	go func() {
+		done <- rs.replay()
+	}()
+
+	// replay waits for the backoff after the failed attempt, the stream stays usable meanwhile
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	assert.NotNil(t, rs.Context())
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	require.NoError(t, <-done)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRetryable(t *testing.T) {
+	p := &RetryPolicy{Codes: []codes.Code{codes.Unavailable}}
+	assert.True(t, p.retryable(status.Error(codes.Unavailable, "")))
+	assert.False(t, p.retryable(status.Error(codes.Internal, "")))
+}
@@ -2,24 +2,52 @@ package rpc
 
 import (
 	"context"
+	"crypto/tls"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/stats"
 )
 
 type statsctxKey struct{}
 
+// MessageStat describes a single received response message
+type MessageStat struct {
+	RecvTime time.Time
+	// Gap is the time since the previous message or since the call start for the first message
+	Gap time.Duration
+	// Size is uncompressed message size
+	Size int
+	// CompressedSize is message size before decompression, the same as Size for uncompressed messages
+	CompressedSize int
+	WireSize       int
+}
+
 type Stats struct {
 	reqHeaders   metadata.MD
 	respHeaders  metadata.MD
 	respTrailers metadata.MD
 	fullMethod   string
+	tlsState     *tls.ConnectionState
+	attempts     []RetryAttempt
+	beginTime    time.Time
+	firstHeader  time.Time
+	messages     []MessageStat
+	connTiming   *ConnTiming
+	traceID      string
 	Duration     time.Duration
 	respSize     atomic.Int64
 	reqSize      atomic.Int64
+	// uncompressed and compressed sizes of messages
+	reqPayloadSize     atomic.Int64
+	reqCompressedSize  atomic.Int64
+	respPayloadSize    atomic.Int64
+	respCompressedSize atomic.Int64
 	sync.RWMutex
 }
 
@@ -31,6 +59,26 @@ func (s *Stats) ReqSize() int64 {
 	return s.reqSize.Load()
 }
 
+// ReqPayloadSize returns uncompressed size of request messages
+func (s *Stats) ReqPayloadSize() int64 {
+	return s.reqPayloadSize.Load()
+}
+
+// ReqCompressedSize returns size of request messages after compression
+func (s *Stats) ReqCompressedSize() int64 {
+	return s.reqCompressedSize.Load()
+}
+
+// RespPayloadSize returns uncompressed size of response messages
+func (s *Stats) RespPayloadSize() int64 {
+	return s.respPayloadSize.Load()
+}
+
+// RespCompressedSize returns size of response messages before decompression
+func (s *Stats) RespCompressedSize() int64 {
+	return s.respCompressedSize.Load()
+}
+
 func (s *Stats) ReqHeaders() metadata.MD {
 	s.RLock()
 	defer s.RUnlock()
@@ -55,17 +103,130 @@ func (s *Stats) FullMethod() string {
 	return s.fullMethod
 }
 
+// TLSState returns negotiated TLS connection parameters, it's nil for insecure connections
+func (s *Stats) TLSState() *tls.ConnectionState {
+	s.RLock()
+	defer s.RUnlock()
+	return s.tlsState
+}
+
+// Attempts returns failed call attempts that were retried
+func (s *Stats) Attempts() []RetryAttempt {
+	s.RLock()
+	defer s.RUnlock()
+	return s.attempts
+}
+
+// BeginTime returns the time when the call started, it's the start of the first attempt for retried calls
+func (s *Stats) BeginTime() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+	return s.beginTime
+}
+
+// TimeToFirstHeader returns time to the first response headers, it's 0 if no headers were received
+func (s *Stats) TimeToFirstHeader() time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	if s.firstHeader.IsZero() {
+		return 0
+	}
+	return s.firstHeader.Sub(s.beginTime)
+}
+
+// ConnTiming returns timing of the connection phases of the connection dialed for the call,
+// it's nil if connection timing is not enabled or the call reused an existing connection
+func (s *Stats) ConnTiming() *ConnTiming {
+	s.RLock()
+	defer s.RUnlock()
+	return s.connTiming
+}
+
+// TraceID returns OpenTelemetry trace id of the call, it's empty if tracing is not enabled
+func (s *Stats) TraceID() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.traceID
+}
+
+func (s *Stats) recordTraceID(id string) {
+	s.Lock()
+	s.traceID = id
+	s.Unlock()
+}
+
+// Messages returns stats of received response messages in the order they arrived
+func (s *Stats) Messages() []MessageStat {
+	s.RLock()
+	defer s.RUnlock()
+	return s.messages
+}
+
+// FirstMessageLatency returns time to the first response message, it's 0 if nothing was received
+func (s *Stats) FirstMessageLatency() time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.messages) == 0 {
+		return 0
+	}
+	return s.messages[0].RecvTime.Sub(s.beginTime)
+}
+
+func (s *Stats) recordMessage(p *stats.InPayload) {
+	s.Lock()
+	defer s.Unlock()
+
+	prev := s.beginTime
+	if n := len(s.messages); n > 0 {
+		prev = s.messages[n-1].RecvTime
+	}
+
+	s.messages = append(s.messages, MessageStat{
+		RecvTime:       p.RecvTime,
+		Gap:            p.RecvTime.Sub(prev),
+		Size:           p.Length,
+		CompressedSize: p.CompressedLength,
+		WireSize:       p.WireLength,
+	})
+}
+
+func (s *Stats) recordAttempt(a RetryAttempt) {
+	s.Lock()
+	s.attempts = append(s.attempts, a)
+	s.Unlock()
+}
+
+func (s *Stats) recordPeer(p *peer.Peer) {
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		s.Lock()
+		s.tlsState = &tlsInfo.State
+		s.Unlock()
+	}
+}
+
 // this method is based on
 // https://github.com/cockroachdb/cockroach/blob/master/pkg/rpc/stats_handler.go
 func (s *Stats) record(rpcStats stats.RPCStats) {
 	switch v := rpcStats.(type) {
+	case *stats.Begin:
+		s.Lock()
+		if s.beginTime.IsZero() {
+			s.beginTime = v.BeginTime
+		}
+		s.Unlock()
 	case *stats.InHeader:
 		s.Lock()
+		if s.firstHeader.IsZero() {
+			s.firstHeader = time.Now()
+		}
 		s.respSize.Add(int64(v.WireLength))
 		s.respHeaders = v.Header.Copy()
 		s.Unlock()
 	case *stats.InPayload:
 		s.respSize.Add(int64(v.WireLength))
+		s.respPayloadSize.Add(int64(v.Length))
+		s.respCompressedSize.Add(int64(v.CompressedLength))
+		s.recordMessage(v)
 	case *stats.InTrailer:
 		s.respSize.Add(int64(v.WireLength))
 		s.Lock()
@@ -79,15 +240,19 @@ func (s *Stats) record(rpcStats stats.RPCStats) {
 		s.Unlock()
 	case *stats.OutPayload:
 		s.reqSize.Add(int64(v.WireLength))
+		s.reqPayloadSize.Add(int64(v.Length))
+		s.reqCompressedSize.Add(int64(v.CompressedLength))
 	case *stats.End:
 		s.Duration = v.EndTime.Sub(v.BeginTime)
 	}
 }
 
-type statsHandler struct{}
+type statsHandler struct {
+	timer *connTimer
+}
 
-func newStatsHanler() stats.Handler {
-	return &statsHandler{}
+func newStatsHanler(timer *connTimer) stats.Handler {
+	return &statsHandler{timer: timer}
 }
 
 func (cs *statsHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) context.Context {
@@ -95,9 +260,27 @@ func (cs *statsHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) conte
 }
 
 func (cs *statsHandler) HandleRPC(ctx context.Context, rpcStats stats.RPCStats) {
-	stats := ExtractRpcStats(ctx)
-	if stats != nil {
-		stats.record(rpcStats)
+	s := ExtractRpcStats(ctx)
+	if s != nil {
+		s.record(rpcStats)
+
+		// transport adds connection peer to the context of outgoing headers
+		if h, ok := rpcStats.(*stats.OutHeader); ok {
+			if p, ok := peer.FromContext(ctx); ok {
+				s.recordPeer(p)
+			}
+
+			// the connection is established when headers are sent, retried attempts keep timing
+			// of the connection that was dialed for the call, reflection calls done before the service call
+			// usually dial the connection, so its timing is left for the service call
+			if cs.timer != nil && !strings.HasPrefix(h.FullMethod, "/grpc.reflection.") {
+				if ct := cs.timer.take(); ct != nil {
+					s.Lock()
+					s.connTiming = ct
+					s.Unlock()
+				}
+			}
+		}
 	}
 }
 
@@ -105,6 +288,9 @@ func (cs *statsHandler) TagConn(ctx context.Context, cti *stats.ConnTagInfo) con
 	return ctx
 }
 
+// HandleConn doesn't record connection timing: ConnBegin and ConnEnd events have the context of the connection,
+// not of a call, so there is no call stats to attach the timing to. The dialer and resolver measure the timing instead
+// and HandleRPC takes it on OutHeader, the first event of the call that is sent over the dialed connection
 func (cs *statsHandler) HandleConn(context.Context, stats.ConnStats) {
 }
 
@@ -10,7 +10,7 @@ import (
 
 func TestRPCStatsRecording(t *testing.T) {
 	ctx := WithStatsCtx(context.Background())
-	h := newStatsHanler()
+	h := newStatsHanler(nil)
 
 	begin := time.Now()
 	end := begin.Add(1 * time.Second)
@@ -53,3 +53,47 @@ func TestRPCStatsRecording(t *testing.T) {
 		t.Errorf("invalid resp size: %d, expected: %d", s.RespSize(), expectedRespSize)
 	}
 }
+
+func TestRPCStatsMessages(t *testing.T) {
+	ctx := WithStatsCtx(context.Background())
+	h := newStatsHanler(nil)
+
+	begin := time.Now()
+	h.HandleRPC(ctx, &stats.Begin{BeginTime: begin})
+
+	recvTimes := []time.Time{begin.Add(50 * time.Millisecond), begin.Add(60 * time.Millisecond), begin.Add(260 * time.Millisecond)}
+	for i, rt := range recvTimes {
+		h.HandleRPC(ctx, &stats.InPayload{
+			RecvTime:   rt,
+			Length:     10 * (i + 1),
+			WireLength: 10*(i+1) + 5,
+		})
+	}
+
+	// retried attempt doesn't change the call start
+	h.HandleRPC(ctx, &stats.Begin{BeginTime: begin.Add(time.Second)})
+
+	s := ExtractRpcStats(ctx)
+	if s.BeginTime() != begin {
+		t.Errorf("invalid begin time: %v, expected %v", s.BeginTime(), begin)
+	}
+
+	if s.FirstMessageLatency() != 50*time.Millisecond {
+		t.Errorf("invalid first message latency: %v", s.FirstMessageLatency())
+	}
+
+	msgs := s.Messages()
+	if len(msgs) != len(recvTimes) {
+		t.Fatalf("invalid number of messages: %d, expected %d", len(msgs), len(recvTimes))
+	}
+
+	expGaps := []time.Duration{50 * time.Millisecond, 10 * time.Millisecond, 200 * time.Millisecond}
+	for i, m := range msgs {
+		if m.Gap != expGaps[i] {
+			t.Errorf("invalid gap of message %d: %v, expected %v", i, m.Gap, expGaps[i])
+		}
+		if m.Size != 10*(i+1) || m.WireSize != 10*(i+1)+5 {
+			t.Errorf("invalid size of message %d: %d/%d", i, m.Size, m.WireSize)
+		}
+	}
+}
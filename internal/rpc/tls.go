@@ -0,0 +1,400 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+	"google.golang.org/grpc/credentials"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const (
+	// spkiPinPrefix is used for base64 encoded SHA-256 hash of the certificate public key, the same format curl and HPKP use
+	spkiPinPrefix = "sha256/"
+	// certPinPrefix is used for hex encoded SHA-256 fingerprint of the whole certificate
+	certPinPrefix = "cert-sha256/"
+)
+
+// TLSOptions contains additional TLS settings applied on top of WithConnCred
+type TLSOptions struct {
+	// ServerName overrides the name used for SNI and server certificate verification
+	ServerName   string
+	MinVersion   uint16
+	MaxVersion   uint16
+	CipherSuites []uint16
+	// AppendSystemCAs adds CA certificate to the system pool instead of replacing it
+	AppendSystemCAs bool
+	CertKeyPassword string
+	// PKCS12 is the client certificate bundle, it's used instead of cert and key files
+	PKCS12         string
+	PKCS12Password string
+	// Pins are sha256/<base64 SPKI hash> or cert-sha256/<hex certificate fingerprint> values,
+	// at least one certificate in the server chain must match one of them
+	Pins []string
+}
+
+// WithTLSOptions sets additional TLS settings, it only has effect with WithConnCred
+func WithTLSOptions(o *TLSOptions) ConnFactoryOption {
+	return func(s *GrpcConnFactorySettings) {
+		s.tlsOpts = o
+	}
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion converts version like 1.2 to its tls package constant
+func ParseTLSVersion(v string) (uint16, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(v), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q, supported versions: 1.0, 1.1, 1.2, 1.3", v)
+	}
+
+	return version, nil
+}
+
+// ParseCipherSuites converts IANA cipher suite names to their ids
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	suites := map[string]uint16{}
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := suites[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func getCredentials(s *GrpcConnFactorySettings) (credentials.TransportCredentials, error) {
+	cfg, err := newTLSConfig(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tlsCreds{TransportCredentials: credentials.NewTLS(cfg.base), cfg: cfg}, nil
+}
+
+// tlsCreds creates TLS credentials with the current CA certificates on every handshake,
+// so reconnects pick up rotated CA certificate without restarting the tool
+type tlsCreds struct {
+	credentials.TransportCredentials
+	cfg *tlsConfig
+}
+
+func (c *tlsCreds) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	// the same as grpc, :authority is used as TLS server name
+	serverName, _, err := net.SplitHostPort(authority)
+	if err != nil {
+		serverName = authority
+	}
+
+	return credentials.NewTLS(c.cfg.forServer(serverName)).ClientHandshake(ctx, authority, rawConn)
+}
+
+func (c *tlsCreds) Clone() credentials.TransportCredentials {
+	return &tlsCreds{TransportCredentials: c.TransportCredentials.Clone(), cfg: c.cfg}
+}
+
+// tlsConfig creates TLS configs for new connections, the server certificate is checked
+// by the standard handshake verification using CA certificates reloaded when the file changes
+type tlsConfig struct {
+	base  *tls.Config
+	roots func() *x509.CertPool
+	pins  func(tls.ConnectionState) error
+}
+
+// forServer returns the config for a new connection, the server name is used for SNI and
+// certificate verification unless it's set with TLSOptions.ServerName
+func (c *tlsConfig) forServer(serverName string) *tls.Config {
+	cfg := c.base.Clone()
+	cfg.RootCAs = c.roots()
+	if cfg.ServerName == "" {
+		cfg.ServerName = serverName
+	}
+	return cfg
+}
+
+// verify checks the server certificates after the handshake done without verification
+func (c *tlsConfig) verify(cs tls.ConnectionState, serverName string) error {
+	if !c.base.InsecureSkipVerify {
+		if err := verifyChain(cs.PeerCertificates, c.roots(), serverName); err != nil {
+			return err
+		}
+	}
+
+	if c.pins != nil {
+		return c.pins(cs)
+	}
+	return nil
+}
+
+func newTLSConfig(s *GrpcConnFactorySettings) (*tlsConfig, error) {
+	opts := s.tlsOpts
+	if opts == nil {
+		opts = &TLSOptions{}
+	}
+
+	cfg := &tlsConfig{
+		base: &tls.Config{
+			ServerName:         opts.ServerName,
+			MinVersion:         opts.MinVersion,
+			MaxVersion:         opts.MaxVersion,
+			CipherSuites:       opts.CipherSuites,
+			InsecureSkipVerify: s.insecure,
+		},
+		// nil pool means system CA certificates
+		roots: func() *x509.CertPool { return nil },
+	}
+
+	if s.caCert != "" && !s.insecure {
+		r, err := newReloader(func() (*x509.CertPool, error) {
+			return loadCertPool(s.caCert, opts.AppendSystemCAs)
+		}, s.caCert)
+		if err != nil {
+			return nil, err
+		}
+		cfg.roots = r.get
+	}
+
+	if len(opts.Pins) > 0 {
+		verify, err := pinVerifier(opts.Pins)
+		if err != nil {
+			return nil, err
+		}
+		cfg.pins = verify
+		cfg.base.VerifyConnection = verify
+	}
+
+	var clientCert *reloader[tls.Certificate]
+	var err error
+	if opts.PKCS12 != "" {
+		if s.cert != "" || s.certKey != "" {
+			return nil, errors.New("pkcs12 bundle cannot be used together with cert and certKey")
+		}
+
+		clientCert, err = newReloader(func() (tls.Certificate, error) {
+			return loadPKCS12(opts.PKCS12, opts.PKCS12Password)
+		}, opts.PKCS12)
+		if err != nil {
+			return nil, err
+		}
+	} else if s.cert != "" && s.certKey != "" {
+		clientCert, err = newReloader(func() (tls.Certificate, error) {
+			certificate, err := loadKeyPair(s.cert, s.certKey, opts.CertKeyPassword)
+			if err != nil {
+				return tls.Certificate{}, fmt.Errorf("failed to read the client certificate: %w", err)
+			}
+			return certificate, nil
+		}, s.cert, s.certKey)
+		if err != nil {
+			return nil, err
+		}
+	} else if s.cert != "" || s.certKey != "" {
+		return nil, errors.New("both cert and certKey need to be specified")
+	}
+
+	if clientCert != nil {
+		// the certificate is requested on every handshake, so reconnects use rotated certificate
+		cfg.base.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate := clientCert.get()
+			return &certificate, nil
+		}
+	}
+
+	return cfg, nil
+}
+
+func verifyChain(certs []*x509.Certificate, roots *x509.CertPool, serverName string) error {
+	if len(certs) == 0 {
+		return errors.New("server didn't present any certificates")
+	}
+
+	if serverName == "" {
+		return errors.New("server name is required to verify the server certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       serverName,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func loadKeyPair(cert, certKey, password string) (tls.Certificate, error) {
+	if password == "" {
+		return tls.LoadX509KeyPair(cert, certKey)
+	}
+
+	certPEM, err := os.ReadFile(cert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPEM, err := os.ReadFile(certKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPEM, err = decryptKey(keyPEM, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// decryptKey decrypts PKCS#8 or legacy OpenSSL encrypted PEM private key,
+// unencrypted keys are returned as is
+func decryptKey(keyPEM []byte, password string) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("failed to find PEM block in the private key")
+	}
+
+	var der []byte
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		key, err := pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt the private key: %w", err)
+		}
+
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	case x509.IsEncryptedPEMBlock(block):
+		// legacy OpenSSL encryption is insecure and deprecated, but such keys are still common
+		var err error
+		der, err = x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt the private key: %w", err)
+		}
+		block = &pem.Block{Type: block.Type, Bytes: der}
+	default:
+		return keyPEM, nil
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+func loadPKCS12(file, password string) (tls.Certificate, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read the pkcs12 bundle: %w", err)
+	}
+
+	key, leaf, caCerts, err := pkcs12.DecodeChain(b, password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to decode the pkcs12 bundle: %w", err)
+	}
+
+	certificate := tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+
+	for _, c := range caCerts {
+		certificate.Certificate = append(certificate.Certificate, c.Raw)
+	}
+
+	return certificate, nil
+}
+
+type certPin struct {
+	spki bool
+	hash []byte
+}
+
+func parsePin(pin string) (certPin, error) {
+	if v, ok := strings.CutPrefix(pin, spkiPinPrefix); ok {
+		hash, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(hash) != sha256.Size {
+			return certPin{}, fmt.Errorf("invalid pin %q, base64 encoded SHA-256 hash is expected", pin)
+		}
+		return certPin{spki: true, hash: hash}, nil
+	}
+
+	if v, ok := strings.CutPrefix(pin, certPinPrefix); ok {
+		hash, err := hex.DecodeString(strings.ReplaceAll(v, ":", ""))
+		if err != nil || len(hash) != sha256.Size {
+			return certPin{}, fmt.Errorf("invalid pin %q, hex encoded SHA-256 fingerprint is expected", pin)
+		}
+		return certPin{hash: hash}, nil
+	}
+
+	return certPin{}, fmt.Errorf("invalid pin %q, it should start with %s or %s", pin, spkiPinPrefix, certPinPrefix)
+}
+
+func (p certPin) match(c *x509.Certificate) bool {
+	var hash [sha256.Size]byte
+	if p.spki {
+		hash = sha256.Sum256(c.RawSubjectPublicKeyInfo)
+	} else {
+		hash = sha256.Sum256(c.Raw)
+	}
+	return bytes.Equal(p.hash, hash[:])
+}
+
+// pinVerifier returns tls.Config.VerifyConnection func that requires one of the certificates
+// presented by the server to match any of the pins, it's checked even when --insecure is used
+func pinVerifier(pins []string) (func(tls.ConnectionState) error, error) {
+	parsed := make([]certPin, 0, len(pins))
+	for _, pin := range pins {
+		p, err := parsePin(pin)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+
+	return func(cs tls.ConnectionState) error {
+		for _, c := range cs.PeerCertificates {
+			for _, p := range parsed {
+				if p.match(c) {
+					return nil
+				}
+			}
+		}
+		return errors.New("server certificate doesn't match any of the pins")
+	}, nil
+}
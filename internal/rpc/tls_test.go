@@ -0,0 +1,243 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const (
+	testCACert     = "../../testdata/certs/test_ca.crt"
+	testClientCert = "../../testdata/certs/test_client.crt"
+	testClientKey  = "../../testdata/certs/test_client.key"
+	testServerCert = "../../testdata/certs/test_server.crt"
+	testServerKey  = "../../testdata/certs/test_server.key"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	v, err := ParseTLSVersion("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+
+	v, err = ParseTLSVersion("TLS1.2")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), v)
+
+	v, err = ParseTLSVersion("")
+	require.NoError(t, err)
+	assert.Zero(t, v)
+
+	_, err = ParseTLSVersion("1.4")
+	assert.Error(t, err)
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ParseCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "tls_rsa_with_aes_128_cbc_sha"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_128_CBC_SHA}, ids)
+
+	_, err = ParseCipherSuites([]string{"TLS_UNKNOWN"})
+	assert.ErrorContains(t, err, "unsupported cipher suite")
+}
+
+func readTestKey(t *testing.T) any {
+	b, err := os.ReadFile(testClientKey)
+	require.NoError(t, err)
+	keyPair, err := tls.X509KeyPair(mustRead(t, testClientCert), b)
+	require.NoError(t, err)
+	return keyPair.PrivateKey
+}
+
+func mustRead(t *testing.T, file string) []byte {
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	return b
+}
+
+func writeFile(t *testing.T, name string, b []byte) string {
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, b, 0o600))
+	return file
+}
+
+func TestLoadEncryptedKey(t *testing.T) {
+	key := readTestKey(t)
+
+	der, err := pkcs8.MarshalPrivateKey(key, []byte("secret"), nil)
+	require.NoError(t, err)
+	pkcs8Key := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der})
+
+	legacyBlock, err := x509.EncryptPEMBlock(rand.Reader, "PRIVATE KEY", mustMarshalPKCS8(t, key), []byte("secret"), x509.PEMCipherAES256)
+	require.NoError(t, err)
+	legacyKey := pem.EncodeToMemory(legacyBlock)
+
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"PKCS8", pkcs8Key},
+		{"Legacy", legacyKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyFile := writeFile(t, "key.pem", tt.key)
+
+			cert, err := loadKeyPair(testClientCert, keyFile, "secret")
+			require.NoError(t, err)
+			assert.Equal(t, key, cert.PrivateKey)
+
+			_, err = loadKeyPair(testClientCert, keyFile, "wrong")
+			assert.Error(t, err)
+
+			_, err = loadKeyPair(testClientCert, keyFile, "")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func mustMarshalPKCS8(t *testing.T, key any) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return der
+}
+
+func TestLoadPKCS12(t *testing.T) {
+	key := readTestKey(t)
+	block, _ := pem.Decode(mustRead(t, testClientCert))
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	pfx, err := pkcs12.Modern.Encode(key, leaf, nil, "secret")
+	require.NoError(t, err)
+	file := writeFile(t, "client.p12", pfx)
+
+	cert, err := loadPKCS12(file, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, key, cert.PrivateKey)
+	assert.Equal(t, [][]byte{leaf.Raw}, cert.Certificate)
+
+	_, err = loadPKCS12(file, "wrong")
+	assert.ErrorContains(t, err, "failed to decode the pkcs12 bundle")
+
+	_, err = newTLSConfig(&GrpcConnFactorySettings{
+		cert:    testClientCert,
+		certKey: testClientKey,
+		tlsOpts: &TLSOptions{PKCS12: file},
+	})
+	assert.ErrorContains(t, err, "cannot be used together")
+}
+
+func TestPinVerifier(t *testing.T) {
+	block, _ := pem.Decode(mustRead(t, testServerCert))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	fingerprint := sha256.Sum256(cert.Raw)
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	otherPin := "sha256/" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name  string
+		pins  []string
+		match bool
+	}{
+		{"SPKI", []string{"sha256/" + base64.StdEncoding.EncodeToString(spki[:])}, true},
+		{"Certificate", []string{"cert-sha256/" + hex.EncodeToString(fingerprint[:])}, true},
+		{"AnyPin", []string{otherPin, "sha256/" + base64.StdEncoding.EncodeToString(spki[:])}, true},
+		{"NoMatch", []string{otherPin}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verify, err := pinVerifier(tt.pins)
+			require.NoError(t, err)
+
+			err = verify(state)
+			if tt.match {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	_, err = pinVerifier([]string{"md5/abc"})
+	assert.ErrorContains(t, err, "invalid pin")
+}
+
+func TestServerNameVerification(t *testing.T) {
+	tests := []struct {
+		name   string
+		cert   string
+		key    string
+		verify bool
+	}{
+		// the server certificate has 127.0.0.1 IP address SAN
+		{"IPAddressSAN", testServerCert, testServerKey, true},
+		// the client certificate is issued by the same CA, but it's not valid for any host
+		{"NoIPAddressSAN", testClientCert, testClientKey, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := tls.LoadX509KeyPair(tt.cert, tt.key)
+			require.NoError(t, err)
+
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			srv.EnableHTTP2 = true
+			srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+			srv.StartTLS()
+			defer srv.Close()
+
+			addr := srv.Listener.Addr().String()
+			f := NewGrpcConnFactory(WithConnCred(false, testCACert, "", ""), WithProtocol(ProtocolGRPCWeb))
+			defer f.Close()
+
+			creds, err := getCredentials(f.settings)
+			require.NoError(t, err)
+			conn, err := net.Dial("tcp", addr)
+			require.NoError(t, err)
+			defer conn.Close()
+			_, _, grpcErr := creds.ClientHandshake(context.Background(), addr, conn)
+
+			c, err := f.newHTTPConn(addr)
+			require.NoError(t, err)
+			defer c.close()
+			resp, httpErr := c.client.Get(c.baseURL)
+			if httpErr == nil {
+				resp.Body.Close()
+			}
+
+			info, err := f.TLSHandshake(context.Background(), addr)
+			require.NoError(t, err)
+
+			if tt.verify {
+				assert.NoError(t, grpcErr)
+				assert.NoError(t, httpErr)
+				assert.NoError(t, info.VerifyErr)
+			} else {
+				assert.ErrorContains(t, grpcErr, "127.0.0.1")
+				assert.ErrorContains(t, httpErr, "127.0.0.1")
+				assert.ErrorContains(t, info.VerifyErr, "127.0.0.1")
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+)
+
+// TLSInfo contains the result of TLS handshake with the server
+type TLSInfo struct {
+	ServerName string
+	State      tls.ConnectionState
+	// VerifyErr is certificate chain, host name or pin verification error,
+	// the handshake itself succeeds even if the server certificate is not trusted
+	VerifyErr error
+}
+
+// TLSHandshake connects to the target using the factory TLS settings and returns negotiated connection parameters,
+// only plain host:port targets (optionally with dns scheme) are supported
+func (f *GrpcConnFactory) TLSHandshake(ctx context.Context, target string) (*TLSInfo, error) {
+	connOpts, err := NewConnectionOpts(target)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := connOpts.Host
+	if scheme, rest, ok := strings.Cut(addr, "://"); ok {
+		if scheme != "dns" {
+			return nil, errors.New("only host:port or dns:///host:port targets are supported")
+		}
+		addr = strings.TrimPrefix(rest, "/")
+	}
+
+	cfg, err := newTLSConfig(f.settings)
+	if err != nil {
+		return nil, err
+	}
+
+	serverName := cfg.base.ServerName
+	if serverName == "" {
+		serverName = f.settings.authority
+	}
+	if serverName == "" {
+		serverName = connOpts.Authority
+	}
+	if serverName == "" {
+		serverName = addr
+	}
+	if h, _, err := net.SplitHostPort(serverName); err == nil {
+		serverName = h
+	}
+
+	// verification is done after the handshake to be able to inspect untrusted certificates
+	tlsCfg := cfg.forServer(serverName)
+	tlsCfg.InsecureSkipVerify = true
+	tlsCfg.VerifyConnection = nil
+	tlsCfg.NextProtos = []string{"h2"}
+
+	d := tls.Dialer{Config: tlsCfg}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	info := &TLSInfo{
+		ServerName: serverName,
+		State:      conn.(*tls.Conn).ConnectionState(),
+	}
+
+	info.VerifyErr = cfg.verify(info.State, serverName)
+
+	return info, nil
+}
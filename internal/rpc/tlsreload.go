@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileVersion identifies the state of a file on disk
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// reloader caches the value loaded from files and loads it again when any of the files change,
+// so rotated certificates are picked up by new TLS handshakes without restarting the tool
+type reloader[T any] struct {
+	files []string
+	load  func() (T, error)
+
+	mu       sync.Mutex
+	versions []fileVersion
+	value    T
+}
+
+func newReloader[T any](load func() (T, error), files ...string) (*reloader[T], error) {
+	r := &reloader[T]{
+		files: files,
+		load:  load,
+	}
+
+	r.versions = r.stat()
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	r.value = value
+	return r, nil
+}
+
+func (r *reloader[T]) stat() []fileVersion {
+	versions := make([]fileVersion, len(r.files))
+	for i, f := range r.files {
+		if fi, err := os.Stat(f); err == nil {
+			versions[i] = fileVersion{modTime: fi.ModTime(), size: fi.Size()}
+		}
+	}
+	return versions
+}
+
+// get returns the current value, if files were changed but cannot be loaded
+// (for example only one file of cert and key pair is rotated yet) the previous value is returned
+func (r *reloader[T]) get() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := r.stat()
+	changed := false
+	for i := range versions {
+		if versions[i] != r.versions[i] {
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return r.value
+	}
+
+	r.versions = versions
+	value, err := r.load()
+	if err != nil {
+		log.Printf("failed to reload TLS files, using previous version: %v", err)
+		return r.value
+	}
+
+	r.value = value
+	return value
+}
+
+func loadCertPool(caCert string, appendSystemCAs bool) (*x509.CertPool, error) {
+	b, err := os.ReadFile(caCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CA certificate: %w", err)
+	}
+
+	cp := x509.NewCertPool()
+	if appendSystemCAs {
+		cp, err = x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system CA certificates: %w", err)
+		}
+	}
+
+	if !cp.AppendCertsFromPEM(b) {
+		return nil, errors.New("failed to append the client certificate")
+	}
+
+	return cp, nil
+}
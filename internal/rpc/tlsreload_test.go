@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotate replaces the file content and moves its modification time forward
+// so the change is detected even on file systems with coarse timestamps
+func rotate(t *testing.T, dst string, src string) {
+	require.NoError(t, os.WriteFile(dst, mustRead(t, src), 0o600))
+	mtime := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(dst, mtime, mtime))
+}
+
+func copyFile(t *testing.T, dir, src string) string {
+	dst := filepath.Join(dir, filepath.Base(src))
+	require.NoError(t, os.WriteFile(dst, mustRead(t, src), 0o600))
+	return dst
+}
+
+func TestReloader(t *testing.T) {
+	file := writeFile(t, "value", []byte("v1"))
+	loads := 0
+	r, err := newReloader(func() (string, error) {
+		loads++
+		b, err := os.ReadFile(file)
+		if string(b) == "broken" {
+			return "", assert.AnError
+		}
+		return string(b), err
+	}, file)
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1", r.get())
+	assert.Equal(t, 1, loads)
+
+	require.NoError(t, os.WriteFile(file, []byte("v2"), 0o600))
+	require.NoError(t, os.Chtimes(file, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+	assert.Equal(t, "v2", r.get())
+
+	// invalid content keeps the previous value
+	require.NoError(t, os.WriteFile(file, []byte("broken"), 0o600))
+	require.NoError(t, os.Chtimes(file, time.Now().Add(2*time.Hour), time.Now().Add(2*time.Hour)))
+	assert.Equal(t, "v2", r.get())
+	assert.Equal(t, 3, loads)
+
+	// no changes, no reloads
+	r.get()
+	assert.Equal(t, 3, loads)
+}
+
+func TestClientCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	cert := copyFile(t, dir, testClientCert)
+	key := copyFile(t, dir, testClientKey)
+
+	cfg, err := newTLSConfig(&GrpcConnFactorySettings{cert: cert, certKey: key})
+	require.NoError(t, err)
+
+	c, err := cfg.base.GetClientCertificate(&tls.CertificateRequestInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "test_client", leafSubject(t, c))
+
+	rotate(t, cert, "../../testdata/certs/other_client.crt")
+	rotate(t, key, "../../testdata/certs/other_client.key")
+
+	c, err = cfg.base.GetClientCertificate(&tls.CertificateRequestInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "other_client", leafSubject(t, c))
+}
+
+func TestCACertificateReload(t *testing.T) {
+	caCert := copyFile(t, t.TempDir(), "../../testdata/certs/other_ca.crt")
+
+	cfg, err := newTLSConfig(&GrpcConnFactorySettings{caCert: caCert})
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(mustRead(t, testServerCert))
+	serverCert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{serverCert}}
+
+	assert.Error(t, cfg.verify(state, "localhost"))
+
+	rotate(t, caCert, "../../testdata/certs/test_ca.crt")
+	assert.NoError(t, cfg.verify(state, "localhost"))
+}
+
+func leafSubject(t *testing.T, c *tls.Certificate) string {
+	leaf, err := x509.ParseCertificate(c.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
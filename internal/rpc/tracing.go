@@ -0,0 +1,241 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultTracingServiceName = "grpc-client-cli"
+	tracingShutdownTimeout    = 5 * time.Second
+)
+
+// TracingConfig describes where client spans are exported,
+// at least one of Endpoint or File should be set
+type TracingConfig struct {
+	// Endpoint is OTLP gRPC collector address, e.g. collector:4317 uses TLS, http://localhost:4317 is plaintext
+	Endpoint string
+	// File is the path to the file where spans are appended as JSON objects
+	File        string
+	ServiceName string
+	// Baggage is propagated to the server in baggage header
+	Baggage map[string]string
+}
+
+type tracing struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	baggage    baggage.Baggage
+	file       *os.File
+}
+
+func newTracing(ctx context.Context, cfg *TracingConfig) (*tracing, error) {
+	members := make([]baggage.Member, 0, len(cfg.Baggage))
+	for k, v := range cfg.Baggage {
+		m, err := baggage.NewMemberRaw(k, v)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	bag, err := baggage.New(members...)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultTracingServiceName
+	}
+
+	t := &tracing{
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		baggage:    bag,
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	}
+
+	if cfg.File != "" {
+		t.file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(t.file))
+		if err != nil {
+			t.file.Close()
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithSyncer(exp))
+	}
+
+	if cfg.Endpoint != "" {
+		var expOpts []otlptracegrpc.Option
+		if strings.Contains(cfg.Endpoint, "://") {
+			expOpts = append(expOpts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		} else {
+			expOpts = append(expOpts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+
+		exp, err := otlptracegrpc.New(ctx, expOpts...)
+		if err != nil {
+			if t.file != nil {
+				t.file.Close()
+			}
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+
+	t.provider = sdktrace.NewTracerProvider(opts...)
+	t.tracer = t.provider.Tracer("github.com/vadimi/grpc-client-cli")
+	return t, nil
+}
+
+// shutdown flushes pending spans
+func (t *tracing) shutdown(ctx context.Context) error {
+	err := t.provider.Shutdown(ctx)
+	if t.file != nil {
+		err = errors.Join(err, t.file.Close())
+	}
+	return err
+}
+
+// start creates client span and injects trace context and baggage into outgoing metadata
+func (t *tracing) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	if t.baggage.Len() > 0 {
+		ctx = baggage.ContextWithBaggage(ctx, t.baggage)
+	}
+
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx, span := t.tracer.Start(ctx, service+"/"+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", name),
+		),
+	)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	t.propagator.Inject(ctx, metadataCarrier(md))
+
+	if s := ExtractRpcStats(ctx); s != nil {
+		s.recordTraceID(span.SpanContext().TraceID().String())
+	}
+
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+func endSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if err != nil {
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+}
+
+// reflection calls are made by the tool itself and are not traced
+func isReflectionMethod(method string) bool {
+	return strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+func tracingUnaryInterceptor(t *tracing) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if isReflectionMethod(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		ctx, span := t.start(ctx, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func tracingStreamInterceptor(t *tracing) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if isReflectionMethod(method) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		ctx, span := t.start(ctx, method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			endSpan(span, err)
+			return nil, err
+		}
+
+		return &tracedStream{ClientStream: cs, span: span}, nil
+	}
+}
+
+// tracedStream ends the span when the stream is finished
+type tracedStream struct {
+	grpc.ClientStream
+	span trace.Span
+	once sync.Once
+}
+
+func (s *tracedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			if err == io.EOF {
+				endSpan(s.span, nil)
+				return
+			}
+			endSpan(s.span, err)
+		})
+	}
+	return err
+}
+
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
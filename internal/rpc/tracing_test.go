@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTracingInterceptor(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "spans.json")
+	tr, err := newTracing(context.Background(), &TracingConfig{
+		File:    file,
+		Baggage: map[string]string{"tenant": "test"},
+	})
+	require.NoError(t, err)
+
+	var md metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return status.Error(codes.NotFound, "not found")
+	}
+
+	ctx := WithStatsCtx(metadata.AppendToOutgoingContext(context.Background(), "x-test", "1"))
+	err = tracingUnaryInterceptor(tr)(ctx, "/pkg.Service/Method", nil, nil, nil, invoker)
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	traceID := ExtractRpcStats(ctx).TraceID()
+	require.Len(t, traceID, 32)
+	require.Len(t, md.Get("traceparent"), 1)
+	assert.Contains(t, md.Get("traceparent")[0], traceID)
+	assert.Equal(t, []string{"tenant=test"}, md.Get("baggage"))
+	assert.Equal(t, []string{"1"}, md.Get("x-test"))
+
+	require.NoError(t, tr.shutdown(context.Background()))
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	var span struct {
+		Name        string
+		SpanContext struct{ TraceID string }
+		Status      struct{ Code string }
+	}
+	require.NoError(t, json.Unmarshal(b, &span))
+	assert.Equal(t, "pkg.Service/Method", span.Name)
+	assert.Equal(t, traceID, span.SpanContext.TraceID)
+	assert.Equal(t, "Error", span.Status.Code)
+}
+
+// messagesStream returns n messages and then io.EOF
+type messagesStream struct {
+	grpc.ClientStream
+	n int
+}
+
+func (s *messagesStream) RecvMsg(any) error {
+	if s.n == 0 {
+		return io.EOF
+	}
+	s.n--
+	return nil
+}
+
+func TestTracingStreamInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream grpc.ClientStream
+		status string
+	}{
+		{"OK", &messagesStream{n: 2}, "Unset"},
+		{"Error", &failingStream{code: codes.Unavailable}, "Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "spans.json")
+			tr, err := newTracing(context.Background(), &TracingConfig{File: file})
+			require.NoError(t, err)
+
+			var md metadata.MD
+			streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				md, _ = metadata.FromOutgoingContext(ctx)
+				return tt.stream, nil
+			}
+
+			ctx := WithStatsCtx(context.Background())
+			cs, err := tracingStreamInterceptor(tr)(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/pkg.Service/Stream", streamer)
+			require.NoError(t, err)
+
+			traceID := ExtractRpcStats(ctx).TraceID()
+			require.Len(t, md.Get("traceparent"), 1)
+			assert.Contains(t, md.Get("traceparent")[0], traceID)
+
+			for cs.RecvMsg(nil) == nil {
+			}
+			// the span is ended only once
+			cs.RecvMsg(nil)
+
+			require.NoError(t, tr.shutdown(context.Background()))
+
+			b, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			var span struct {
+				Name        string
+				SpanContext struct{ TraceID string }
+				Status      struct{ Code string }
+			}
+			dec := json.NewDecoder(bytes.NewReader(b))
+			require.NoError(t, dec.Decode(&span))
+			assert.False(t, dec.More(), "only one span is expected")
+			assert.Equal(t, "pkg.Service/Stream", span.Name)
+			assert.Equal(t, traceID, span.SpanContext.TraceID)
+			assert.Equal(t, tt.status, span.Status.Code)
+		})
+	}
+}
+
+func TestTracingSkipsReflection(t *testing.T) {
+	tr, err := newTracing(context.Background(), &TracingConfig{File: filepath.Join(t.TempDir(), "spans.json")})
+	require.NoError(t, err)
+	defer tr.shutdown(context.Background())
+
+	var md metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	err = tracingUnaryInterceptor(tr)(context.Background(), "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", nil, nil, nil, invoker)
+	require.NoError(t, err)
+	assert.Empty(t, md.Get("traceparent"))
+}
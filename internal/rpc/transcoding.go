@@ -0,0 +1,570 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vadimi/grpc-client-cli/internal/jsonnum"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+// HTTP/JSON transcoding rules are described in https://github.com/googleapis/googleapis/blob/master/google/api/http.proto,
+// responses and errors are decoded the same way as grpc-gateway encodes them
+const grpcGatewayTrailerPrefix = "Grpc-Trailer-"
+
+func newTranscodingStream(ctx context.Context, c *httpConn, desc *grpc.StreamDesc, method string) (grpc.ClientStream, error) {
+	if desc.ClientStreams {
+		return nil, status.Error(codes.Unimplemented, "http: "+errClientStreaming.Error())
+	}
+
+	md, err := findMethodDescriptor(method)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := httpRule(md)
+	if err != nil {
+		return nil, err
+	}
+
+	types := dynamicpb.NewTypes(protoregistry.GlobalFiles)
+	s := &transcodingStream{
+		httpStream: newHTTPStream(ctx, c, method, ProtocolHTTP),
+		rule:       rule,
+		streaming:  desc.ServerStreams,
+		marshal:    protojson.MarshalOptions{UseProtoNames: true, Resolver: types},
+		unmarshal:  protojson.UnmarshalOptions{DiscardUnknown: true, Resolver: types},
+	}
+	s.send = s.do
+
+	return s, nil
+}
+
+// transcodingStream performs HTTP call described by google.api.http rule of the method,
+// server streaming responses are expected to be newline delimited JSON objects
+type transcodingStream struct {
+	*httpStream
+	rule      *annotations.HttpRule
+	streaming bool
+	marshal   protojson.MarshalOptions
+	unmarshal protojson.UnmarshalOptions
+
+	req         *http.Request
+	respTrailer metadata.MD
+	body        *json.Decoder
+
+	// received is used by RecvMsg only
+	received bool
+}
+
+func (s *transcodingStream) SendMsg(m any) error {
+	if s.req != nil || s.err != nil {
+		return status.Error(codes.Internal, "http: only a single request message is supported")
+	}
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "http: unsupported message type %T", m)
+	}
+
+	req, bodySize, err := s.newRequest(msg)
+	if err != nil {
+		s.err = err
+		return err
+	}
+	s.req = req
+
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.OutPayload{
+			Client:           true,
+			Length:           bodySize,
+			CompressedLength: bodySize,
+			WireLength:       bodySize,
+			SentTime:         time.Now(),
+		})
+	})
+
+	return nil
+}
+
+func (s *transcodingStream) RecvMsg(m any) error {
+	if err := s.waitResponse(); err != nil {
+		return err
+	}
+
+	if s.err != nil {
+		return s.finish(s.respTrailer, status.Convert(s.err))
+	}
+
+	var payload json.RawMessage
+	if s.streaming {
+		var chunk struct {
+			Result json.RawMessage `json:"result"`
+			Error  json.RawMessage `json:"error"`
+		}
+
+		if err := s.body.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				return s.finish(s.respTrailer, status.New(codes.OK, ""))
+			}
+			return s.finish(nil, s.readError(err))
+		}
+
+		if len(chunk.Error) > 0 {
+			return s.finish(s.respTrailer, statusFromGatewayError(chunk.Error, codes.Unknown))
+		}
+		payload = chunk.Result
+	} else {
+		if s.received {
+			return s.finish(s.respTrailer, status.New(codes.OK, ""))
+		}
+
+		// empty body is returned for empty messages, e.g. with 204 status
+		if err := s.body.Decode(&payload); err != nil {
+			if err != io.EOF {
+				return s.finish(nil, s.readError(err))
+			}
+			payload = json.RawMessage("{}")
+		}
+	}
+	s.received = true
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return s.finish(nil, status.Newf(codes.Internal, "http: unsupported message type %T", m))
+	}
+
+	if err := s.unmarshalResponse(payload, msg); err != nil {
+		return s.finish(nil, status.Newf(codes.Internal, "http: failed to unmarshal response: %v", err))
+	}
+
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.InPayload{
+			Client:           true,
+			Length:           len(payload),
+			CompressedLength: len(payload),
+			WireLength:       len(payload),
+			RecvTime:         time.Now(),
+		})
+	})
+
+	return nil
+}
+
+func (s *transcodingStream) do() {
+	if s.err != nil {
+		return
+	}
+
+	if s.req == nil {
+		s.err = status.Error(codes.Internal, "http: request message is missing")
+		return
+	}
+
+	if deadline, ok := s.ctx.Deadline(); ok {
+		s.req.Header.Set("Grpc-Timeout", encodeTimeout(time.Until(deadline)))
+	}
+
+	recordHTTPStats(s.ctx, func(st *Stats) {
+		st.record(&stats.OutHeader{Client: true, Header: headersToMetadata(s.req.Header), FullMethod: s.method})
+	})
+
+	resp, err := s.conn.client.Do(s.req)
+	if err != nil {
+		s.err = s.readError(err).Err()
+		return
+	}
+
+	s.header, s.respTrailer = splitTrailers(resp.Header, grpcGatewayTrailerPrefix)
+	recordHTTPResponse(s.ctx, s.method, s.header, resp)
+	s.closer = resp.Body
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, int64(s.conn.maxRecvMsgSize)))
+		s.err = statusFromGatewayError(body, httpStatusToCode(resp.StatusCode)).Err()
+		return
+	}
+
+	var body io.Reader = resp.Body
+	if !s.streaming {
+		body = io.LimitReader(resp.Body, int64(s.conn.maxRecvMsgSize))
+	}
+	s.body = json.NewDecoder(body)
+}
+
+// newRequest builds URL, query and body of the request from the message according to the rule
+func (s *transcodingStream) newRequest(msg proto.Message) (*http.Request, int, error) {
+	verb, tmpl := httpRulePattern(s.rule)
+	if tmpl == "" {
+		return nil, 0, status.Errorf(codes.Unimplemented, "http: %s has no HTTP pattern", s.method)
+	}
+
+	b, err := s.marshal.Marshal(msg)
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "http: failed to marshal request: %v", err)
+	}
+
+	fields := map[string]any{}
+	if err := jsonnum.Unmarshal(b, &fields); err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "http: failed to marshal request: %v", err)
+	}
+
+	path, bound, err := expandPathTemplate(tmpl, msg.ProtoReflect())
+	if err != nil {
+		return nil, 0, status.Errorf(codes.InvalidArgument, "http: %v", err)
+	}
+
+	for _, fieldPath := range bound {
+		deleteField(fields, fieldPath)
+	}
+
+	var body []byte
+	switch s.rule.GetBody() {
+	case "":
+	case "*":
+		body, err = json.Marshal(fields)
+		fields = nil
+	default:
+		body, err = s.bodyField(msg, s.rule.GetBody())
+		deleteField(fields, strings.Split(s.rule.GetBody(), "."))
+	}
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "http: failed to marshal request body: %v", err)
+	}
+
+	query := url.Values{}
+	encodeQuery(query, "", fields, msg.ProtoReflect().Descriptor())
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	req, err := s.conn.newHTTPRequest(s.ctx, verb, path, s.method, body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	return req, len(body), nil
+}
+
+// bodyField marshals the top level field used as the request body, unset fields are sent as zero values
+func (s *transcodingStream) bodyField(msg proto.Message, name string) ([]byte, error) {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		return nil, fmt.Errorf("body field %q not found in %s", name, m.Descriptor().FullName())
+	}
+
+	var body bytes.Buffer
+	if fd.Message() != nil && !fd.IsList() && !fd.IsMap() {
+		b, err := s.marshal.Marshal(m.Get(fd).Message().Interface())
+		if err != nil {
+			return nil, err
+		}
+		err = json.Compact(&body, b)
+		return body.Bytes(), err
+	}
+
+	// only the body field is set, so unpopulated nested messages are not emitted
+	field := m.New()
+	if m.Has(fd) {
+		field.Set(fd, m.Get(fd))
+	}
+
+	opts := s.marshal
+	opts.EmitUnpopulated = true
+	b, err := opts.Marshal(field.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	err = json.Compact(&body, fields[string(fd.Name())])
+	return body.Bytes(), err
+}
+
+// unmarshalResponse decodes the response, response_body of the rule points to the output message field
+func (s *transcodingStream) unmarshalResponse(payload []byte, msg proto.Message) error {
+	if field := s.rule.GetResponseBody(); field != "" {
+		wrapped, err := json.Marshal(map[string]json.RawMessage{field: payload})
+		if err != nil {
+			return err
+		}
+		payload = wrapped
+	}
+	return s.unmarshal.Unmarshal(payload, msg)
+}
+
+func findMethodDescriptor(method string) (protoreflect.MethodDescriptor, error) {
+	name := strings.ReplaceAll(strings.TrimPrefix(method, "/"), "/", ".")
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "http: method %s not found: %v", method, err)
+	}
+
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "http: %s is not a method", name)
+	}
+	return md, nil
+}
+
+// httpRule returns google.api.http option of the method, options of the descriptors parsed
+// from proto files or received from reflection can keep the extension as unknown fields
+func httpRule(md protoreflect.MethodDescriptor) (*annotations.HttpRule, error) {
+	b, err := proto.Marshal(md.Options())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "http: invalid options of %s: %v", md.FullName(), err)
+	}
+
+	opts := &descriptorpb.MethodOptions{}
+	if err := (proto.UnmarshalOptions{Resolver: protoregistry.GlobalTypes}).Unmarshal(b, opts); err != nil {
+		return nil, status.Errorf(codes.Internal, "http: invalid options of %s: %v", md.FullName(), err)
+	}
+
+	rule, _ := proto.GetExtension(opts, annotations.E_Http).(*annotations.HttpRule)
+	if rule == nil {
+		return nil, status.Errorf(codes.Unimplemented, "http: %s has no google.api.http option", md.FullName())
+	}
+	return rule, nil
+}
+
+func httpRulePattern(rule *annotations.HttpRule) (string, string) {
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		return p.Custom.GetKind(), p.Custom.GetPath()
+	default:
+		return "", ""
+	}
+}
+
+// expandPathTemplate replaces {field.path} and {field.path=pattern} variables with field values,
+// the values of single segment variables are escaped, multi segment values keep "/" as is
+func expandPathTemplate(tmpl string, msg protoreflect.Message) (string, [][]string, error) {
+	var b strings.Builder
+	var bound [][]string
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			b.WriteString(tmpl)
+			break
+		}
+
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("invalid path template %q", tmpl)
+		}
+		end += start
+
+		b.WriteString(tmpl[:start])
+		variable, pattern, _ := strings.Cut(tmpl[start+1:end], "=")
+		fieldPath := strings.Split(variable, ".")
+
+		value, err := fieldValue(msg, fieldPath)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if value == "" {
+			return "", nil, fmt.Errorf("value of path variable %s is empty", variable)
+		}
+
+		if strings.Contains(pattern, "/") || strings.Contains(pattern, "**") {
+			segments := strings.Split(value, "/")
+			for i := range segments {
+				segments[i] = url.PathEscape(segments[i])
+			}
+			b.WriteString(strings.Join(segments, "/"))
+		} else {
+			b.WriteString(url.PathEscape(value))
+		}
+
+		bound = append(bound, fieldPath)
+		tmpl = tmpl[end+1:]
+	}
+
+	return b.String(), bound, nil
+}
+
+// fieldValue formats a scalar field value referenced by the path of field names
+func fieldValue(msg protoreflect.Message, fieldPath []string) (string, error) {
+	for i, name := range fieldPath {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil || fd.IsList() || fd.IsMap() {
+			return "", fmt.Errorf("path variable %s doesn't reference a singular field of %s", strings.Join(fieldPath, "."), msg.Descriptor().FullName())
+		}
+
+		v := msg.Get(fd)
+		if i < len(fieldPath)-1 {
+			if fd.Message() == nil {
+				return "", fmt.Errorf("path variable %s doesn't reference a message field", strings.Join(fieldPath[:i+1], "."))
+			}
+			msg = v.Message()
+			continue
+		}
+
+		switch fd.Kind() {
+		case protoreflect.EnumKind:
+			if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+				return string(ev.Name()), nil
+			}
+			return strconv.Itoa(int(v.Enum())), nil
+		case protoreflect.BytesKind:
+			return base64.URLEncoding.EncodeToString(v.Bytes()), nil
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			b, err := protojson.Marshal(v.Message().Interface())
+			if err != nil {
+				return "", err
+			}
+			return strings.Trim(string(b), `"`), nil
+		default:
+			return v.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("empty path variable")
+}
+
+// encodeQuery flattens JSON fields of the message into query parameters using dot separated field paths,
+// repeated scalar fields are sent as repeated parameters and map entries as name[key]=value like grpc-gateway expects
+func encodeQuery(query url.Values, prefix string, fields map[string]any, md protoreflect.MessageDescriptor) {
+	for k, v := range fields {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+
+		var fd protoreflect.FieldDescriptor
+		if md != nil {
+			fd = md.Fields().ByName(protoreflect.Name(k))
+		}
+
+		switch val := v.(type) {
+		case map[string]any:
+			if fd != nil && fd.IsMap() {
+				for key, item := range val {
+					if s, ok := queryValue(item); ok {
+						query.Add(name+"["+key+"]", s)
+					}
+				}
+				continue
+			}
+
+			var nested protoreflect.MessageDescriptor
+			if fd != nil {
+				nested = fd.Message()
+			}
+			encodeQuery(query, name, val, nested)
+		case []any:
+			for _, item := range val {
+				if s, ok := queryValue(item); ok {
+					query.Add(name, s)
+				}
+			}
+		default:
+			if s, ok := queryValue(val); ok {
+				query.Add(name, s)
+			}
+		}
+	}
+}
+
+func queryValue(v any) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	case bool:
+		return strconv.FormatBool(val), true
+	default:
+		return "", false
+	}
+}
+
+// deleteField removes the field referenced by the path, empty parent objects are removed as well
+func deleteField(fields map[string]any, fieldPath []string) {
+	if fields == nil || len(fieldPath) == 0 {
+		return
+	}
+
+	if len(fieldPath) == 1 {
+		delete(fields, fieldPath[0])
+		return
+	}
+
+	nested, ok := fields[fieldPath[0]].(map[string]any)
+	if !ok {
+		return
+	}
+
+	deleteField(nested, fieldPath[1:])
+	if len(nested) == 0 {
+		delete(fields, fieldPath[0])
+	}
+}
+
+// statusFromGatewayError decodes google.rpc.Status JSON returned by grpc-gateway,
+// defaultCode is used if the body is not a status
+func statusFromGatewayError(body []byte, defaultCode codes.Code) *status.Status {
+	st := &spb.Status{}
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true, Resolver: dynamicpb.NewTypes(protoregistry.GlobalFiles)}
+	if err := opts.Unmarshal(body, st); err == nil && (st.Code != 0 || st.Message != "") {
+		return status.FromProto(st)
+	}
+
+	// details can't be decoded if their types are unknown
+	var fallback struct {
+		Code    int32  `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &fallback); err == nil && fallback.Code != 0 {
+		return status.New(codes.Code(fallback.Code), fallback.Message)
+	}
+
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = "http: request failed"
+	}
+	return status.New(defaultCode, msg)
+}
@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vadimi/grpc-client-cli/internal/jsonnum"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+func TestExpandPathTemplate(t *testing.T) {
+	msg := (&errdetails.ResourceInfo{ResourceName: "projects/a b/x", Owner: "a/b"}).ProtoReflect()
+
+	path, bound, err := expandPathTemplate("/v1/{resource_name=projects/**}:get", msg)
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/projects/a%20b/x:get", path)
+	assert.Equal(t, [][]string{{"resource_name"}}, bound)
+
+	// single segment variables escape slashes
+	path, _, err = expandPathTemplate("/v1/owners/{owner}", msg)
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/owners/a%2Fb", path)
+
+	_, _, err = expandPathTemplate("/v1/{description}", msg)
+	assert.ErrorContains(t, err, "empty")
+
+	_, _, err = expandPathTemplate("/v1/{unknown}", msg)
+	assert.Error(t, err)
+}
+
+func TestEncodeQuery(t *testing.T) {
+	fields := map[string]any{}
+	require.NoError(t, jsonnum.Unmarshal([]byte(`{"a": {"b": "x", "c": 1}, "d": [true, false], "e": [{"f": 1}], "g": "y"}`), &fields))
+
+	deleteField(fields, []string{"a", "b"})
+	deleteField(fields, []string{"g"})
+
+	query := url.Values{}
+	encodeQuery(query, "", fields, nil)
+
+	// repeated messages can't be sent as query parameters
+	assert.Equal(t, "a.c=1&d=true&d=false", query.Encode())
+}
+
+func TestEncodeQueryMap(t *testing.T) {
+	fields := map[string]any{}
+	require.NoError(t, jsonnum.Unmarshal([]byte(`{"reason": "r", "metadata": {"k1": "v1", "k2": "v2"}}`), &fields))
+
+	query := url.Values{}
+	encodeQuery(query, "", fields, (&errdetails.ErrorInfo{}).ProtoReflect().Descriptor())
+
+	assert.Equal(t, "metadata%5Bk1%5D=v1&metadata%5Bk2%5D=v2&reason=r", query.Encode())
+}
+
+func TestStatusFromGatewayError(t *testing.T) {
+	st := statusFromGatewayError([]byte(`{"code": 7, "message": "denied", "details": [{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "TEST"}]}`), codes.Unknown)
+	assert.Equal(t, codes.PermissionDenied, st.Code())
+	assert.Equal(t, "denied", st.Message())
+	assert.Len(t, st.Details(), 1)
+
+	// unknown detail types
+	st = statusFromGatewayError([]byte(`{"code": 7, "message": "denied", "details": [{"@type": "type.googleapis.com/unknown.Type"}]}`), codes.Unknown)
+	assert.Equal(t, codes.PermissionDenied, st.Code())
+	assert.Equal(t, "denied", st.Message())
+
+	st = statusFromGatewayError([]byte("bad gateway"), codes.Unavailable)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	assert.Equal(t, "bad gateway", st.Message())
+}
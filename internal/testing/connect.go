@@ -0,0 +1,176 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/vadimi/grpc-client-cli/internal/testing/grpc_testing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	testServerConnectAddr = ""
+	testConnectServer     *http.Server
+	testConnectConn       *grpc.ClientConn
+)
+
+// TestServerConnectAddr returns address of the server implementing TestService unary,
+// server and client streaming methods with Connect handlers, reflection is served using gRPC
+func TestServerConnectAddr() string {
+	return testServerConnectAddr
+}
+
+func setupConnectServer() error {
+	conn, err := grpc.NewClient(testServerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+
+	client := grpc_testing.NewTestServiceClient(conn)
+
+	grpcServer := createServer()
+	reflection.Register(grpcServer)
+
+	mux := http.NewServeMux()
+	mux.Handle("/grpc_client_cli.testing.TestService/UnaryCall", connect.NewUnaryHandler(
+		"/grpc_client_cli.testing.TestService/UnaryCall",
+		func(ctx context.Context, req *connect.Request[grpc_testing.SimpleRequest]) (*connect.Response[grpc_testing.SimpleResponse], error) {
+			var header, trailer metadata.MD
+			res, err := client.UnaryCall(connectOutgoingContext(ctx, req.Header()), req.Msg, grpc.Header(&header), grpc.Trailer(&trailer))
+			if err != nil {
+				return nil, toConnectError(err, trailer)
+			}
+
+			resp := connect.NewResponse(res)
+			copyMetadata(resp.Header(), header)
+			copyMetadata(resp.Trailer(), trailer)
+			return resp, nil
+		},
+	))
+	mux.Handle("/grpc_client_cli.testing.TestService/StreamingOutputCall", connect.NewServerStreamHandler(
+		"/grpc_client_cli.testing.TestService/StreamingOutputCall",
+		func(ctx context.Context, req *connect.Request[grpc_testing.StreamingOutputCallRequest], str *connect.ServerStream[grpc_testing.StreamingOutputCallResponse]) error {
+			cs, err := client.StreamingOutputCall(connectOutgoingContext(ctx, req.Header()), req.Msg)
+			if err != nil {
+				return toConnectError(err, nil)
+			}
+
+			for {
+				res, err := cs.Recv()
+				if err == io.EOF {
+					copyMetadata(str.ResponseTrailer(), cs.Trailer())
+					return nil
+				}
+				if err != nil {
+					return toConnectError(err, cs.Trailer())
+				}
+
+				if err := str.Send(res); err != nil {
+					return err
+				}
+			}
+		},
+	))
+	mux.Handle("/grpc_client_cli.testing.TestService/StreamingInputCall", connect.NewClientStreamHandler(
+		"/grpc_client_cli.testing.TestService/StreamingInputCall",
+		func(ctx context.Context, str *connect.ClientStream[grpc_testing.StreamingInputCallRequest]) (*connect.Response[grpc_testing.StreamingInputCallResponse], error) {
+			cs, err := client.StreamingInputCall(connectOutgoingContext(ctx, str.RequestHeader()))
+			if err != nil {
+				return nil, toConnectError(err, nil)
+			}
+
+			for str.Receive() {
+				if err := cs.Send(str.Msg()); err != nil {
+					break
+				}
+			}
+			if err := str.Err(); err != nil {
+				return nil, err
+			}
+
+			res, err := cs.CloseAndRecv()
+			if err != nil {
+				return nil, toConnectError(err, cs.Trailer())
+			}
+			return connect.NewResponse(res), nil
+		},
+	))
+	mux.Handle("/", grpcServer)
+
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	server := &http.Server{
+		Protocols: protocols,
+		Handler:   mux,
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	go server.Serve(l)
+
+	testConnectConn = conn
+	testConnectServer = server
+	testServerConnectAddr = l.Addr().String()
+	return nil
+}
+
+func stopConnectServer() {
+	if testConnectServer != nil {
+		testConnectServer.Close()
+	}
+	if testConnectConn != nil {
+		testConnectConn.Close()
+	}
+}
+
+// connectOutgoingContext passes request headers to the gRPC server
+func connectOutgoingContext(ctx context.Context, h http.Header) context.Context {
+	md := metadata.MD{}
+	for k, values := range h {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "connect-") {
+			continue
+		}
+		switch k {
+		case "content-type", "content-length", "accept-encoding", "user-agent", "grpc-timeout":
+			continue
+		}
+		md.Append(k, values...)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func copyMetadata(h http.Header, md metadata.MD) {
+	for k, values := range md {
+		for _, v := range values {
+			h.Add(k, v)
+		}
+	}
+}
+
+// toConnectError converts gRPC status to Connect error keeping status details
+func toConnectError(err error, trailer metadata.MD) error {
+	st := status.Convert(err)
+	cerr := connect.NewError(connect.Code(st.Code()), errors.New(st.Message()))
+	for _, d := range st.Proto().GetDetails() {
+		if detail, err := connect.NewErrorDetail(d); err == nil {
+			cerr.AddDetail(detail)
+		}
+	}
+	copyMetadata(cerr.Meta(), trailer)
+	return cerr
+}
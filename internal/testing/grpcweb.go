@@ -0,0 +1,192 @@
+package testing
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	testServerWebAddr = ""
+	testWebServer     *http.Server
+	testWebConn       *grpc.ClientConn
+)
+
+// TestServerWebAddr returns address of the server accepting both gRPC and gRPC-Web requests,
+// the same way as Envoy with grpc_web filter does
+func TestServerWebAddr() string {
+	return testServerWebAddr
+}
+
+func setupWebServer() error {
+	conn, err := grpc.NewClient(testServerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+
+	grpcServer := createServer()
+	reflection.Register(grpcServer)
+
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	server := &http.Server{
+		Protocols: protocols,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc-web") {
+				serveGrpcWeb(conn, w, r)
+				return
+			}
+			grpcServer.ServeHTTP(w, r)
+		}),
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	go server.Serve(l)
+
+	testWebConn = conn
+	testWebServer = server
+	testServerWebAddr = l.Addr().String()
+	return nil
+}
+
+func stopWebServer() {
+	if testWebServer != nil {
+		testWebServer.Close()
+	}
+	if testWebConn != nil {
+		testWebConn.Close()
+	}
+}
+
+// serveGrpcWeb translates gRPC-Web request to gRPC call
+func serveGrpcWeb(conn *grpc.ClientConn, w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, "application/grpc-web-text")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if text {
+		body, err = base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		http.Error(w, "invalid grpc-web frame", http.StatusBadRequest)
+		return
+	}
+	payload := body[5:]
+
+	md := metadata.MD{}
+	for k, values := range r.Header {
+		k = strings.ToLower(k)
+		switch k {
+		case "content-type", "content-length", "accept", "accept-encoding", "user-agent", "x-grpc-web", "x-user-agent", "grpc-timeout":
+			continue
+		}
+		md.Append(k, values...)
+	}
+
+	ctx := metadata.NewOutgoingContext(r.Context(), md)
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, r.URL.Path, grpc.ForceCodec(rawCodec{}))
+	if err == nil {
+		err = stream.SendMsg(&payload)
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+
+	writeFrame := func(flags byte, b []byte) {
+		frame := append([]byte{flags, 0, 0, 0, 0}, b...)
+		binary.BigEndian.PutUint32(frame[1:5], uint32(len(b)))
+		if text {
+			frame = []byte(base64.StdEncoding.EncodeToString(frame))
+		}
+		w.Write(frame)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	if err == nil {
+		header, _ := stream.Header()
+		for k, values := range header {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	for err == nil {
+		var msg []byte
+		if err = stream.RecvMsg(&msg); err == nil {
+			writeFrame(0, msg)
+		}
+	}
+
+	var trailer metadata.MD
+	if stream != nil {
+		trailer = stream.Trailer()
+	}
+
+	st := status.Convert(err)
+	if err == io.EOF {
+		st = status.New(0, "")
+	}
+
+	var b strings.Builder
+	for k, values := range trailer {
+		for _, v := range values {
+			if strings.HasSuffix(k, "-bin") {
+				v = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+			fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+		}
+	}
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", st.Code())
+	if st.Message() != "" {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", st.Message())
+	}
+	writeFrame(0x80, []byte(b.String()))
+}
+
+// rawCodec passes serialized messages as is
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	*(v.(*[]byte)) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/vadimi/grpc-client-cli/internal/testing/grpc_testing"
@@ -31,8 +32,17 @@ const (
 
 	// CheckHeader is used to echo specified headers back
 	CheckHeader = "check-header"
+
+	// FailAttempts makes UnaryCall fail with Unavailable code the specified number of times,
+	// the value has "id=count" format where id identifies the call across attempts
+	FailAttempts = "fail-attempts"
 )
 
+var failedAttempts = struct {
+	sync.Mutex
+	counts map[string]int
+}{counts: map[string]int{}}
+
 var (
 	testServerAddr = ""
 	testGrpcServer *grpc.Server
@@ -56,6 +66,10 @@ func (testService) EmptyCall(ctx context.Context, req *emptypb.Empty) (*emptypb.
 }
 
 func (testService) UnaryCall(ctx context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+	if shouldFailAttempt(ctx) {
+		return nil, status.Error(codes.Unavailable, "attempt failed")
+	}
+
 	checkHeaders := extractCheckHeaders(ctx)
 	if len(checkHeaders) > 0 {
 		imd, _ := metadata.FromIncomingContext(ctx)
@@ -107,6 +121,13 @@ func (testService) StreamingOutputCall(req *grpc_testing.StreamingOutputCallRequ
 
 	rsp := &grpc_testing.StreamingOutputCallResponse{User: &grpc_testing.User{}}
 	for _, param := range req.ResponseParameters {
+		if interval := time.Duration(param.GetIntervalUs()) * time.Microsecond; interval > 0 {
+			select {
+			case <-str.Context().Done():
+			case <-time.After(interval):
+			}
+		}
+
 		if str.Context().Err() != nil {
 			return str.Context().Err()
 		}
@@ -271,8 +292,19 @@ func SetupTestServer() error {
 	}
 
 	testGrpcMTLSServer, testServerMTLSAddr, err = setupTestServer(mTLSCreds)
+	if err != nil {
+		return err
+	}
+
+	if err := setupWebServer(); err != nil {
+		return err
+	}
+
+	if err := setupConnectServer(); err != nil {
+		return err
+	}
 
-	return err
+	return setupHTTPServer()
 }
 
 func setupTestServer(opts ...grpc.ServerOption) (*grpc.Server, string, error) {
@@ -309,6 +341,9 @@ func createListener(server *grpc.Server) (*grpc.Server, string, error) {
 }
 
 func StopTestServer() {
+	stopWebServer()
+	stopConnectServer()
+	stopHTTPServer()
 	stopTestServer(testGrpcServer)
 	stopTestServer(testGrpcTLSServer)
 	stopTestServer(testGrpcMTLSServer)
@@ -374,6 +409,30 @@ func extractCheckHeaders(ctx context.Context) []headerKV {
 	return res
 }
 
+func shouldFailAttempt(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	values := md.Get(FailAttempts)
+	if len(values) == 0 {
+		return false
+	}
+
+	id, countStr, _ := strings.Cut(values[0], "=")
+	count, _ := strconv.Atoi(countStr)
+
+	failedAttempts.Lock()
+	defer failedAttempts.Unlock()
+	if failedAttempts.counts[id] >= count {
+		return false
+	}
+
+	failedAttempts.counts[id]++
+	return true
+}
+
 func extractStatusCodes(ctx context.Context) codes.Code {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -0,0 +1,90 @@
+package testing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	testServerHTTPAddr = ""
+	testHTTPServer     *http.Server
+)
+
+// TestServerHTTPAddr returns address of the server emulating grpc-gateway for HttpService
+// from testdata/http_service.proto, responses describe the received HTTP request
+func TestServerHTTPAddr() string {
+	return testServerHTTPAddr
+}
+
+func setupHTTPServer() error {
+	server := &http.Server{Handler: http.HandlerFunc(serveTranscoding)}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	go server.Serve(l)
+
+	testHTTPServer = server
+	testServerHTTPAddr = l.Addr().String()
+	return nil
+}
+
+func stopHTTPServer() {
+	if testHTTPServer != nil {
+		testHTTPServer.Close()
+	}
+}
+
+type httpRequestInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query"`
+	Body   string `json:"body"`
+}
+
+func serveTranscoding(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	info := httpRequestInfo{
+		Method: r.Method,
+		Path:   r.URL.EscapedPath(),
+		Query:  r.URL.RawQuery,
+		Body:   string(body),
+	}
+
+	// the same way as grpc-gateway sends metadata
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Grpc-Metadata-X-Echo", r.Header.Get("X-Echo"))
+	w.Header().Set("Grpc-Trailer-X-Trailer", "done")
+
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/v1/status/"):
+		code, _ := strconv.Atoi(strings.TrimPrefix(path, "/v1/status/"))
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"code": %d, "message": "status error", "details": [{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "TEST"}]}`, code)
+	case strings.HasSuffix(path, "/method"):
+		json.NewEncoder(w).Encode(r.Method)
+	case strings.HasSuffix(path, ":watch"):
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		enc := json.NewEncoder(w)
+		for range count {
+			enc.Encode(map[string]any{"result": info})
+		}
+		if r.URL.Query().Get("fail") != "" {
+			enc.Encode(map[string]any{"error": map[string]any{"code": 13, "message": "stream failed"}})
+		}
+	default:
+		json.NewEncoder(w).Encode(info)
+	}
+}